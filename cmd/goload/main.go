@@ -2,9 +2,12 @@ package main
 
 import (
 	"GoLoad/internal/configs"
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/logic"
 	"GoLoad/internal/wiring"
 	"fmt"
-	"log"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -16,6 +19,12 @@ var (
 
 const (
 	flagConfigFilePath = "config-file-path"
+	flagAccountName    = "account-name"
+	flagRole           = "role"
+	flagQueueName      = "queue-name"
+	flagLimit          = "limit"
+	flagPartition      = "partition"
+	flagOffset         = "offset"
 )
 
 func server() *cobra.Command {
@@ -38,14 +47,255 @@ func server() *cobra.Command {
 	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
 	return command
 }
+func apiServer() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "api-server",
+		Long: "Start the gRPC + HTTP server of GoLoad, which can be scaled out behind a load balancer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			app, cleanup, err := wiring.InitializeAPIServer(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			return app.Start()
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	return command
+}
+func worker() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "worker",
+		Long: "Start a GoLoad worker consuming and executing download tasks, of which several can run at once",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			app, cleanup, err := wiring.InitializeWorker(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			return app.Start()
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	return command
+}
+func cronScheduler() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "cron-scheduler",
+		Long: "Start the GoLoad cronjobs, which only run on the instance elected as leader",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			app, cleanup, err := wiring.InitializeCronScheduler(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			return app.Start()
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	return command
+}
+func createPasswordResetToken() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "create-password-reset-token",
+		Long: "Create a single-use token the owner of an account can reset its password with",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			accountName, err := cmd.Flags().GetString(flagAccountName)
+			if err != nil {
+				return err
+			}
+			accountLogic, cleanup, err := wiring.InitializeAccountLogic(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			output, err := accountLogic.CreatePasswordResetToken(cmd.Context(), logic.CreatePasswordResetTokenParams{
+				AccountName: accountName,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "reset token: %s\nexpires at: %s\n", output.ResetToken, output.ExpireTime.Format(time.RFC3339))
+			return nil
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	command.Flags().String(flagAccountName, "", "Name of the account to reset the password of.")
+	_ = command.MarkFlagRequired(flagAccountName)
+	return command
+}
+func setAccountRole() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "set-account-role",
+		Long: "Set the role of an account, for example to create the first administrator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			accountName, err := cmd.Flags().GetString(flagAccountName)
+			if err != nil {
+				return err
+			}
+			roleName, err := cmd.Flags().GetString(flagRole)
+			if err != nil {
+				return err
+			}
+			role, ok := go_load.AccountRole_value[roleName]
+			if !ok {
+				return fmt.Errorf("unknown role %q", roleName)
+			}
+			adminLogic, cleanup, err := wiring.InitializeAdminLogic(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			output, err := adminLogic.UpdateAccountRoleOfAccountName(cmd.Context(), logic.UpdateAccountRoleOfAccountNameParams{
+				AccountName: accountName,
+				Role:        go_load.AccountRole(role),
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "account %d now has role %s\n", output.Account.GetId(), output.Account.GetRole())
+			return nil
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	command.Flags().String(flagAccountName, "", "Name of the account to set the role of.")
+	command.Flags().String(flagRole, "", "One of Admin, User and ReadOnlyUser.")
+	_ = command.MarkFlagRequired(flagAccountName)
+	_ = command.MarkFlagRequired(flagRole)
+	return command
+}
+func getDeadLetterMessageList() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "get-dead-letter-message-list",
+		Long: "Print the messages of a queue that were moved to its dead letter queue after failing to be handled",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			queueName, err := cmd.Flags().GetString(flagQueueName)
+			if err != nil {
+				return err
+			}
+			limit, err := cmd.Flags().GetUint64(flagLimit)
+			if err != nil {
+				return err
+			}
+			deadLetterLogic, cleanup, err := wiring.InitializeDeadLetterLogic(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			output, err := deadLetterLogic.GetDeadLetterMessageList(cmd.Context(), logic.GetDeadLetterMessageListParams{
+				QueueName: queueName,
+				Limit:     limit,
+			})
+			if err != nil {
+				return err
+			}
+			for _, message := range output.DeadLetterMessageList {
+				fmt.Fprintf(
+					cmd.OutOrStdout(),
+					"partition: %d\noffset: %d\nfailed at: %s\nattempt count: %d\nerror: %s\npayload: %s\n\n",
+					message.Partition,
+					message.Offset,
+					message.FailedTime.Format(time.RFC3339),
+					message.AttemptCount,
+					message.Error,
+					message.Payload,
+				)
+			}
+			return nil
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	command.Flags().String(flagQueueName, "", "Name of the queue to print the dead-lettered messages of.")
+	command.Flags().Uint64(flagLimit, 100, "Maximum number of messages to print.")
+	_ = command.MarkFlagRequired(flagQueueName)
+	return command
+}
+func replayDeadLetterMessage() *cobra.Command {
+	command := &cobra.Command{
+		Use:  "replay-dead-letter-message",
+		Long: "Publish a dead-lettered message to the queue it failed to be handled from again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath, err := cmd.Flags().GetString(flagConfigFilePath)
+			if err != nil {
+				return err
+			}
+			queueName, err := cmd.Flags().GetString(flagQueueName)
+			if err != nil {
+				return err
+			}
+			partition, err := cmd.Flags().GetInt32(flagPartition)
+			if err != nil {
+				return err
+			}
+			offset, err := cmd.Flags().GetInt64(flagOffset)
+			if err != nil {
+				return err
+			}
+			deadLetterLogic, cleanup, err := wiring.InitializeDeadLetterLogic(configs.ConfigFilePath(configFilePath))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			if err = deadLetterLogic.ReplayDeadLetterMessage(cmd.Context(), logic.ReplayDeadLetterMessageParams{
+				QueueName: queueName,
+				Partition: partition,
+				Offset:    offset,
+			}); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "replayed message %d of partition %d to %s\n", offset, partition, queueName)
+			return nil
+		},
+	}
+	command.Flags().String(flagConfigFilePath, "", "If provided, will use the provided config file.")
+	command.Flags().String(flagQueueName, "", "Name of the queue the message failed to be handled from.")
+	command.Flags().Int32(flagPartition, 0, "Partition of the message in the dead letter queue.")
+	command.Flags().Int64(flagOffset, 0, "Offset of the message in the dead letter queue.")
+	_ = command.MarkFlagRequired(flagQueueName)
+	_ = command.MarkFlagRequired(flagPartition)
+	_ = command.MarkFlagRequired(flagOffset)
+	return command
+}
 func main() {
 	rootCommand := &cobra.Command{
 		Version: fmt.Sprintf("%s-%s", version, commitHash),
 	}
 	rootCommand.AddCommand(
 		server(),
+		apiServer(),
+		worker(),
+		cronScheduler(),
+		createPasswordResetToken(),
+		setAccountRole(),
+		getDeadLetterMessageList(),
+		replayDeadLetterMessage(),
 	)
+	// Cobra already prints the error, such as a component failing to start, so only the exit code is left to set.
 	if err := rootCommand.Execute(); err != nil {
-		log.Panic(err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/handler/grpc"
+	"GoLoad/internal/handler/http"
+	"GoLoad/internal/logic"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// APIServer serves clients through the gRPC server and the HTTP gateway. It keeps no state of its own, so any number of
+// instances can run behind a load balancer.
+type APIServer struct {
+	grpcServer     grpc.Server
+	httpServer     http.Server
+	metricsServer  http.MetricsServer
+	healthLogic    logic.Health
+	shutdownConfig configs.Shutdown
+	logger         *zap.Logger
+}
+
+func NewAPIServer(
+	grpcServer grpc.Server,
+	httpServer http.Server,
+	metricsServer http.MetricsServer,
+	healthLogic logic.Health,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) *APIServer {
+	return &APIServer{
+		grpcServer:     grpcServer,
+		httpServer:     httpServer,
+		metricsServer:  metricsServer,
+		healthLogic:    healthLogic,
+		shutdownConfig: shutdownConfig,
+		logger:         logger,
+	}
+}
+
+// run serves until ctx is cancelled, then reports the instance as not ready for the readiness grace period before
+// draining both servers. If either server fails, the other one is stopped too.
+func (a APIServer) run(ctx context.Context) error {
+	readinessGracePeriod, err := a.shutdownConfig.GetReadinessGracePeriodDuration()
+	if err != nil {
+		a.logger.With(zap.Error(err)).Error("failed to parse shutdown readiness grace period")
+		return err
+	}
+	serverCtx, cancelServers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelServers()
+	group, groupCtx := errgroup.WithContext(serverCtx)
+	group.Go(func() error {
+		return a.grpcServer.Start(groupCtx)
+	})
+	group.Go(func() error {
+		return a.httpServer.Start(groupCtx)
+	})
+	group.Go(func() error {
+		select {
+		case <-ctx.Done():
+		case <-groupCtx.Done():
+			return nil
+		}
+		a.healthLogic.SetShuttingDown()
+		a.logger.With(zap.Duration("readiness_grace_period", readinessGracePeriod)).
+			Info("reporting not ready before stopping api server")
+		select {
+		case <-time.After(readinessGracePeriod):
+		case <-groupCtx.Done():
+		}
+		cancelServers()
+		return nil
+	})
+	if err = group.Wait(); err != nil {
+		a.logger.With(zap.Error(err)).Error("api server failed")
+		return err
+	}
+	a.logger.Info("api server stopped")
+	return nil
+}
+func (a APIServer) Start() error {
+	return runUntilSignal(func(ctx context.Context) error {
+		return runWithServers(ctx, a.healthLogic, a.run, a.metricsServer)
+	})
+}
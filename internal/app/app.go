@@ -0,0 +1,92 @@
+package app
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// interruptedJobStopTimeout is how long the jobs interrupted at the end of the shutdown timeout are given to return.
+const interruptedJobStopTimeout = 10 * time.Second
+
+// runUntilSignal runs run until the process is asked to stop, returning the error it fails with, if any.
+func runUntilSignal(run func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return run(ctx)
+}
+
+// backgroundServer is a listener a command serves besides its own work, such as the health and metrics servers.
+type backgroundServer interface {
+	Start(ctx context.Context) error
+}
+
+// runWithServers runs run along with serverList, for the orchestrator to probe the instance and Prometheus to scrape
+// it. The instance reports itself as not ready once ctx is cancelled, and serverList is served until run has returned.
+// If any of them fails, the other ones are stopped too.
+func runWithServers(
+	ctx context.Context,
+	healthLogic logic.Health,
+	run func(ctx context.Context) error,
+	serverList ...backgroundServer,
+) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	stopSettingShuttingDown := context.AfterFunc(groupCtx, healthLogic.SetShuttingDown)
+	defer stopSettingShuttingDown()
+	serverCtx, cancelServers := context.WithCancel(context.WithoutCancel(groupCtx))
+	defer cancelServers()
+	for _, server := range serverList {
+		group.Go(func() error {
+			return server.Start(serverCtx)
+		})
+	}
+	group.Go(func() error {
+		defer cancelServers()
+		return run(groupCtx)
+	})
+	return group.Wait()
+}
+
+// newScheduler returns a scheduler waiting for its running jobs on shutdown until they are interrupted and returned.
+func newScheduler(shutdownTimeout time.Duration) (gocron.Scheduler, error) {
+	return gocron.NewScheduler(gocron.WithStopTimeout(shutdownTimeout + interruptedJobStopTimeout))
+}
+
+// scheduleCronJob runs run with jobCtx and logs its errors, since the scheduler has nothing to return them to.
+func scheduleCronJob(
+	jobCtx context.Context,
+	scheduler gocron.Scheduler,
+	schedule string,
+	jobName string,
+	run func(context.Context) error,
+	logger *zap.Logger,
+	options ...gocron.JobOption,
+) error {
+	if _, err := scheduler.NewJob(
+		gocron.CronJob(schedule, true),
+		gocron.NewTask(func() {
+			if err := run(jobCtx); err != nil {
+				logger.With(zap.Error(err)).Error("failed to run " + jobName + " job")
+			}
+		}),
+		options...,
+	); err != nil {
+		logger.With(zap.Error(err)).Error("failed to schedule " + jobName + " job")
+		return err
+	}
+	return nil
+}
+
+// shutdownScheduler stops the scheduler once ctx is done, waiting for its running jobs.
+func shutdownScheduler(ctx context.Context, scheduler gocron.Scheduler, logger *zap.Logger) {
+	<-ctx.Done()
+	if err := scheduler.Shutdown(); err != nil {
+		logger.With(zap.Error(err)).Error("failed to shutdown scheduler")
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/handler/http"
+	"GoLoad/internal/handler/jobs"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	cronSchedulerLeaderLeaseName = "cron_scheduler"
+)
+
+// CronScheduler runs the cron jobs that must run on a single instance at a time. Every instance schedules the jobs,
+// but only the one elected leader runs them, so that another instance takes over when the leader stops. A job already
+// running when its instance loses the leadership is not interrupted.
+type CronScheduler struct {
+	updateDownloadingAndFailedDownloadTaskStatusToPendingJob jobs.UpdateDownloadingAndFailedDownloadTaskStatusToPending
+	deleteExpiredDownloadTaskFileJob                         jobs.DeleteExpiredDownloadTaskFile
+	rotateTokenSigningKeyJob                                 jobs.RotateTokenSigningKey
+	relayOutboxEventsJob                                     jobs.RelayOutboxEvents
+	deliverWebhooksJob                                       jobs.DeliverWebhooks
+	leaderElectionLogic                                      logic.LeaderElection
+	healthServer                                             http.HealthServer
+	metricsServer                                            http.MetricsServer
+	healthLogic                                              logic.Health
+	cronConfig                                               configs.Cron
+	shutdownConfig                                           configs.Shutdown
+	isLeader                                                 *atomic.Bool
+	logger                                                   *zap.Logger
+}
+
+func NewCronScheduler(
+	updateDownloadingAndFailedDownloadTaskStatusToPendingJob jobs.UpdateDownloadingAndFailedDownloadTaskStatusToPending,
+	deleteExpiredDownloadTaskFileJob jobs.DeleteExpiredDownloadTaskFile,
+	rotateTokenSigningKeyJob jobs.RotateTokenSigningKey,
+	relayOutboxEventsJob jobs.RelayOutboxEvents,
+	deliverWebhooksJob jobs.DeliverWebhooks,
+	leaderElectionLogic logic.LeaderElection,
+	healthServer http.HealthServer,
+	metricsServer http.MetricsServer,
+	healthLogic logic.Health,
+	cronConfig configs.Cron,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) *CronScheduler {
+	return &CronScheduler{
+		updateDownloadingAndFailedDownloadTaskStatusToPendingJob: updateDownloadingAndFailedDownloadTaskStatusToPendingJob,
+		deleteExpiredDownloadTaskFileJob:                         deleteExpiredDownloadTaskFileJob,
+		rotateTokenSigningKeyJob:                                 rotateTokenSigningKeyJob,
+		relayOutboxEventsJob:                                     relayOutboxEventsJob,
+		deliverWebhooksJob:                                       deliverWebhooksJob,
+		leaderElectionLogic:                                      leaderElectionLogic,
+		healthServer:                                             healthServer,
+		metricsServer:                                            metricsServer,
+		healthLogic:                                              healthLogic,
+		cronConfig:                                               cronConfig,
+		shutdownConfig:                                           shutdownConfig,
+		isLeader:                                                 new(atomic.Bool),
+		logger:                                                   logger,
+	}
+}
+func (c CronScheduler) runIfLeader(run func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if !c.isLeader.Load() {
+			return nil
+		}
+		return run(ctx)
+	}
+}
+func (c CronScheduler) scheduleCronJobs(jobCtx context.Context, scheduler gocron.Scheduler) error {
+	if err := scheduleCronJob(
+		jobCtx,
+		scheduler,
+		c.cronConfig.UpdateDownloadingAndFailedDownloadTaskStatusToPending.Schedule,
+		"update downloading and failed download task status to pending",
+		c.runIfLeader(c.updateDownloadingAndFailedDownloadTaskStatusToPendingJob.Run),
+		c.logger,
+	); err != nil {
+		return err
+	}
+	if err := scheduleCronJob(
+		jobCtx,
+		scheduler,
+		c.cronConfig.DeleteExpiredDownloadTaskFile.Schedule,
+		"delete expired download task file",
+		c.runIfLeader(c.deleteExpiredDownloadTaskFileJob.Run),
+		c.logger,
+	); err != nil {
+		return err
+	}
+	if err := scheduleCronJob(
+		jobCtx,
+		scheduler,
+		c.cronConfig.RotateTokenSigningKey.Schedule,
+		"rotate token signing key",
+		c.runIfLeader(c.rotateTokenSigningKeyJob.Run),
+		c.logger,
+	); err != nil {
+		return err
+	}
+	// Runs of the relay and webhook jobs must not overlap, since their schedules are shorter than a run may take.
+	if err := scheduleCronJob(
+		jobCtx,
+		scheduler,
+		c.cronConfig.RelayOutboxEvents.Schedule,
+		"relay outbox events",
+		c.runIfLeader(c.relayOutboxEventsJob.Run),
+		c.logger,
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	); err != nil {
+		return err
+	}
+	return scheduleCronJob(
+		jobCtx,
+		scheduler,
+		c.cronConfig.DeliverWebhooks.Schedule,
+		"deliver webhooks",
+		c.runIfLeader(c.deliverWebhooksJob.Run),
+		c.logger,
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// renewLeadership steps down if the lease cannot be renewed, since another instance may take it over once it expires.
+func (c CronScheduler) renewLeadership(ctx context.Context) {
+	acquired, err := c.leaderElectionLogic.TryAcquireLeadership(ctx, cronSchedulerLeaderLeaseName)
+	if err != nil {
+		c.logger.With(zap.Error(err)).Warn("failed to renew cron scheduler leadership")
+		acquired = false
+	}
+	if wasLeader := c.isLeader.Swap(acquired); wasLeader != acquired {
+		c.logger.With(zap.Bool("is_leader", acquired)).Info("cron scheduler leadership changed")
+	}
+}
+func (c CronScheduler) renewLeadershipUntilDone(ctx context.Context, renewInterval time.Duration) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.renewLeadership(ctx)
+		}
+	}
+}
+
+// run runs the cron jobs while this instance is the leader until ctx is cancelled, then waits for the running jobs
+// and releases the leadership, renewing it until then.
+func (c CronScheduler) run(ctx context.Context) error {
+	renewInterval, err := c.cronConfig.LeaderElection.GetRenewIntervalDuration()
+	if err != nil {
+		c.logger.With(zap.Error(err)).Error("failed to parse leader election renew interval")
+		return err
+	}
+	shutdownTimeout, err := c.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		c.logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	scheduler, err := newScheduler(shutdownTimeout)
+	if err != nil {
+		c.logger.With(zap.Error(err)).Error("failed to initialize scheduler")
+		return err
+	}
+	jobCtx, cancelJobs := utils.WithDrainTimeout(ctx, shutdownTimeout)
+	defer cancelJobs()
+	if err = c.scheduleCronJobs(jobCtx, scheduler); err != nil {
+		return err
+	}
+	c.renewLeadership(ctx)
+	scheduler.Start()
+	// The leadership is still renewed while the scheduler waits for the running jobs, for another instance not to
+	// take over the lease and run the same jobs at the same time.
+	renewCtx, stopRenewingLeadership := context.WithCancel(context.WithoutCancel(ctx))
+	renewDone := make(chan struct{})
+	go func() {
+		defer close(renewDone)
+		c.renewLeadershipUntilDone(renewCtx, renewInterval)
+	}()
+	shutdownScheduler(ctx, scheduler, c.logger)
+	stopRenewingLeadership()
+	<-renewDone
+	if c.isLeader.Swap(false) {
+		releaseErr := c.leaderElectionLogic.ReleaseLeadership(context.WithoutCancel(ctx), cronSchedulerLeaderLeaseName)
+		if releaseErr != nil {
+			c.logger.With(zap.Error(releaseErr)).Warn("failed to release cron scheduler leadership")
+		}
+	}
+	c.logger.Info("cron scheduler stopped")
+	return nil
+}
+func (c CronScheduler) Start() error {
+	return runUntilSignal(func(ctx context.Context) error {
+		return runWithServers(ctx, c.healthLogic, c.run, c.healthServer, c.metricsServer)
+	})
+}
@@ -1,106 +1,58 @@
 package app
 
 import (
-	"GoLoad/internal/configs"
-	consumers "GoLoad/internal/handler/consumer"
-	"GoLoad/internal/handler/grpc"
 	"GoLoad/internal/handler/http"
-	"GoLoad/internal/handler/jobs"
-	"GoLoad/internal/utils"
+	"GoLoad/internal/logic"
 	"context"
-	"syscall"
 
-	"github.com/go-co-op/gocron/v2"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
+// StandaloneServer runs the API server, a worker and the cron scheduler in a single process.
 type StandaloneServer struct {
-	grpcServer                                               grpc.Server
-	httpServer                                               http.Server
-	rootConsumer                                             consumers.Root
-	executeAllPendingDownloadTaskJob                         jobs.ExecuteAllPendingDownloadTask
-	updateDownloadingAndFailedDownloadTaskStatusToPendingJob jobs.UpdateDownloadingAndFailedDownloadTaskStatusToPending
-	cronConfig                                               configs.Cron
-	logger                                                   *zap.Logger
+	apiServer     *APIServer
+	worker        *Worker
+	cronScheduler *CronScheduler
+	metricsServer http.MetricsServer
+	healthLogic   logic.Health
+	logger        *zap.Logger
 }
 
 func NewStandaloneServer(
-	grpcServer grpc.Server,
-	httpServer http.Server,
-	rootConsumer consumers.Root,
-	executeAllPendingDownloadTaskJob jobs.ExecuteAllPendingDownloadTask,
-	updateDownloadingAndFailedDownloadTaskStatusToPendingJob jobs.UpdateDownloadingAndFailedDownloadTaskStatusToPending,
-	cronConfig configs.Cron,
+	apiServer *APIServer,
+	worker *Worker,
+	cronScheduler *CronScheduler,
+	metricsServer http.MetricsServer,
+	healthLogic logic.Health,
 	logger *zap.Logger,
 ) *StandaloneServer {
 	return &StandaloneServer{
-		grpcServer:                       grpcServer,
-		httpServer:                       httpServer,
-		rootConsumer:                     rootConsumer,
-		executeAllPendingDownloadTaskJob: executeAllPendingDownloadTaskJob,
-		updateDownloadingAndFailedDownloadTaskStatusToPendingJob: updateDownloadingAndFailedDownloadTaskStatusToPendingJob,
-		cronConfig: cronConfig,
-		logger:     logger,
+		apiServer:     apiServer,
+		worker:        worker,
+		cronScheduler: cronScheduler,
+		metricsServer: metricsServer,
+		healthLogic:   healthLogic,
+		logger:        logger,
 	}
 }
-func (s StandaloneServer) scheduleCronJobs(scheduler gocron.Scheduler) error {
-	if _, err := scheduler.NewJob(
-		gocron.CronJob(s.cronConfig.ExecuteAllPendingDownloadTask.Schedule, true),
-		gocron.NewTask(func() {
-			if err := s.executeAllPendingDownloadTaskJob.Run(context.Background()); err != nil {
-				s.logger.With(zap.Error(err)).Error("failed to run execute all pending download task job")
-			}
-		}),
-	); err != nil {
-		s.logger.With(zap.Error(err)).Error("failed to schedule execute all pending download task job")
-		return err
-	}
-	if _, err := scheduler.NewJob(
-		gocron.CronJob(s.cronConfig.UpdateDownloadingAndFailedDownloadTaskStatusToPending.Schedule, true),
-		gocron.NewTask(func() {
-			if err := s.executeAllPendingDownloadTaskJob.Run(context.Background()); err != nil {
-				s.logger.With(zap.Error(err)).
-					Error("failed to run update downloading and failed download task status to pending job")
-			}
-		}),
-	); err != nil {
-		s.logger.With(zap.Error(err)).
-			Error("failed to schedule update downloading and failed download task status to pending job")
-		return err
-	}
-	return nil
+
+// run drains all components once ctx is cancelled. If any component fails, the other ones are stopped too.
+func (s StandaloneServer) run(ctx context.Context) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return s.apiServer.run(groupCtx)
+	})
+	group.Go(func() error {
+		return s.worker.run(groupCtx)
+	})
+	group.Go(func() error {
+		return s.cronScheduler.run(groupCtx)
+	})
+	return group.Wait()
 }
 func (s StandaloneServer) Start() error {
-	if err := s.updateDownloadingAndFailedDownloadTaskStatusToPendingJob.Run(context.Background()); err != nil {
-		return err
-	}
-	scheduler, err := gocron.NewScheduler()
-	if err != nil {
-		s.logger.With(zap.Error(err)).Error("failed to initialize scheduler")
-		return err
-	}
-	defer func() {
-		if shutdownErr := scheduler.Shutdown(); shutdownErr != nil {
-			s.logger.With(zap.Error(shutdownErr)).Error("failed to shutdown scheduler")
-		}
-	}()
-	err = s.scheduleCronJobs(scheduler)
-	if err != nil {
-		return err
-	}
-	go func() {
-		grpcStartErr := s.grpcServer.Start(context.Background())
-		s.logger.With(zap.Error(grpcStartErr)).Info("grpc server stopped")
-	}()
-	go func() {
-		httpStartErr := s.httpServer.Start(context.Background())
-		s.logger.With(zap.Error(httpStartErr)).Info("http server stopped")
-	}()
-	go func() {
-		consumerStartErr := s.rootConsumer.Start(context.Background())
-		s.logger.With(zap.Error(consumerStartErr)).Info("message queue consumer stopped")
-	}()
-
-	utils.BlockUntilSignal(syscall.SIGINT, syscall.SIGTERM)
-	return nil
+	return runUntilSignal(func(ctx context.Context) error {
+		return runWithServers(ctx, s.healthLogic, s.run, s.metricsServer)
+	})
 }
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"GoLoad/internal/configs"
+	consumers "GoLoad/internal/handler/consumer"
+	"GoLoad/internal/handler/http"
+	"GoLoad/internal/handler/jobs"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Worker executes download tasks as their events are consumed from the message queue, and periodically executes the
+// pending download tasks left behind. Any number of instances can run, download task leases keeping two workers from
+// executing the same download task.
+type Worker struct {
+	rootConsumer                     consumers.Root
+	executeAllPendingDownloadTaskJob jobs.ExecuteAllPendingDownloadTask
+	healthServer                     http.HealthServer
+	metricsServer                    http.MetricsServer
+	healthLogic                      logic.Health
+	cronConfig                       configs.Cron
+	shutdownConfig                   configs.Shutdown
+	logger                           *zap.Logger
+}
+
+func NewWorker(
+	rootConsumer consumers.Root,
+	executeAllPendingDownloadTaskJob jobs.ExecuteAllPendingDownloadTask,
+	healthServer http.HealthServer,
+	metricsServer http.MetricsServer,
+	healthLogic logic.Health,
+	cronConfig configs.Cron,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) *Worker {
+	return &Worker{
+		rootConsumer:                     rootConsumer,
+		executeAllPendingDownloadTaskJob: executeAllPendingDownloadTaskJob,
+		healthServer:                     healthServer,
+		metricsServer:                    metricsServer,
+		healthLogic:                      healthLogic,
+		cronConfig:                       cronConfig,
+		shutdownConfig:                   shutdownConfig,
+		logger:                           logger,
+	}
+}
+
+// run works until ctx is cancelled, then lets the downloads running finish. Those still running after the shutdown
+// timeout are interrupted and returned to pending.
+func (w Worker) run(ctx context.Context) error {
+	shutdownTimeout, err := w.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		w.logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	scheduler, err := newScheduler(shutdownTimeout)
+	if err != nil {
+		w.logger.With(zap.Error(err)).Error("failed to initialize scheduler")
+		return err
+	}
+	jobCtx, cancelJobs := utils.WithDrainTimeout(ctx, shutdownTimeout)
+	defer cancelJobs()
+	if err = scheduleCronJob(
+		jobCtx,
+		scheduler,
+		w.cronConfig.ExecuteAllPendingDownloadTask.Schedule,
+		"execute all pending download task",
+		w.executeAllPendingDownloadTaskJob.Run,
+		w.logger,
+	); err != nil {
+		return err
+	}
+	consumerCtx, cancelConsumer := context.WithCancel(ctx)
+	defer cancelConsumer()
+	schedulerStoppedChannel := make(chan struct{})
+	scheduler.Start()
+	go func() {
+		defer close(schedulerStoppedChannel)
+		shutdownScheduler(consumerCtx, scheduler, w.logger)
+	}()
+	err = w.rootConsumer.Start(consumerCtx)
+	cancelConsumer()
+	<-schedulerStoppedChannel
+	if err != nil {
+		w.logger.With(zap.Error(err)).Error("worker failed")
+		return err
+	}
+	w.logger.Info("worker stopped")
+	return nil
+}
+func (w Worker) Start() error {
+	return runUntilSignal(func(ctx context.Context) error {
+		return runWithServers(ctx, w.healthLogic, w.run, w.healthServer, w.metricsServer)
+	})
+}
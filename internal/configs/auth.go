@@ -1,13 +1,53 @@
 package configs
 
-import "time"
+import (
+	"encoding/base64"
+	"time"
+)
 
+type HashAlgorithm string
+
+const (
+	HashAlgorithmBcrypt   HashAlgorithm = "bcrypt"
+	HashAlgorithmArgon2id HashAlgorithm = "argon2id"
+)
+
+// Argon2id configures the argon2id parameters, with Memory in KiB.
+type Argon2id struct {
+	Time       uint32 `yaml:"time"`
+	Memory     uint32 `yaml:"memory"`
+	Threads    uint8  `yaml:"threads"`
+	KeyLength  uint32 `yaml:"key_length"`
+	SaltLength uint32 `yaml:"salt_length"`
+}
+
+// Hash configures how new passwords are hashed. Algorithm defaults to bcrypt, which uses Cost; argon2id uses Argon2id.
+// Hashes made with other algorithms or parameters are still accepted and are replaced on the next login.
 type Hash struct {
-	Cost int `yaml:"cost"`
+	Algorithm HashAlgorithm `yaml:"algorithm"`
+	Cost      int           `yaml:"cost"`
+	Argon2id  Argon2id      `yaml:"argon2id"`
+}
+
+// SigningKey configures the RSA keys tokens are signed with. Private keys are stored encrypted with EncryptionKey, a
+// base64 encoded 32 byte AES key, and a new key pair is generated every RotationInterval.
+type SigningKey struct {
+	EncryptionKey    string `yaml:"encryption_key"`
+	RotationInterval string `yaml:"rotation_interval"`
+}
+
+func (s SigningKey) GetEncryptionKeyBytes() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(s.EncryptionKey)
+}
+func (s SigningKey) GetRotationIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(s.RotationInterval)
 }
+
 type Token struct {
-	ExpiresIn                   string `yaml:"expires_in"`
-	RegenerateTokenBeforeExpiry string `yaml:"regenerate_token_before_expiry"`
+	ExpiresIn                   string     `yaml:"expires_in"`
+	RegenerateTokenBeforeExpiry string     `yaml:"regenerate_token_before_expiry"`
+	RefreshTokenExpiresIn       string     `yaml:"refresh_token_expires_in"`
+	SigningKey                  SigningKey `yaml:"signing_key"`
 }
 
 func (t Token) GetExpiresInDuration() (time.Duration, error) {
@@ -16,8 +56,73 @@ func (t Token) GetExpiresInDuration() (time.Duration, error) {
 func (t Token) GetRegenerateTokenBeforeExpiryDuration() (time.Duration, error) {
 	return time.ParseDuration(t.RegenerateTokenBeforeExpiry)
 }
+func (t Token) GetRefreshTokenExpiresInDuration() (time.Duration, error) {
+	return time.ParseDuration(t.RefreshTokenExpiresIn)
+}
+
+type PasswordReset struct {
+	ExpiresIn string `yaml:"expires_in"`
+}
+
+func (p PasswordReset) GetExpiresInDuration() (time.Duration, error) {
+	return time.ParseDuration(p.ExpiresIn)
+}
+
+// PasswordPolicy configures the passwords accepted for new accounts and password changes. BreachedPasswordListFile,
+// if set, is a file with one known breached password per line.
+type PasswordPolicy struct {
+	MinLength                int    `yaml:"min_length"`
+	RequireUppercase         bool   `yaml:"require_uppercase"`
+	RequireLowercase         bool   `yaml:"require_lowercase"`
+	RequireDigit             bool   `yaml:"require_digit"`
+	RequireSymbol            bool   `yaml:"require_symbol"`
+	BreachedPasswordListFile string `yaml:"breached_password_list_file"`
+}
+
+// LoginRateLimit configures the lockout of accounts and IPs after repeated failed logins. Failed attempts are counted
+// until Window passes without one; once a limit is reached, each further failure doubles the lockout, starting from
+// BaseLockoutDuration and capped at MaxLockoutDuration.
+type LoginRateLimit struct {
+	Window                      string `yaml:"window"`
+	MaxFailedAttemptsPerAccount int64  `yaml:"max_failed_attempts_per_account"`
+	MaxFailedAttemptsPerIP      int64  `yaml:"max_failed_attempts_per_ip"`
+	BaseLockoutDuration         string `yaml:"base_lockout_duration"`
+	MaxLockoutDuration          string `yaml:"max_lockout_duration"`
+}
+
+func (l LoginRateLimit) GetWindowDuration() (time.Duration, error) {
+	return time.ParseDuration(l.Window)
+}
+func (l LoginRateLimit) GetBaseLockoutDuration() (time.Duration, error) {
+	return time.ParseDuration(l.BaseLockoutDuration)
+}
+func (l LoginRateLimit) GetMaxLockoutDuration() (time.Duration, error) {
+	return time.ParseDuration(l.MaxLockoutDuration)
+}
+
+// OIDC configures logging in with an OpenID Connect identity provider using the authorization code flow with PKCE.
+// Accounts are created on first login, named after the preferred_username or email claim. LoginExpiresIn bounds how
+// long the user may take at the identity provider, and PostLoginRedirectURL is where the browser is sent afterwards.
+type OIDC struct {
+	Enabled              bool     `yaml:"enabled"`
+	IssuerURL            string   `yaml:"issuer_url"`
+	ClientID             string   `yaml:"client_id"`
+	ClientSecret         string   `yaml:"client_secret"`
+	RedirectURL          string   `yaml:"redirect_url"`
+	Scopes               []string `yaml:"scopes"`
+	LoginExpiresIn       string   `yaml:"login_expires_in"`
+	PostLoginRedirectURL string   `yaml:"post_login_redirect_url"`
+}
+
+func (o OIDC) GetLoginExpiresInDuration() (time.Duration, error) {
+	return time.ParseDuration(o.LoginExpiresIn)
+}
 
 type Auth struct {
-	Hash  Hash
-	Token Token
+	Hash           Hash
+	Token          Token
+	PasswordReset  PasswordReset  `yaml:"password_reset"`
+	PasswordPolicy PasswordPolicy `yaml:"password_policy"`
+	LoginRateLimit LoginRateLimit `yaml:"login_rate_limit"`
+	OIDC           OIDC           `yaml:"oidc"`
 }
@@ -3,6 +3,7 @@ package configs
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"GoLoad/configs"
 
@@ -13,17 +14,30 @@ type ConfigFilePath string
 
 type Config struct {
 	// Log      Log      `yaml:"log"`
-	GRPC     GRPC     `yaml:"grpc"`
-	HTTP     HTTP     `yaml:"http"`
-	Log      Log      `yaml:"log"`
-	Auth     Auth     `yaml:"auth"`
-	Database Database `yaml:"database"`
-	Cache    Cache    `yaml:"cache"`
-	MQ       MQ       `yaml:"mq"`
-	Cron     Cron     `yaml:"cron"`
-	Download Download `yaml:"download"`
+	GRPC      GRPC      `yaml:"grpc"`
+	HTTP      HTTP      `yaml:"http"`
+	Log       Log       `yaml:"log"`
+	Auth      Auth      `yaml:"auth"`
+	Database  Database  `yaml:"database"`
+	Cache     Cache     `yaml:"cache"`
+	MQ        MQ        `yaml:"mq"`
+	Cron      Cron      `yaml:"cron"`
+	Download  Download  `yaml:"download"`
+	Retention Retention `yaml:"retention"`
+	Webhook   Webhook   `yaml:"webhook"`
+	Worker    Worker    `yaml:"worker"`
+	Shutdown  Shutdown  `yaml:"shutdown"`
+	Health    Health    `yaml:"health"`
+	Metrics   Metrics   `yaml:"metrics"`
 }
 
+// parseDurationOrDefault lets duration settings be left out of the config file.
+func parseDurationOrDefault(duration string, defaultDuration time.Duration) (time.Duration, error) {
+	if duration == "" {
+		return defaultDuration, nil
+	}
+	return time.ParseDuration(duration)
+}
 func NewConfig(filePath ConfigFilePath) (Config, error) {
 	var (
 		configBytes = configs.DefaultConfigBytes
@@ -1,5 +1,7 @@
 package configs
 
+import "time"
+
 type ExecuteAllPendingDownloadTask struct {
 	Schedule         string `yaml:"schedule"`
 	ConcurrencyLimit int    `yaml:"concurrency_limit"`
@@ -7,9 +9,54 @@ type ExecuteAllPendingDownloadTask struct {
 type UpdateDownloadingAndFailedDownloadTaskStatusToPending struct {
 	Schedule string `yaml:"schedule"`
 }
+type DeleteExpiredDownloadTaskFile struct {
+	Schedule string `yaml:"schedule"`
+}
+type RotateTokenSigningKey struct {
+	Schedule string `yaml:"schedule"`
+}
+type RelayOutboxEvents struct {
+	Schedule string `yaml:"schedule"`
+}
+type DeliverWebhooks struct {
+	Schedule string `yaml:"schedule"`
+}
+
+// LeaderElection configures how the cron scheduler instances elect the one running the cron jobs. The leader renews its
+// lease every RenewInterval to last LeaseTTL more, and another instance takes over once the lease has expired.
+// CandidateID tells instances apart, and defaults to the host name and process ID. Durations left empty take their
+// default.
+type LeaderElection struct {
+	CandidateID   string `yaml:"candidate_id"`
+	LeaseTTL      string `yaml:"lease_ttl"`
+	RenewInterval string `yaml:"renew_interval"`
+}
+
+const (
+	defaultLeaderElectionLeaseTTL      = 30 * time.Second
+	defaultLeaderElectionRenewInterval = 10 * time.Second
+)
+
+func (l LeaderElection) GetCandidateID() string {
+	if l.CandidateID != "" {
+		return l.CandidateID
+	}
+	return getDefaultInstanceID()
+}
+func (l LeaderElection) GetLeaseTTLDuration() (time.Duration, error) {
+	return parseDurationOrDefault(l.LeaseTTL, defaultLeaderElectionLeaseTTL)
+}
+func (l LeaderElection) GetRenewIntervalDuration() (time.Duration, error) {
+	return parseDurationOrDefault(l.RenewInterval, defaultLeaderElectionRenewInterval)
+}
 
 //nolint:lll // Long field names
 type Cron struct {
 	ExecuteAllPendingDownloadTask                         ExecuteAllPendingDownloadTask                         `yaml:"execute_all_pending_download_task"`
 	UpdateDownloadingAndFailedDownloadTaskStatusToPending UpdateDownloadingAndFailedDownloadTaskStatusToPending `yaml:"update_downloading_and_failed_download_task_status_to_pending"`
+	DeleteExpiredDownloadTaskFile                         DeleteExpiredDownloadTaskFile                         `yaml:"delete_expired_download_task_file"`
+	RotateTokenSigningKey                                 RotateTokenSigningKey                                 `yaml:"rotate_token_signing_key"`
+	RelayOutboxEvents                                     RelayOutboxEvents                                     `yaml:"relay_outbox_events"`
+	DeliverWebhooks                                       DeliverWebhooks                                       `yaml:"deliver_webhooks"`
+	LeaderElection                                        LeaderElection                                        `yaml:"leader_election"`
 }
@@ -1,5 +1,14 @@
 package configs
 
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/yaml.v2"
+)
+
 type DownloadMode string
 
 const (
@@ -7,6 +16,57 @@ const (
 	DownloadModeS3    DownloadMode = "s3"
 )
 
+// FileURL configures the pre-signed URLs of download task files. SigningSecret has no default and must be set to a
+// random value of at least 32 bytes, since anyone knowing it can sign URLs to any file.
+type FileURL struct {
+	ExpiresIn     string `yaml:"expires_in"`
+	BaseURL       string `yaml:"base_url"`
+	SigningSecret string `yaml:"signing_secret"`
+}
+
+func (f FileURL) GetExpiresInDuration() (time.Duration, error) {
+	return time.ParseDuration(f.ExpiresIn)
+}
+
+type EncryptionKey struct {
+	ID  string `yaml:"id"`
+	Key string `yaml:"key"`
+}
+
+// EncryptionKeyring holds the master keys used to wrap per-file data keys. New files are always wrapped with the
+// active key; the other keys are kept so that files written before a rotation can still be decrypted.
+type EncryptionKeyring struct {
+	ActiveKeyID string          `yaml:"active_key_id"`
+	MasterKeys  []EncryptionKey `yaml:"master_keys"`
+}
+
+type Encryption struct {
+	Enabled   bool              `yaml:"enabled"`
+	ChunkSize string            `yaml:"chunk_size"`
+	KeyFile   string            `yaml:"key_file"`
+	Keyring   EncryptionKeyring `yaml:",inline"`
+}
+
+func (e Encryption) GetChunkSizeInBytes() (uint64, error) {
+	return humanize.ParseBytes(e.ChunkSize)
+}
+
+// GetKeyring returns the keyring from the key file if one is configured, or the keyring in the config otherwise.
+func (e Encryption) GetKeyring() (EncryptionKeyring, error) {
+	if e.KeyFile == "" {
+		return e.Keyring, nil
+	}
+	keyFileBytes, err := os.ReadFile(e.KeyFile)
+	if err != nil {
+		return EncryptionKeyring{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+	keyring := EncryptionKeyring{}
+	if err = yaml.Unmarshal(keyFileBytes, &keyring); err != nil {
+		return EncryptionKeyring{}, fmt.Errorf("failed to unmarshal key file: %w", err)
+	}
+	return keyring, nil
+}
+
 type Download struct {
 	Mode              DownloadMode `yaml:"mode"`
 	DownloadDirectory string       `yaml:"download_directory"`
@@ -14,4 +74,6 @@ type Download struct {
 	Address           string       `yaml:"address"`
 	Username          string       `yaml:"username"`
 	Password          string       `yaml:"password"`
+	FileURL           FileURL      `yaml:"file_url"`
+	Encryption        Encryption   `yaml:"encryption"`
 }
@@ -1,6 +1,9 @@
 package configs
 
 import (
+	"fmt"
+	"net/netip"
+
 	"github.com/dustin/go-humanize"
 )
 
@@ -12,7 +15,26 @@ func (g GetDownloadTaskFile) GetResponseBufferSizeInBytes() (uint64, error) {
 	return humanize.ParseBytes(g.ResponseBufferSize)
 }
 
+// GRPC configures the gRPC server. The X-Forwarded-For metadata is only honored on requests coming from loopback, as
+// the HTTP gateway sends them, or from TrustedProxyList, a list of addresses or CIDR prefixes.
 type GRPC struct {
 	Address             string              `yaml:"address"`
 	GetDownloadTaskFile GetDownloadTaskFile `yaml:"get_download_task_file"`
+	TrustedProxyList    []string            `yaml:"trusted_proxy_list"`
+}
+
+func (g GRPC) GetTrustedProxyPrefixList() ([]netip.Prefix, error) {
+	trustedProxyPrefixList := make([]netip.Prefix, 0, len(g.TrustedProxyList))
+	for _, trustedProxy := range g.TrustedProxyList {
+		if addr, err := netip.ParseAddr(trustedProxy); err == nil {
+			trustedProxyPrefixList = append(trustedProxyPrefixList, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(trustedProxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", trustedProxy, err)
+		}
+		trustedProxyPrefixList = append(trustedProxyPrefixList, prefix.Masked())
+	}
+	return trustedProxyPrefixList, nil
 }
@@ -0,0 +1,17 @@
+package configs
+
+// Health configures the listener serving the liveness and readiness probes of the worker and cron scheduler, which
+// have no HTTP server of their own. The API server serves the probes on its HTTP server instead. Address defaults to
+// 0.0.0.0:8082 if left empty.
+type Health struct {
+	Address string `yaml:"address"`
+}
+
+const defaultHealthAddress = "0.0.0.0:8082"
+
+func (h Health) GetAddress() string {
+	if h.Address == "" {
+		return defaultHealthAddress
+	}
+	return h.Address
+}
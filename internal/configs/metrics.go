@@ -0,0 +1,16 @@
+package configs
+
+// Metrics configures the listener serving the Prometheus metrics, kept apart from the public HTTP server so that they
+// are only reachable from the network of the instance. Address defaults to 0.0.0.0:8083 if left empty.
+type Metrics struct {
+	Address string `yaml:"address"`
+}
+
+const defaultMetricsAddress = "0.0.0.0:8083"
+
+func (m Metrics) GetAddress() string {
+	if m.Address == "" {
+		return defaultMetricsAddress
+	}
+	return m.Address
+}
@@ -1,6 +1,87 @@
 package configs
 
+import "time"
+
+// Outbox configures how events written to the outbox table are relayed to the message queue. Failed events are retried
+// with a delay doubling from RetryBaseDelay up to RetryMaxDelay. Sent events are deleted once older than
+// SentEventRetention. Durations left empty take their default.
+type Outbox struct {
+	BatchSize          uint64 `yaml:"batch_size"`
+	RetryBaseDelay     string `yaml:"retry_base_delay"`
+	RetryMaxDelay      string `yaml:"retry_max_delay"`
+	SentEventRetention string `yaml:"sent_event_retention"`
+}
+
+const (
+	defaultOutboxRetryBaseDelay     = time.Second
+	defaultOutboxRetryMaxDelay      = 5 * time.Minute
+	defaultOutboxSentEventRetention = 24 * time.Hour
+	defaultConsumerRetryBaseDelay   = time.Second
+	defaultConsumerRetryMaxDelay    = 30 * time.Second
+)
+
+func (o Outbox) GetRetryBaseDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(o.RetryBaseDelay, defaultOutboxRetryBaseDelay)
+}
+func (o Outbox) GetRetryMaxDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(o.RetryMaxDelay, defaultOutboxRetryMaxDelay)
+}
+func (o Outbox) GetSentEventRetentionDuration() (time.Duration, error) {
+	return parseDurationOrDefault(o.SentEventRetention, defaultOutboxSentEventRetention)
+}
+
+// ConsumerRetry configures how many times a message is handled before it is moved to the dead letter queue, waiting a
+// delay doubling from BaseDelay up to MaxDelay between attempts. Durations left empty take their default.
+type ConsumerRetry struct {
+	MaxAttempts uint64 `yaml:"max_attempts"`
+	BaseDelay   string `yaml:"base_delay"`
+	MaxDelay    string `yaml:"max_delay"`
+}
+
+func (c ConsumerRetry) GetBaseDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(c.BaseDelay, defaultConsumerRetryBaseDelay)
+}
+func (c ConsumerRetry) GetMaxDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(c.MaxDelay, defaultConsumerRetryMaxDelay)
+}
+
+// Consumer configures how messages failing to be handled are retried. QueueRetry overrides DefaultRetry for the queues
+// it lists.
+type Consumer struct {
+	DefaultRetry ConsumerRetry            `yaml:"default_retry"`
+	QueueRetry   map[string]ConsumerRetry `yaml:"queue_retry"`
+}
+
+func (c Consumer) GetRetryOfQueue(queueName string) ConsumerRetry {
+	if retry, ok := c.QueueRetry[queueName]; ok {
+		return retry
+	}
+	return c.DefaultRetry
+}
+
+type MQType string
+
+const (
+	MQTypeKafka    MQType = "kafka"
+	MQTypeInMemory MQType = "in_memory"
+	MQTypeNATS     MQType = "nats"
+	MQTypeRabbitMQ MQType = "rabbitmq"
+)
+
+// MQ configures the message queue. Addresses are broker addresses for Kafka, server URLs for NATS and a single AMQP
+// URL for RabbitMQ. The in-memory message queue only delivers messages within the process and loses them on exit.
 type MQ struct {
+	Type      MQType   `yaml:"type"`
 	Addresses []string `yaml:"addresses"`
 	ClientID  string   `yaml:"client_id"`
+	Outbox    Outbox   `yaml:"outbox"`
+	Consumer  Consumer `yaml:"consumer"`
+}
+
+// GetType defaults to Kafka, the only message queue supported before the type could be configured.
+func (m MQ) GetType() MQType {
+	if m.Type == "" {
+		return MQTypeKafka
+	}
+	return m.Type
 }
@@ -0,0 +1,22 @@
+package configs
+
+import "time"
+
+// Retention configures how long downloaded files are kept. TTLs are Go duration strings, and an empty TTL means files
+// are kept indefinitely.
+type Retention struct {
+	DefaultTTL  string            `yaml:"default_ttl"`
+	AccountTTLs map[string]string `yaml:"account_ttls"`
+}
+
+// GetTTLOfAccount returns the TTL applied to download tasks of the account, with 0 meaning no expiry.
+func (r Retention) GetTTLOfAccount(accountName string) (time.Duration, error) {
+	ttl, ok := r.AccountTTLs[accountName]
+	if !ok {
+		ttl = r.DefaultTTL
+	}
+	if ttl == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(ttl)
+}
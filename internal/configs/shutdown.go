@@ -0,0 +1,24 @@
+package configs
+
+import "time"
+
+// Shutdown configures how long each component is given to drain once the process is asked to stop. The API server
+// first reports itself as not ready for ReadinessGracePeriod, for the orchestrator to stop sending it requests. gRPC and
+// HTTP requests still running after Timeout are aborted, and downloads still running are interrupted and returned to
+// pending. Settings left empty take their default.
+type Shutdown struct {
+	Timeout              string `yaml:"timeout"`
+	ReadinessGracePeriod string `yaml:"readiness_grace_period"`
+}
+
+const (
+	defaultShutdownTimeout              = 30 * time.Second
+	defaultShutdownReadinessGracePeriod = 5 * time.Second
+)
+
+func (s Shutdown) GetTimeoutDuration() (time.Duration, error) {
+	return parseDurationOrDefault(s.Timeout, defaultShutdownTimeout)
+}
+func (s Shutdown) GetReadinessGracePeriodDuration() (time.Duration, error) {
+	return parseDurationOrDefault(s.ReadinessGracePeriod, defaultShutdownReadinessGracePeriod)
+}
@@ -0,0 +1,36 @@
+package configs
+
+import "time"
+
+// Webhook configures how webhook deliveries are sent. A delivery is attempted up to MaxAttempts times, waiting a delay
+// doubling from RetryBaseDelay up to RetryMaxDelay between attempts. Deliveries that are not pending anymore are
+// deleted once older than DeliveryRetention. Durations left empty take their default.
+type Webhook struct {
+	BatchSize         uint64 `yaml:"batch_size"`
+	ConcurrencyLimit  int    `yaml:"concurrency_limit"`
+	MaxAttempts       uint64 `yaml:"max_attempts"`
+	RetryBaseDelay    string `yaml:"retry_base_delay"`
+	RetryMaxDelay     string `yaml:"retry_max_delay"`
+	RequestTimeout    string `yaml:"request_timeout"`
+	DeliveryRetention string `yaml:"delivery_retention"`
+}
+
+const (
+	defaultWebhookRetryBaseDelay    = 10 * time.Second
+	defaultWebhookRetryMaxDelay     = time.Hour
+	defaultWebhookRequestTimeout    = 10 * time.Second
+	defaultWebhookDeliveryRetention = 30 * 24 * time.Hour
+)
+
+func (w Webhook) GetRetryBaseDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.RetryBaseDelay, defaultWebhookRetryBaseDelay)
+}
+func (w Webhook) GetRetryMaxDelayDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.RetryMaxDelay, defaultWebhookRetryMaxDelay)
+}
+func (w Webhook) GetRequestTimeoutDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.RequestTimeout, defaultWebhookRequestTimeout)
+}
+func (w Webhook) GetDeliveryRetentionDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.DeliveryRetention, defaultWebhookDeliveryRetention)
+}
@@ -14,4 +14,10 @@ var WireSet = wire.NewSet(
 	wire.FieldsOf(new(Config), "MQ"),
 	wire.FieldsOf(new(Config), "Cron"),
 	wire.FieldsOf(new(Config), "Download"),
+	wire.FieldsOf(new(Config), "Retention"),
+	wire.FieldsOf(new(Config), "Webhook"),
+	wire.FieldsOf(new(Config), "Worker"),
+	wire.FieldsOf(new(Config), "Shutdown"),
+	wire.FieldsOf(new(Config), "Health"),
+	wire.FieldsOf(new(Config), "Metrics"),
 )
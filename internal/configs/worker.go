@@ -0,0 +1,43 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Worker configures the workers executing download tasks. A worker holds a lease on each download task it executes,
+// renewed every HeartbeatInterval to last LeaseTTL more, and a download task is only taken back from its worker once
+// the lease has expired. ID tells workers apart, and defaults to the host name and process ID. Durations left empty
+// take their default.
+type Worker struct {
+	ID                string `yaml:"id"`
+	LeaseTTL          string `yaml:"lease_ttl"`
+	HeartbeatInterval string `yaml:"heartbeat_interval"`
+}
+
+const (
+	defaultWorkerLeaseTTL          = time.Minute
+	defaultWorkerHeartbeatInterval = 15 * time.Second
+)
+
+// getDefaultInstanceID tells apart the processes running GoLoad by their host name and process ID.
+func getDefaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+func (w Worker) GetID() string {
+	if w.ID != "" {
+		return w.ID
+	}
+	return getDefaultInstanceID()
+}
+func (w Worker) GetLeaseTTLDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.LeaseTTL, defaultWorkerLeaseTTL)
+}
+func (w Worker) GetHeartbeatIntervalDuration() (time.Duration, error) {
+	return parseDurationOrDefault(w.HeartbeatInterval, defaultWorkerHeartbeatInterval)
+}
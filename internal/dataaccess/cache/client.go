@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -24,6 +25,12 @@ type Client interface {
 	Get(ctx context.Context, key string) (any, error)
 	AddToSet(ctx context.Context, key string, data ...any) error
 	IsDataInSet(ctx context.Context, key string, data any) (bool, error)
+	RemoveFromSet(ctx context.Context, key string, data ...any) error
+	// Increment adds one to the counter at key and returns the new value, resetting the key's ttl each time.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+	// Ping reports whether the cache can be reached.
+	Ping(ctx context.Context) error
 }
 
 func NewClient(cacheConfig configs.Cache, logger *zap.Logger) (Client, error) {
@@ -101,31 +108,119 @@ func (c redisClient) IsDataInSet(ctx context.Context, key string, data any) (boo
 	}
 	return result, nil
 }
+func (c redisClient) RemoveFromSet(ctx context.Context, key string, data ...any) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).
+		With(zap.String("key", key)).
+		With(zap.Any("data", data))
+
+	if err := c.redisClient.SRem(ctx, key, data...).Err(); err != nil {
+		logger.With(zap.Error(err)).Error("failed to remove data from set inside cache")
+		return status.Error(codes.Internal, "failed to remove data from set inside cache")
+	}
+	return nil
+}
+func (c redisClient) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	logger := utils.LoggerWithContext(ctx, c.logger).
+		With(zap.String("key", key)).
+		With(zap.Duration("ttl", ttl))
+
+	var incrementCmd *redis.IntCmd
+	if _, err := c.redisClient.TxPipelined(ctx, func(pipeliner redis.Pipeliner) error {
+		incrementCmd = pipeliner.Incr(ctx, key)
+		pipeliner.Expire(ctx, key, ttl)
+		return nil
+	}); err != nil {
+		logger.With(zap.Error(err)).Error("failed to increment counter inside cache")
+		return 0, status.Error(codes.Internal, "failed to increment counter inside cache")
+	}
+	return incrementCmd.Val(), nil
+}
+func (c redisClient) Delete(ctx context.Context, key string) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("key", key))
+
+	if err := c.redisClient.Del(ctx, key).Err(); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete data from cache")
+		return status.Error(codes.Internal, "failed to delete data from cache")
+	}
+	return nil
+}
+
+func (c redisClient) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	if err := c.redisClient.Ping(ctx).Err(); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to ping redis")
+		return status.Error(codes.Unavailable, "failed to ping redis")
+	}
+	return nil
+}
 
 type inMemoryClient struct {
-	cache      map[string]any
-	cacheMutex *sync.Mutex
-	logger     *zap.Logger
+	cache       map[string]any
+	expireTimes map[string]time.Time
+	cacheMutex  *sync.Mutex
+	logger      *zap.Logger
 }
 
 func NewInMemoryClient(logger *zap.Logger) Client {
 	return &inMemoryClient{
-		cache:      make(map[string]any),
-		cacheMutex: new(sync.Mutex),
-		logger:     logger,
+		cache:       make(map[string]any),
+		expireTimes: make(map[string]time.Time),
+		cacheMutex:  new(sync.Mutex),
+		logger:      logger,
+	}
+}
+
+// deleteIfExpired must be called with cacheMutex held.
+func (c inMemoryClient) deleteIfExpired(key string) {
+	if expireTime, ok := c.expireTimes[key]; ok && !time.Now().Before(expireTime) {
+		delete(c.cache, key)
+		delete(c.expireTimes, key)
+	}
+}
+
+// setExpireTime must be called with cacheMutex held. A non-positive ttl means the key never expires.
+func (c inMemoryClient) setExpireTime(key string, ttl time.Duration) {
+	if ttl <= 0 {
+		delete(c.expireTimes, key)
+		return
 	}
+	c.expireTimes[key] = time.Now().Add(ttl)
 }
-func (c inMemoryClient) Set(_ context.Context, key string, data any, _ time.Duration) error {
+func (c inMemoryClient) Set(_ context.Context, key string, data any, ttl time.Duration) error {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
 	c.cache[key] = data
+	c.setExpireTime(key, ttl)
 	return nil
 }
 func (c inMemoryClient) Get(_ context.Context, key string) (any, error) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	c.deleteIfExpired(key)
 	data, ok := c.cache[key]
 	if !ok {
 		return nil, ErrCacheMiss
 	}
 	return data, nil
 }
+func (c inMemoryClient) Increment(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	c.deleteIfExpired(key)
+	count, _ := c.cache[key].(int64)
+	count++
+	c.cache[key] = count
+	c.setExpireTime(key, ttl)
+	return count, nil
+}
+func (c inMemoryClient) Delete(_ context.Context, key string) error {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	delete(c.cache, key)
+	delete(c.expireTimes, key)
+	return nil
+}
 func (c inMemoryClient) AddToSet(_ context.Context, key string, data ...any) error {
 	c.cacheMutex.Lock()
 	defer c.cacheMutex.Unlock()
@@ -145,6 +240,22 @@ func (c inMemoryClient) IsDataInSet(_ context.Context, key string, data any) (bo
 	}
 	return false, nil
 }
+func (c inMemoryClient) RemoveFromSet(_ context.Context, key string, data ...any) error {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	set := c.getSet(key)
+	remainingSet := make([]any, 0, len(set))
+	for i := range set {
+		if !lo.Contains(data, set[i]) {
+			remainingSet = append(remainingSet, set[i])
+		}
+	}
+	c.cache[key] = remainingSet
+	return nil
+}
+func (c inMemoryClient) Ping(context.Context) error {
+	return nil
+}
 func (c inMemoryClient) getSet(key string) []any {
 	setValue, ok := c.cache[key]
 	if !ok {
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const loginLockoutCacheValue = "locked"
+
+// LoginAttempt counts failed logins and records lockouts. Keys identify what is being limited, such as an account name
+// or a client IP.
+type LoginAttempt interface {
+	IncrementFailedCount(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetFailedCount(ctx context.Context, key string) error
+	SetLockout(ctx context.Context, key string, duration time.Duration) error
+	IsLockedOut(ctx context.Context, key string) (bool, error)
+}
+type loginAttempt struct {
+	client Client
+	logger *zap.Logger
+}
+
+func NewLoginAttempt(client Client, logger *zap.Logger) LoginAttempt {
+	return &loginAttempt{
+		client: client,
+		logger: logger,
+	}
+}
+func (c loginAttempt) getFailedCountCacheKey(key string) string {
+	return fmt.Sprintf("login_failed_count:%s", key)
+}
+func (c loginAttempt) getLockoutCacheKey(key string) string {
+	return fmt.Sprintf("login_lockout:%s", key)
+}
+func (c loginAttempt) IncrementFailedCount(ctx context.Context, key string, window time.Duration) (int64, error) {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("key", key))
+
+	failedCount, err := c.client.Increment(ctx, c.getFailedCountCacheKey(key), window)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to increment failed login count in cache")
+		return 0, err
+	}
+	return failedCount, nil
+}
+func (c loginAttempt) ResetFailedCount(ctx context.Context, key string) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("key", key))
+
+	if err := c.client.Delete(ctx, c.getFailedCountCacheKey(key)); err != nil {
+		logger.With(zap.Error(err)).Error("failed to reset failed login count in cache")
+		return err
+	}
+	return nil
+}
+func (c loginAttempt) SetLockout(ctx context.Context, key string, duration time.Duration) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("key", key))
+
+	if err := c.client.Set(ctx, c.getLockoutCacheKey(key), loginLockoutCacheValue, duration); err != nil {
+		logger.With(zap.Error(err)).Error("failed to set login lockout in cache")
+		return err
+	}
+	return nil
+}
+func (c loginAttempt) IsLockedOut(ctx context.Context, key string) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("key", key))
+
+	if _, err := c.client.Get(ctx, c.getLockoutCacheKey(key)); err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return false, nil
+		}
+		logger.With(zap.Error(err)).Error("failed to get login lockout from cache")
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OIDCLoginEntry holds what the callback of an OIDC login needs to check the response of the identity provider.
+type OIDCLoginEntry struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OIDCLogin keeps pending OIDC logins by their state parameter. Take removes the entry, so each state can only be used
+// once.
+type OIDCLogin interface {
+	Set(ctx context.Context, state string, entry OIDCLoginEntry, ttl time.Duration) error
+	Take(ctx context.Context, state string) (OIDCLoginEntry, error)
+}
+type oidcLogin struct {
+	client Client
+	logger *zap.Logger
+}
+
+func NewOIDCLogin(client Client, logger *zap.Logger) OIDCLogin {
+	return &oidcLogin{
+		client: client,
+		logger: logger,
+	}
+}
+func (c oidcLogin) getOIDCLoginCacheKey(state string) string {
+	return fmt.Sprintf("oidc_login:%s", state)
+}
+func (c oidcLogin) Set(ctx context.Context, state string, entry OIDCLoginEntry, ttl time.Duration) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to marshal oidc login entry")
+		return err
+	}
+	if err = c.client.Set(ctx, c.getOIDCLoginCacheKey(state), string(entryBytes), ttl); err != nil {
+		logger.With(zap.Error(err)).Error("failed to set oidc login entry into cache")
+		return err
+	}
+	return nil
+}
+func (c oidcLogin) Take(ctx context.Context, state string) (OIDCLoginEntry, error) {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	cacheKey := c.getOIDCLoginCacheKey(state)
+	cacheEntry, err := c.client.Get(ctx, cacheKey)
+	if err != nil {
+		return OIDCLoginEntry{}, err
+	}
+	if err = c.client.Delete(ctx, cacheKey); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete oidc login entry from cache")
+		return OIDCLoginEntry{}, err
+	}
+	entryString, ok := cacheEntry.(string)
+	if !ok {
+		logger.Error("cache entry is not of type string")
+		return OIDCLoginEntry{}, ErrCacheMiss
+	}
+	entry := OIDCLoginEntry{}
+	if err = json.Unmarshal([]byte(entryString), &entry); err != nil {
+		logger.With(zap.Error(err)).Error("failed to unmarshal oidc login entry")
+		return OIDCLoginEntry{}, ErrCacheMiss
+	}
+	return entry, nil
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	revokedSessionCacheValueRevoked = "revoked"
+	revokedSessionCacheValueActive  = "active"
+)
+
+// RevokedSession caches whether a session has been revoked, so that tokens can be checked for revocation without a
+// database round trip on every request.
+type RevokedSession interface {
+	Get(ctx context.Context, sessionID uint64) (bool, error)
+	Set(ctx context.Context, sessionID uint64, revoked bool, ttl time.Duration) error
+}
+type revokedSession struct {
+	client Client
+	logger *zap.Logger
+}
+
+func NewRevokedSession(client Client, logger *zap.Logger) RevokedSession {
+	return &revokedSession{
+		client: client,
+		logger: logger,
+	}
+}
+func (c revokedSession) getRevokedSessionCacheKey(sessionID uint64) string {
+	return fmt.Sprintf("revoked_session:%d", sessionID)
+}
+func (c revokedSession) Get(ctx context.Context, sessionID uint64) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.Uint64("session_id", sessionID))
+
+	cacheEntry, err := c.client.Get(ctx, c.getRevokedSessionCacheKey(sessionID))
+	if err != nil {
+		return false, err
+	}
+	switch cacheEntry {
+	case revokedSessionCacheValueRevoked:
+		return true, nil
+	case revokedSessionCacheValueActive:
+		return false, nil
+	default:
+		logger.Error("cache entry is not a valid revoked session value")
+		return false, ErrCacheMiss
+	}
+}
+func (c revokedSession) Set(ctx context.Context, sessionID uint64, revoked bool, ttl time.Duration) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.Uint64("session_id", sessionID))
+
+	cacheValue := revokedSessionCacheValueActive
+	if revoked {
+		cacheValue = revokedSessionCacheValueRevoked
+	}
+	if err := c.client.Set(ctx, c.getRevokedSessionCacheKey(sessionID), cacheValue, ttl); err != nil {
+		logger.With(zap.Error(err)).Error("failed to set revoked session into cache")
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"GoLoad/internal/generated/grpc/go_load"
 	"GoLoad/internal/utils"
 	"context"
 
@@ -18,16 +19,26 @@ var (
 const (
 	ColNameAccountsID          = "id"
 	ColNameAccountsAccountName = "account_name"
+	ColNameAccountsRole        = "role"
+	ColNameAccountsDisabled    = "disabled"
 )
 
+// Account is created with the role and disabled columns left to their defaults, go_load.AccountRole_User and false.
 type Account struct {
-	ID          uint64 `db:"id" goqu:"skipinsert,skipupdate"`
-	AccountName string `db:"account_name"`
+	ID          uint64              `db:"id" goqu:"skipinsert,skipupdate"`
+	AccountName string              `db:"account_name" goqu:"skipupdate"`
+	Role        go_load.AccountRole `db:"role"`
+	Disabled    bool                `db:"disabled"`
 }
 type AccountDataAccessor interface {
 	CreateAccount(ctx context.Context, account Account) (uint64, error)
 	GetAccountByID(ctx context.Context, id uint64) (Account, error)
 	GetAccountByAccountName(ctx context.Context, accountName string) (Account, error)
+	GetAccountList(ctx context.Context, offset, limit uint64) ([]Account, error)
+	GetAccountListByIDList(ctx context.Context, idList []uint64) ([]Account, error)
+	GetAccountCount(ctx context.Context) (uint64, error)
+	UpdateAccount(ctx context.Context, account Account) error
+	DeleteAccount(ctx context.Context, id uint64) error
 	WithDatabase(database Database) AccountDataAccessor
 }
 type accountDataAccessor struct {
@@ -105,6 +116,90 @@ func (a *accountDataAccessor) GetAccountByAccountName(ctx context.Context, accou
 	return account, nil
 }
 
+// GetAccountList implements AccountDataAccessor.
+func (a *accountDataAccessor) GetAccountList(ctx context.Context, offset, limit uint64) ([]Account, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).
+		With(zap.Uint64("offset", offset)).
+		With(zap.Uint64("limit", limit))
+
+	accountList := make([]Account, 0)
+	if err := a.database.
+		Select().
+		From(TabNameAccounts).
+		Order(goqu.C(ColNameAccountsID).Asc()).
+		Offset(uint(offset)).
+		Limit(uint(limit)).
+		ScanStructsContext(ctx, &accountList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get account list")
+		return nil, status.Error(codes.Internal, "failed to get account list")
+	}
+	return accountList, nil
+}
+
+// GetAccountListByIDList implements AccountDataAccessor.
+func (a *accountDataAccessor) GetAccountListByIDList(ctx context.Context, idList []uint64) ([]Account, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	accountList := make([]Account, 0)
+	if len(idList) == 0 {
+		return accountList, nil
+	}
+	if err := a.database.
+		Select().
+		From(TabNameAccounts).
+		Where(goqu.C(ColNameAccountsID).In(idList)).
+		ScanStructsContext(ctx, &accountList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get account list by id list")
+		return nil, status.Error(codes.Internal, "failed to get account list by id list")
+	}
+	return accountList, nil
+}
+
+// GetAccountCount implements AccountDataAccessor.
+func (a *accountDataAccessor) GetAccountCount(ctx context.Context) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	count, err := a.database.
+		From(TabNameAccounts).
+		CountContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to count accounts")
+		return 0, status.Error(codes.Internal, "failed to count accounts")
+	}
+	return uint64(count), nil
+}
+
+// UpdateAccount implements AccountDataAccessor.
+func (a *accountDataAccessor) UpdateAccount(ctx context.Context, account Account) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("id", account.ID))
+
+	if _, err := a.database.
+		Update(TabNameAccounts).
+		Set(account).
+		Where(goqu.Ex{ColNameAccountsID: account.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update account")
+		return status.Error(codes.Internal, "failed to update account")
+	}
+	return nil
+}
+
+// DeleteAccount implements AccountDataAccessor.
+func (a *accountDataAccessor) DeleteAccount(ctx context.Context, id uint64) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("id", id))
+
+	if _, err := a.database.
+		Delete(TabNameAccounts).
+		Where(goqu.Ex{ColNameAccountsID: id}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete account")
+		return status.Error(codes.Internal, "failed to delete account")
+	}
+	return nil
+}
+
 // WithDatabase implements AccountDataAccessor.
 func (a *accountDataAccessor) WithDatabase(database Database) AccountDataAccessor {
 	return &accountDataAccessor{
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameAccountIdentities   = goqu.T("account_identities")
+	ErrAccountIdentityNotFound = status.Error(codes.NotFound, "account identity not found")
+)
+
+const (
+	ColNameAccountIdentitiesID          = "id"
+	ColNameAccountIdentitiesOfAccountID = "of_account_id"
+	ColNameAccountIdentitiesIssuer      = "issuer"
+	ColNameAccountIdentitiesSubject     = "subject"
+	ColNameAccountIdentitiesCreatedTime = "created_time"
+)
+
+// AccountIdentity links an account to the subject of an external identity provider, identified by its issuer.
+type AccountIdentity struct {
+	ID          uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID uint64    `db:"of_account_id"`
+	Issuer      string    `db:"issuer"`
+	Subject     string    `db:"subject"`
+	CreatedTime time.Time `db:"created_time"`
+}
+type AccountIdentityDataAccessor interface {
+	CreateAccountIdentity(ctx context.Context, accountIdentity AccountIdentity) (uint64, error)
+	GetAccountIdentityByIssuerAndSubject(ctx context.Context, issuer string, subject string) (AccountIdentity, error)
+	DeleteAccountIdentitiesOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database Database) AccountIdentityDataAccessor
+}
+type accountIdentityDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewAccountIdentityDataAccessor(database *goqu.Database, logger *zap.Logger) AccountIdentityDataAccessor {
+	return &accountIdentityDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (a accountIdentityDataAccessor) CreateAccountIdentity(ctx context.Context, accountIdentity AccountIdentity) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("of_account_id", accountIdentity.OfAccountID))
+
+	result, err := a.database.
+		Insert(TabNameAccountIdentities).
+		Rows(accountIdentity).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create account identity")
+		return 0, status.Error(codes.Internal, "failed to create account identity")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (a accountIdentityDataAccessor) GetAccountIdentityByIssuerAndSubject(
+	ctx context.Context,
+	issuer string,
+	subject string,
+) (AccountIdentity, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.String("issuer", issuer))
+
+	accountIdentity := AccountIdentity{}
+	found, err := a.database.
+		Select().
+		From(TabNameAccountIdentities).
+		Where(goqu.Ex{
+			ColNameAccountIdentitiesIssuer:  issuer,
+			ColNameAccountIdentitiesSubject: subject,
+		}).
+		ScanStructContext(ctx, &accountIdentity)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get account identity")
+		return AccountIdentity{}, status.Error(codes.Internal, "failed to get account identity")
+	}
+	if !found {
+		return AccountIdentity{}, ErrAccountIdentityNotFound
+	}
+	return accountIdentity, nil
+}
+func (a accountIdentityDataAccessor) DeleteAccountIdentitiesOfAccount(ctx context.Context, accountID uint64) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountID))
+
+	if _, err := a.database.
+		Delete(TabNameAccountIdentities).
+		Where(goqu.Ex{ColNameAccountIdentitiesOfAccountID: accountID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete account identities of account")
+		return status.Error(codes.Internal, "failed to delete account identities of account")
+	}
+	return nil
+}
+func (a accountIdentityDataAccessor) WithDatabase(database Database) AccountIdentityDataAccessor {
+	return &accountIdentityDataAccessor{
+		database: database,
+		logger:   a.logger,
+	}
+}
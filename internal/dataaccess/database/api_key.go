@@ -0,0 +1,158 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameAPIKeys    = goqu.T("api_keys")
+	ErrAPIKeyNotFound = status.Error(codes.NotFound, "api key not found")
+)
+
+const (
+	ColNameAPIKeysID          = "id"
+	ColNameAPIKeysOfAccountID = "of_account_id"
+	ColNameAPIKeysName        = "name"
+	ColNameAPIKeysKeyPrefix   = "key_prefix"
+	ColNameAPIKeysKeyHash     = "key_hash"
+	ColNameAPIKeysScopes      = "scopes"
+	ColNameAPIKeysCreatedTime = "created_time"
+	ColNameAPIKeysExpireTime  = "expire_time"
+	ColNameAPIKeysRevoked     = "revoked"
+)
+
+// APIKey only stores the hash of the key. KeyPrefix is the start of the key, kept so owners can tell their keys apart.
+// Scopes is a comma separated list, with an empty list granting every scope.
+type APIKey struct {
+	ID          uint64     `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID uint64     `db:"of_account_id" goqu:"skipupdate"`
+	Name        string     `db:"name"`
+	KeyPrefix   string     `db:"key_prefix" goqu:"skipupdate"`
+	KeyHash     string     `db:"key_hash" goqu:"skipupdate"`
+	Scopes      string     `db:"scopes"`
+	CreatedTime time.Time  `db:"created_time" goqu:"skipupdate"`
+	ExpireTime  *time.Time `db:"expire_time"`
+	Revoked     bool       `db:"revoked"`
+}
+type APIKeyDataAccessor interface {
+	CreateAPIKey(ctx context.Context, apiKey APIKey) (uint64, error)
+	GetAPIKeyWithXLock(ctx context.Context, id uint64) (APIKey, error)
+	GetAPIKeyByKeyHash(ctx context.Context, keyHash string) (APIKey, error)
+	GetAPIKeyListOfAccount(ctx context.Context, accountID uint64) ([]APIKey, error)
+	UpdateAPIKey(ctx context.Context, apiKey APIKey) error
+	DeleteAPIKeysOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database Database) APIKeyDataAccessor
+}
+type apiKeyDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewAPIKeyDataAccessor(database *goqu.Database, logger *zap.Logger) APIKeyDataAccessor {
+	return &apiKeyDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (a apiKeyDataAccessor) CreateAPIKey(ctx context.Context, apiKey APIKey) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("of_account_id", apiKey.OfAccountID))
+
+	result, err := a.database.
+		Insert(TabNameAPIKeys).
+		Rows(apiKey).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create api key")
+		return 0, status.Error(codes.Internal, "failed to create api key")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (a apiKeyDataAccessor) getAPIKey(ctx context.Context, expression goqu.Ex, forUpdate bool) (APIKey, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	query := a.database.
+		Select().
+		From(TabNameAPIKeys).
+		Where(expression)
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	apiKey := APIKey{}
+	found, err := query.ScanStructContext(ctx, &apiKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get api key")
+		return APIKey{}, status.Error(codes.Internal, "failed to get api key")
+	}
+	if !found {
+		logger.Warn("api key not found")
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return apiKey, nil
+}
+func (a apiKeyDataAccessor) GetAPIKeyWithXLock(ctx context.Context, id uint64) (APIKey, error) {
+	return a.getAPIKey(ctx, goqu.Ex{ColNameAPIKeysID: id}, true)
+}
+func (a apiKeyDataAccessor) GetAPIKeyByKeyHash(ctx context.Context, keyHash string) (APIKey, error) {
+	return a.getAPIKey(ctx, goqu.Ex{ColNameAPIKeysKeyHash: keyHash}, false)
+}
+func (a apiKeyDataAccessor) GetAPIKeyListOfAccount(ctx context.Context, accountID uint64) ([]APIKey, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountID))
+
+	apiKeyList := make([]APIKey, 0)
+	if err := a.database.
+		Select().
+		From(TabNameAPIKeys).
+		Where(goqu.Ex{ColNameAPIKeysOfAccountID: accountID}).
+		Order(goqu.C(ColNameAPIKeysID).Asc()).
+		ScanStructsContext(ctx, &apiKeyList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get api key list of account")
+		return nil, status.Error(codes.Internal, "failed to get api key list of account")
+	}
+	return apiKeyList, nil
+}
+func (a apiKeyDataAccessor) UpdateAPIKey(ctx context.Context, apiKey APIKey) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("id", apiKey.ID))
+
+	if _, err := a.database.
+		Update(TabNameAPIKeys).
+		Set(apiKey).
+		Where(goqu.Ex{ColNameAPIKeysID: apiKey.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update api key")
+		return status.Error(codes.Internal, "failed to update api key")
+	}
+	return nil
+}
+func (a apiKeyDataAccessor) DeleteAPIKeysOfAccount(ctx context.Context, accountID uint64) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountID))
+
+	if _, err := a.database.
+		Delete(TabNameAPIKeys).
+		Where(goqu.Ex{ColNameAPIKeysOfAccountID: accountID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete api keys of account")
+		return status.Error(codes.Internal, "failed to delete api keys of account")
+	}
+	return nil
+}
+func (a apiKeyDataAccessor) WithDatabase(database Database) APIKeyDataAccessor {
+	return &apiKeyDataAccessor{
+		database: database,
+		logger:   a.logger,
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameAuditLogs = goqu.T("audit_logs")
+)
+
+const (
+	ColNameAuditLogsID          = "id"
+	ColNameAuditLogsOfAccountID = "of_account_id"
+	ColNameAuditLogsAction      = "action"
+	ColNameAuditLogsClientIP    = "client_ip"
+	ColNameAuditLogsMetadata    = "metadata"
+	ColNameAuditLogsCreatedTime = "created_time"
+)
+
+// AuditLog records a security relevant event. OfAccountID is nil when the event cannot be tied to an existing
+// account, such as a login attempt with an unknown account name.
+type AuditLog struct {
+	ID          uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID *uint64   `db:"of_account_id"`
+	Action      string    `db:"action"`
+	ClientIP    string    `db:"client_ip"`
+	Metadata    JSON      `db:"metadata"`
+	CreatedTime time.Time `db:"created_time"`
+}
+type AuditLogDataAccessor interface {
+	CreateAuditLog(ctx context.Context, auditLog AuditLog) (uint64, error)
+	WithDatabase(database Database) AuditLogDataAccessor
+}
+type auditLogDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewAuditLogDataAccessor(database *goqu.Database, logger *zap.Logger) AuditLogDataAccessor {
+	return &auditLogDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (a auditLogDataAccessor) CreateAuditLog(ctx context.Context, auditLog AuditLog) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.String("action", auditLog.Action))
+
+	result, err := a.database.
+		Insert(TabNameAuditLogs).
+		Rows(auditLog).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create audit log")
+		return 0, status.Error(codes.Internal, "failed to create audit log")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (a auditLogDataAccessor) WithDatabase(database Database) AuditLogDataAccessor {
+	return &auditLogDataAccessor{
+		database: database,
+		logger:   a.logger,
+	}
+}
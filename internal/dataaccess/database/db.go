@@ -67,3 +67,9 @@ func InitializeAndMigrateUpDB(databaseConfig configs.Database, logger *zap.Logge
 func InitializeGoquDB(db *sql.DB) *goqu.Database {
 	return goqu.New("mysql", db)
 }
+
+// PingDatabase reports whether the database can be reached, through a round trip over one of its connections.
+func PingDatabase(ctx context.Context, database Database) error {
+	_, err := database.ExecContext(ctx, "SELECT 1")
+	return err
+}
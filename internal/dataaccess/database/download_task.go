@@ -4,6 +4,7 @@ import (
 	"GoLoad/internal/generated/grpc/go_load"
 	"GoLoad/internal/utils"
 	"context"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"go.uber.org/zap"
@@ -17,34 +18,59 @@ var (
 )
 
 const (
-	ColNameDownloadTaskID             = "id"
-	ColNameDownloadTaskOfAccountID    = "of_account_id"
-	ColNameDownloadTaskDownloadType   = "download_type"
-	ColNameDownloadTaskURL            = "url"
-	ColNameDownloadTaskDownloadStatus = "download_status"
-	ColNameDownloadTaskMetadata       = "metadata"
+	ColNameDownloadTaskID              = "id"
+	ColNameDownloadTaskOfAccountID     = "of_account_id"
+	ColNameDownloadTaskOfWorkspaceID   = "of_workspace_id"
+	ColNameDownloadTaskDownloadType    = "download_type"
+	ColNameDownloadTaskURL             = "url"
+	ColNameDownloadTaskDownloadStatus  = "download_status"
+	ColNameDownloadTaskMetadata        = "metadata"
+	ColNameDownloadTaskExpireTime      = "expire_time"
+	ColNameDownloadTaskWorkerID        = "worker_id"
+	ColNameDownloadTaskLeaseExpireTime = "lease_expire_time"
+	ColNameDownloadTaskHeartbeatTime   = "heartbeat_time"
 )
 
 type DownloadTaskDataAccessor interface {
 	CreateDownloadTask(ctx context.Context, task DownloadTask) (uint64, error)
 	GetDownloadTaskListOfAccount(ctx context.Context, accountID, offset, limit uint64) ([]DownloadTask, error)
 	GetDownloadTaskCountOfAccount(ctx context.Context, accountID uint64) (uint64, error)
+	GetDownloadTaskListOfWorkspace(ctx context.Context, workspaceID, offset, limit uint64) ([]DownloadTask, error)
+	GetDownloadTaskCountOfWorkspace(ctx context.Context, workspaceID uint64) (uint64, error)
+	GetDownloadTaskList(ctx context.Context, offset, limit uint64) ([]DownloadTask, error)
+	GetDownloadTaskCount(ctx context.Context) (uint64, error)
 	GetDownloadTask(ctx context.Context, id uint64) (DownloadTask, error)
 	GetDownloadTaskWithXLock(ctx context.Context, id uint64) (DownloadTask, error)
 	UpdateDownloadTask(ctx context.Context, task DownloadTask) error
 	DeleteDownloadTask(ctx context.Context, id uint64) error
 	GetPendingDownloadTaskIDList(ctx context.Context) ([]uint64, error)
-	UpdateDownloadingAndFailedDownloadTaskStatusToPending(ctx context.Context) error
+	// RenewDownloadTaskLease reports false if the worker does not hold the lease of the downloading task anymore.
+	RenewDownloadTaskLease(ctx context.Context, id uint64, workerID string, leaseExpireTime time.Time) (bool, error)
+	// UpdateExpiredDownloadingDownloadTaskStatusToPending takes back the downloading tasks whose lease expired before
+	// leaseExpireTime.
+	UpdateExpiredDownloadingDownloadTaskStatusToPending(ctx context.Context, leaseExpireTime time.Time) error
+	GetExpiredDownloadTaskIDList(ctx context.Context, expireTime time.Time) ([]uint64, error)
+	// GetDownloadTaskIDListOfAccount only lists the download tasks owned by the account alone.
+	GetDownloadTaskIDListOfAccount(ctx context.Context, accountID uint64) ([]uint64, error)
+	UpdateDownloadTaskAccountOfWorkspace(ctx context.Context, workspaceID, accountID, newAccountID uint64) error
 	WithDatabase(database Database) DownloadTaskDataAccessor
 }
 
+// DownloadTask is owned by OfWorkspaceID when it is set, with OfAccountID being the account that created it.
+// WorkerID, LeaseExpireTime and HeartbeatTime are only set while the download task is downloading, by the worker
+// holding its lease.
 type DownloadTask struct {
-	ID             uint64                 `db:"id" goqu:"skipinsert,skipupdate"`
-	OfAccountID    uint64                 `db:"of_account_id" goqu:"skipupdate"`
-	DownloadType   go_load.DownloadType   `db:"download_type"`
-	URL            string                 `db:"url"`
-	DownloadStatus go_load.DownloadStatus `db:"download_status"`
-	Metadata       JSON                   `db:"metadata"`
+	ID              uint64                 `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID     uint64                 `db:"of_account_id" goqu:"skipupdate"`
+	OfWorkspaceID   *uint64                `db:"of_workspace_id" goqu:"skipupdate"`
+	DownloadType    go_load.DownloadType   `db:"download_type"`
+	URL             string                 `db:"url"`
+	DownloadStatus  go_load.DownloadStatus `db:"download_status"`
+	Metadata        JSON                   `db:"metadata"`
+	ExpireTime      *time.Time             `db:"expire_time"`
+	WorkerID        *string                `db:"worker_id"`
+	LeaseExpireTime *time.Time             `db:"lease_expire_time"`
+	HeartbeatTime   *time.Time             `db:"heartbeat_time"`
 }
 
 type downloadTaskDataAccessor struct {
@@ -95,7 +121,10 @@ func (d downloadTaskDataAccessor) GetDownloadTaskCountOfAccount(ctx context.Cont
 
 	count, err := d.database.
 		From(TabNameDownloadTasks).
-		Where(goqu.Ex{ColNameDownloadTaskOfAccountID: accountID}).
+		Where(goqu.Ex{
+			ColNameDownloadTaskOfAccountID:   accountID,
+			ColNameDownloadTaskOfWorkspaceID: nil,
+		}).
 		CountContext(ctx)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to count download task of user")
@@ -113,7 +142,10 @@ func (d downloadTaskDataAccessor) GetDownloadTaskListOfAccount(ctx context.Conte
 	if err := d.database.
 		Select().
 		From(TabNameDownloadTasks).
-		Where(goqu.Ex{ColNameAccountPasswordsOfAccountID: accountID}).
+		Where(goqu.Ex{
+			ColNameDownloadTaskOfAccountID:   accountID,
+			ColNameDownloadTaskOfWorkspaceID: nil,
+		}).
 		Offset(uint(offset)).
 		Limit(uint(limit)).
 		Executor().
@@ -123,6 +155,71 @@ func (d downloadTaskDataAccessor) GetDownloadTaskListOfAccount(ctx context.Conte
 	}
 	return downloadTaskList, nil
 }
+func (d downloadTaskDataAccessor) GetDownloadTaskCountOfWorkspace(ctx context.Context, workspaceID uint64) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("workspace_id", workspaceID))
+
+	count, err := d.database.
+		From(TabNameDownloadTasks).
+		Where(goqu.Ex{ColNameDownloadTaskOfWorkspaceID: workspaceID}).
+		CountContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to count download task of workspace")
+		return 0, status.Error(codes.Internal, "failed to count download task of workspace")
+	}
+	return uint64(count), nil
+}
+func (d downloadTaskDataAccessor) GetDownloadTaskListOfWorkspace(ctx context.Context, workspaceID uint64, offset uint64, limit uint64) ([]DownloadTask, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.Uint64("workspace_id", workspaceID)).
+		With(zap.Uint64("offset", offset)).
+		With(zap.Uint64("limit", limit))
+
+	downloadTaskList := make([]DownloadTask, 0)
+	if err := d.database.
+		Select().
+		From(TabNameDownloadTasks).
+		Where(goqu.Ex{ColNameDownloadTaskOfWorkspaceID: workspaceID}).
+		Order(goqu.C(ColNameDownloadTaskID).Asc()).
+		Offset(uint(offset)).
+		Limit(uint(limit)).
+		Executor().
+		ScanStructsContext(ctx, &downloadTaskList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get download task list of workspace")
+		return nil, status.Error(codes.Internal, "failed to get download task list of workspace")
+	}
+	return downloadTaskList, nil
+}
+func (d downloadTaskDataAccessor) GetDownloadTaskList(ctx context.Context, offset uint64, limit uint64) ([]DownloadTask, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.Uint64("offset", offset)).
+		With(zap.Uint64("limit", limit))
+
+	downloadTaskList := make([]DownloadTask, 0)
+	if err := d.database.
+		Select().
+		From(TabNameDownloadTasks).
+		Order(goqu.C(ColNameDownloadTaskID).Asc()).
+		Offset(uint(offset)).
+		Limit(uint(limit)).
+		Executor().
+		ScanStructsContext(ctx, &downloadTaskList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get download task list")
+		return nil, status.Error(codes.Internal, "failed to get download task list")
+	}
+	return downloadTaskList, nil
+}
+func (d downloadTaskDataAccessor) GetDownloadTaskCount(ctx context.Context) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger)
+
+	count, err := d.database.
+		From(TabNameDownloadTasks).
+		CountContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to count download tasks")
+		return 0, status.Error(codes.Internal, "failed to count download tasks")
+	}
+	return uint64(count), nil
+}
 func (d downloadTaskDataAccessor) GetDownloadTask(ctx context.Context, id uint64) (DownloadTask, error) {
 	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
 
@@ -193,21 +290,122 @@ func (d downloadTaskDataAccessor) GetPendingDownloadTaskIDList(ctx context.Conte
 	}
 	return downloadTaskIDList, nil
 }
-func (d downloadTaskDataAccessor) UpdateDownloadingAndFailedDownloadTaskStatusToPending(ctx context.Context) error {
+func (d downloadTaskDataAccessor) RenewDownloadTaskLease(
+	ctx context.Context,
+	id uint64,
+	workerID string,
+	leaseExpireTime time.Time,
+) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id)).With(zap.String("worker_id", workerID))
+
+	result, err := d.database.
+		Update(TabNameDownloadTasks).
+		Set(goqu.Record{
+			ColNameDownloadTaskLeaseExpireTime: leaseExpireTime,
+			ColNameDownloadTaskHeartbeatTime:   time.Now(),
+		}).
+		Where(goqu.Ex{
+			ColNameDownloadTaskID:             id,
+			ColNameDownloadTaskWorkerID:       workerID,
+			ColNameDownloadTaskDownloadStatus: go_load.DownloadStatus_Downloading,
+		}).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to renew download task lease")
+		return false, status.Error(codes.Internal, "failed to renew download task lease")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get rows affected")
+		return false, status.Error(codes.Internal, "failed to get rows affected")
+	}
+	return rowsAffected > 0, nil
+}
+func (d downloadTaskDataAccessor) UpdateExpiredDownloadingDownloadTaskStatusToPending(
+	ctx context.Context,
+	leaseExpireTime time.Time,
+) error {
 	logger := utils.LoggerWithContext(ctx, d.logger)
 
 	if _, err := d.database.
 		Update(TabNameDownloadTasks).
 		Set(goqu.Record{
-			ColNameDownloadTaskDownloadStatus: go_load.DownloadStatus_Pending,
+			ColNameDownloadTaskDownloadStatus:  go_load.DownloadStatus_Pending,
+			ColNameDownloadTaskWorkerID:        nil,
+			ColNameDownloadTaskLeaseExpireTime: nil,
+			ColNameDownloadTaskHeartbeatTime:   nil,
 		}).
 		Where(
-			goqu.C(ColNameDownloadTaskDownloadStatus).
-				In(go_load.DownloadStatus_Pending, go_load.DownloadStatus_Failed),
+			goqu.C(ColNameDownloadTaskDownloadStatus).Eq(go_load.DownloadStatus_Downloading),
+			goqu.Or(
+				goqu.C(ColNameDownloadTaskLeaseExpireTime).IsNull(),
+				goqu.C(ColNameDownloadTaskLeaseExpireTime).Lt(leaseExpireTime),
+			),
 		).Executor().
 		ExecContext(ctx); err != nil {
-		logger.With(zap.Error(err)).Error("failed to update downloading and failed download task status to pending")
-		return status.Error(codes.Internal, "failed to update downloading and failed download task status to pending")
+		logger.With(zap.Error(err)).Error("failed to update expired downloading download task status to pending")
+		return status.Error(codes.Internal, "failed to update expired downloading download task status to pending")
+	}
+	return nil
+}
+func (d downloadTaskDataAccessor) GetExpiredDownloadTaskIDList(ctx context.Context, expireTime time.Time) ([]uint64, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Time("expire_time", expireTime))
+
+	downloadTaskIDList := make([]uint64, 0)
+	if err := d.database.
+		Select(ColNameDownloadTaskID).
+		From(TabNameDownloadTasks).
+		Where(
+			goqu.C(ColNameDownloadTaskExpireTime).Lte(expireTime),
+			goqu.C(ColNameDownloadTaskDownloadStatus).
+				NotIn(go_load.DownloadStatus_Downloading, go_load.DownloadStatus_Expired),
+		).
+		ScanValsContext(ctx, &downloadTaskIDList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get expired download task id list")
+		return nil, status.Error(codes.Internal, "failed to get expired download task id list")
+	}
+	return downloadTaskIDList, nil
+}
+func (d downloadTaskDataAccessor) GetDownloadTaskIDListOfAccount(ctx context.Context, accountID uint64) ([]uint64, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("account_id", accountID))
+
+	downloadTaskIDList := make([]uint64, 0)
+	if err := d.database.
+		Select(ColNameDownloadTaskID).
+		From(TabNameDownloadTasks).
+		Where(goqu.Ex{
+			ColNameDownloadTaskOfAccountID:   accountID,
+			ColNameDownloadTaskOfWorkspaceID: nil,
+		}).
+		ScanValsContext(ctx, &downloadTaskIDList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get download task id list of account")
+		return nil, status.Error(codes.Internal, "failed to get download task id list of account")
+	}
+	return downloadTaskIDList, nil
+}
+func (d downloadTaskDataAccessor) UpdateDownloadTaskAccountOfWorkspace(
+	ctx context.Context,
+	workspaceID uint64,
+	accountID uint64,
+	newAccountID uint64,
+) error {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.Uint64("workspace_id", workspaceID)).
+		With(zap.Uint64("account_id", accountID)).
+		With(zap.Uint64("new_account_id", newAccountID))
+
+	if _, err := d.database.
+		Update(TabNameDownloadTasks).
+		Set(goqu.Record{ColNameDownloadTaskOfAccountID: newAccountID}).
+		Where(goqu.Ex{
+			ColNameDownloadTaskOfAccountID:   accountID,
+			ColNameDownloadTaskOfWorkspaceID: workspaceID,
+		}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update download task account of workspace")
+		return status.Error(codes.Internal, "failed to update download task account of workspace")
 	}
 	return nil
 }
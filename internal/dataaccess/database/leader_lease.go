@@ -0,0 +1,102 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameLeaderLeases    = goqu.T("leader_leases")
+	ErrLeaderLeaseNotFound = status.Error(codes.NotFound, "leader lease not found")
+)
+
+const (
+	ColNameLeaderLeasesName            = "name"
+	ColNameLeaderLeasesHolderID        = "holder_id"
+	ColNameLeaderLeasesLeaseExpireTime = "lease_expire_time"
+)
+
+// LeaderLease makes HolderID the leader of Name until LeaseExpireTime.
+type LeaderLease struct {
+	Name            string    `db:"name" goqu:"skipupdate"`
+	HolderID        string    `db:"holder_id"`
+	LeaseExpireTime time.Time `db:"lease_expire_time"`
+}
+type LeaderLeaseDataAccessor interface {
+	// CreateLeaderLease does nothing if the lease already exists.
+	CreateLeaderLease(ctx context.Context, leaderLease LeaderLease) error
+	GetLeaderLeaseWithXLock(ctx context.Context, name string) (LeaderLease, error)
+	UpdateLeaderLease(ctx context.Context, leaderLease LeaderLease) error
+	WithDatabase(database Database) LeaderLeaseDataAccessor
+}
+type leaderLeaseDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewLeaderLeaseDataAccessor(database *goqu.Database, logger *zap.Logger) LeaderLeaseDataAccessor {
+	return &leaderLeaseDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (l leaderLeaseDataAccessor) CreateLeaderLease(ctx context.Context, leaderLease LeaderLease) error {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("name", leaderLease.Name))
+
+	if _, err := l.database.
+		Insert(TabNameLeaderLeases).
+		Rows(leaderLease).
+		OnConflict(goqu.DoNothing()).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to create leader lease")
+		return status.Error(codes.Internal, "failed to create leader lease")
+	}
+	return nil
+}
+func (l leaderLeaseDataAccessor) GetLeaderLeaseWithXLock(ctx context.Context, name string) (LeaderLease, error) {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("name", name))
+
+	leaderLease := LeaderLease{}
+	found, err := l.database.
+		Select().
+		From(TabNameLeaderLeases).
+		Where(goqu.Ex{ColNameLeaderLeasesName: name}).
+		ForUpdate(goqu.Wait).
+		ScanStructContext(ctx, &leaderLease)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get leader lease")
+		return LeaderLease{}, status.Error(codes.Internal, "failed to get leader lease")
+	}
+	if !found {
+		logger.Warn("leader lease not found")
+		return LeaderLease{}, ErrLeaderLeaseNotFound
+	}
+	return leaderLease, nil
+}
+func (l leaderLeaseDataAccessor) UpdateLeaderLease(ctx context.Context, leaderLease LeaderLease) error {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("name", leaderLease.Name))
+
+	if _, err := l.database.
+		Update(TabNameLeaderLeases).
+		Set(leaderLease).
+		Where(goqu.Ex{ColNameLeaderLeasesName: leaderLease.Name}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update leader lease")
+		return status.Error(codes.Internal, "failed to update leader lease")
+	}
+	return nil
+}
+func (l leaderLeaseDataAccessor) WithDatabase(database Database) LeaderLeaseDataAccessor {
+	return &leaderLeaseDataAccessor{
+		database: database,
+		logger:   l.logger,
+	}
+}
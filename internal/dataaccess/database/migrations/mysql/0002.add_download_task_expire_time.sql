@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE download_tasks ADD COLUMN expire_time DATETIME NULL;
+
+CREATE INDEX download_tasks_expire_time_idx ON download_tasks(expire_time);
+
+-- +migrate Down
+DROP INDEX download_tasks_expire_time_idx ON download_tasks;
+
+ALTER TABLE download_tasks DROP COLUMN expire_time;
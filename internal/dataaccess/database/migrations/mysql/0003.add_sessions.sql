@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NOT NULL,
+    refresh_token_hash VARCHAR(128) NOT NULL,
+    expire_time DATETIME NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    UNIQUE (refresh_token_hash),
+    FOREIGN KEY (of_account_id) REFERENCES accounts(id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,21 @@
+-- +migrate Up
+ALTER TABLE token_public_keys ADD COLUMN private_key_ciphertext TEXT NULL;
+
+ALTER TABLE token_public_keys ADD COLUMN created_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE token_public_keys ADD COLUMN retire_time DATETIME NULL;
+
+ALTER TABLE token_public_keys ADD COLUMN expire_time DATETIME NULL;
+
+-- Keys created before this migration have lost their private key, and tokens signed by them lack the session claim
+-- that is now required, so they are expired right away.
+UPDATE token_public_keys SET retire_time = CURRENT_TIMESTAMP, expire_time = CURRENT_TIMESTAMP;
+
+-- +migrate Down
+ALTER TABLE token_public_keys DROP COLUMN expire_time;
+
+ALTER TABLE token_public_keys DROP COLUMN retire_time;
+
+ALTER TABLE token_public_keys DROP COLUMN created_time;
+
+ALTER TABLE token_public_keys DROP COLUMN private_key_ciphertext;
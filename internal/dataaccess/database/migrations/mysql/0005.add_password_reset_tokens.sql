@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NOT NULL,
+    token_hash VARCHAR(128) NOT NULL,
+    expire_time DATETIME NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    UNIQUE (token_hash),
+    FOREIGN KEY (of_account_id) REFERENCES accounts(id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS password_reset_tokens;
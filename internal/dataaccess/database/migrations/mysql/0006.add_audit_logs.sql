@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NULL,
+    action VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    metadata TEXT NOT NULL,
+    created_time DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    INDEX audit_logs_of_account_id_idx (of_account_id),
+    INDEX audit_logs_created_time_idx (created_time)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS audit_logs;
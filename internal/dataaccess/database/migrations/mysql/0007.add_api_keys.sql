@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(256) NOT NULL,
+    key_prefix VARCHAR(32) NOT NULL,
+    key_hash VARCHAR(128) NOT NULL,
+    scopes VARCHAR(256) NOT NULL,
+    created_time DATETIME NOT NULL,
+    expire_time DATETIME NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    UNIQUE (key_hash),
+    FOREIGN KEY (of_account_id) REFERENCES accounts(id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS api_keys;
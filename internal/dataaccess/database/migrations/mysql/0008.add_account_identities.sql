@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS account_identities (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NOT NULL,
+    issuer VARCHAR(512) NOT NULL,
+    subject VARCHAR(256) NOT NULL,
+    created_time DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (issuer, subject),
+    FOREIGN KEY (of_account_id) REFERENCES accounts(id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS account_identities;
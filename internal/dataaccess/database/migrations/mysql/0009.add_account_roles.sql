@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE accounts
+    ADD COLUMN role SMALLINT NOT NULL DEFAULT 2,
+    ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE accounts
+    DROP COLUMN role,
+    DROP COLUMN disabled;
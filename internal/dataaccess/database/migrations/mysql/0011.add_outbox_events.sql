@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    queue_name VARCHAR(256) NOT NULL,
+    payload BLOB NOT NULL,
+    created_time DATETIME NOT NULL,
+    attempt_count INT UNSIGNED NOT NULL DEFAULT 0,
+    next_attempt_time DATETIME NOT NULL,
+    last_error TEXT NULL,
+    sent_time DATETIME NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX outbox_events_sent_time_next_attempt_time_idx ON outbox_events(sent_time, next_attempt_time);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,38 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_account_id BIGINT UNSIGNED NOT NULL,
+    of_download_task_id BIGINT UNSIGNED NULL,
+    url VARCHAR(2000) NOT NULL,
+    secret VARCHAR(256) NOT NULL,
+    created_time DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (of_account_id) REFERENCES accounts(id),
+    FOREIGN KEY (of_download_task_id) REFERENCES download_tasks(id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT UNSIGNED AUTO_INCREMENT,
+    of_webhook_id BIGINT UNSIGNED NOT NULL,
+    of_download_task_id BIGINT UNSIGNED NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload BLOB NOT NULL,
+    status SMALLINT NOT NULL,
+    attempt_count INT UNSIGNED NOT NULL DEFAULT 0,
+    next_attempt_time DATETIME NOT NULL,
+    last_response_status_code INT NULL,
+    last_error TEXT NULL,
+    created_time DATETIME NOT NULL,
+    delivered_time DATETIME NULL,
+    PRIMARY KEY (id),
+    UNIQUE (of_webhook_id, event_id),
+    FOREIGN KEY (of_webhook_id) REFERENCES webhooks(id)
+);
+
+CREATE INDEX webhook_deliveries_status_next_attempt_time_idx ON webhook_deliveries(status, next_attempt_time);
+
+-- +migrate Down
+DROP TABLE IF EXISTS webhook_deliveries;
+
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE download_tasks
+    ADD COLUMN worker_id VARCHAR(256) NULL,
+    ADD COLUMN lease_expire_time DATETIME NULL,
+    ADD COLUMN heartbeat_time DATETIME NULL;
+
+CREATE INDEX download_tasks_download_status_lease_expire_time_idx ON download_tasks(download_status, lease_expire_time);
+
+-- +migrate Down
+DROP INDEX download_tasks_download_status_lease_expire_time_idx ON download_tasks;
+
+ALTER TABLE download_tasks
+    DROP COLUMN worker_id,
+    DROP COLUMN lease_expire_time,
+    DROP COLUMN heartbeat_time;
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS leader_leases (
+    name VARCHAR(256) NOT NULL,
+    holder_id VARCHAR(256) NOT NULL,
+    lease_expire_time DATETIME NOT NULL,
+    PRIMARY KEY (name)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS leader_leases;
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameOutboxEvents = goqu.T("outbox_events")
+)
+
+const (
+	ColNameOutboxEventsID              = "id"
+	ColNameOutboxEventsQueueName       = "queue_name"
+	ColNameOutboxEventsPayload         = "payload"
+	ColNameOutboxEventsCreatedTime     = "created_time"
+	ColNameOutboxEventsAttemptCount    = "attempt_count"
+	ColNameOutboxEventsNextAttemptTime = "next_attempt_time"
+	ColNameOutboxEventsLastError       = "last_error"
+	ColNameOutboxEventsSentTime        = "sent_time"
+)
+
+// OutboxEvent is a message queue event written in the same transaction as the change it announces, to be relayed to
+// the message queue once committed. SentTime is nil until the event has been relayed.
+type OutboxEvent struct {
+	ID              uint64     `db:"id" goqu:"skipinsert,skipupdate"`
+	QueueName       string     `db:"queue_name" goqu:"skipupdate"`
+	Payload         []byte     `db:"payload" goqu:"skipupdate"`
+	CreatedTime     time.Time  `db:"created_time" goqu:"skipupdate"`
+	AttemptCount    uint64     `db:"attempt_count"`
+	NextAttemptTime time.Time  `db:"next_attempt_time"`
+	LastError       *string    `db:"last_error"`
+	SentTime        *time.Time `db:"sent_time"`
+}
+type OutboxEventDataAccessor interface {
+	CreateOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) (uint64, error)
+	// GetPendingOutboxEventListWithXLock skips events locked by other transactions, so that several relays can run at
+	// the same time without sending an event twice.
+	GetPendingOutboxEventListWithXLock(ctx context.Context, nextAttemptTime time.Time, limit uint64) ([]OutboxEvent, error)
+	GetPendingOutboxEventCount(ctx context.Context) (uint64, error)
+	// GetOldestPendingOutboxEventCreatedTime returns nil if there is no pending event.
+	GetOldestPendingOutboxEventCreatedTime(ctx context.Context) (*time.Time, error)
+	UpdateOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) error
+	DeleteSentOutboxEventsBefore(ctx context.Context, sentTime time.Time) error
+	WithDatabase(database Database) OutboxEventDataAccessor
+}
+type outboxEventDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewOutboxEventDataAccessor(database *goqu.Database, logger *zap.Logger) OutboxEventDataAccessor {
+	return &outboxEventDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (o outboxEventDataAccessor) CreateOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger).With(zap.String("queue_name", outboxEvent.QueueName))
+
+	result, err := o.database.
+		Insert(TabNameOutboxEvents).
+		Rows(outboxEvent).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create outbox event")
+		return 0, status.Error(codes.Internal, "failed to create outbox event")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (o outboxEventDataAccessor) GetPendingOutboxEventListWithXLock(
+	ctx context.Context,
+	nextAttemptTime time.Time,
+	limit uint64,
+) ([]OutboxEvent, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	outboxEventList := make([]OutboxEvent, 0)
+	if err := o.database.
+		Select().
+		From(TabNameOutboxEvents).
+		Where(
+			goqu.C(ColNameOutboxEventsSentTime).IsNull(),
+			goqu.C(ColNameOutboxEventsNextAttemptTime).Lte(nextAttemptTime),
+		).
+		Order(goqu.C(ColNameOutboxEventsID).Asc()).
+		Limit(uint(limit)).
+		ForUpdate(goqu.SkipLocked).
+		ScanStructsContext(ctx, &outboxEventList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get pending outbox event list")
+		return nil, status.Error(codes.Internal, "failed to get pending outbox event list")
+	}
+	return outboxEventList, nil
+}
+func (o outboxEventDataAccessor) GetPendingOutboxEventCount(ctx context.Context) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	count, err := o.database.
+		From(TabNameOutboxEvents).
+		Where(goqu.C(ColNameOutboxEventsSentTime).IsNull()).
+		CountContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to count pending outbox events")
+		return 0, status.Error(codes.Internal, "failed to count pending outbox events")
+	}
+	return uint64(count), nil
+}
+func (o outboxEventDataAccessor) GetOldestPendingOutboxEventCreatedTime(ctx context.Context) (*time.Time, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	outboxEvent := OutboxEvent{}
+	found, err := o.database.
+		Select().
+		From(TabNameOutboxEvents).
+		Where(goqu.C(ColNameOutboxEventsSentTime).IsNull()).
+		Order(goqu.C(ColNameOutboxEventsID).Asc()).
+		ScanStructContext(ctx, &outboxEvent)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get oldest pending outbox event")
+		return nil, status.Error(codes.Internal, "failed to get oldest pending outbox event")
+	}
+	if !found {
+		return nil, nil
+	}
+	return &outboxEvent.CreatedTime, nil
+}
+func (o outboxEventDataAccessor) UpdateOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) error {
+	logger := utils.LoggerWithContext(ctx, o.logger).With(zap.Uint64("id", outboxEvent.ID))
+
+	if _, err := o.database.
+		Update(TabNameOutboxEvents).
+		Set(outboxEvent).
+		Where(goqu.Ex{ColNameOutboxEventsID: outboxEvent.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update outbox event")
+		return status.Error(codes.Internal, "failed to update outbox event")
+	}
+	return nil
+}
+func (o outboxEventDataAccessor) DeleteSentOutboxEventsBefore(ctx context.Context, sentTime time.Time) error {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	if _, err := o.database.
+		Delete(TabNameOutboxEvents).
+		Where(goqu.C(ColNameOutboxEventsSentTime).Lt(sentTime)).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete sent outbox events")
+		return status.Error(codes.Internal, "failed to delete sent outbox events")
+	}
+	return nil
+}
+func (o outboxEventDataAccessor) WithDatabase(database Database) OutboxEventDataAccessor {
+	return &outboxEventDataAccessor{
+		database: database,
+		logger:   o.logger,
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNamePasswordResetTokens    = goqu.T("password_reset_tokens")
+	ErrPasswordResetTokenNotFound = status.Error(codes.NotFound, "password reset token not found")
+)
+
+const (
+	ColNamePasswordResetTokensID          = "id"
+	ColNamePasswordResetTokensOfAccountID = "of_account_id"
+	ColNamePasswordResetTokensTokenHash   = "token_hash"
+	ColNamePasswordResetTokensExpireTime  = "expire_time"
+	ColNamePasswordResetTokensUsed        = "used"
+)
+
+type PasswordResetToken struct {
+	ID          uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID uint64    `db:"of_account_id" goqu:"skipupdate"`
+	TokenHash   string    `db:"token_hash"`
+	ExpireTime  time.Time `db:"expire_time"`
+	Used        bool      `db:"used"`
+}
+type PasswordResetTokenDataAccessor interface {
+	CreatePasswordResetToken(ctx context.Context, passwordResetToken PasswordResetToken) (uint64, error)
+	GetPasswordResetTokenByTokenHashWithXLock(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	UpdatePasswordResetToken(ctx context.Context, passwordResetToken PasswordResetToken) error
+	DeletePasswordResetTokensOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database Database) PasswordResetTokenDataAccessor
+}
+type passwordResetTokenDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewPasswordResetTokenDataAccessor(database *goqu.Database, logger *zap.Logger) PasswordResetTokenDataAccessor {
+	return &passwordResetTokenDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (p passwordResetTokenDataAccessor) CreatePasswordResetToken(
+	ctx context.Context,
+	passwordResetToken PasswordResetToken,
+) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, p.logger).With(zap.Uint64("of_account_id", passwordResetToken.OfAccountID))
+
+	result, err := p.database.
+		Insert(TabNamePasswordResetTokens).
+		Rows(passwordResetToken).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create password reset token")
+		return 0, status.Error(codes.Internal, "failed to create password reset token")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (p passwordResetTokenDataAccessor) GetPasswordResetTokenByTokenHashWithXLock(
+	ctx context.Context,
+	tokenHash string,
+) (PasswordResetToken, error) {
+	logger := utils.LoggerWithContext(ctx, p.logger)
+
+	passwordResetToken := PasswordResetToken{}
+	found, err := p.database.
+		Select().
+		From(TabNamePasswordResetTokens).
+		Where(goqu.Ex{ColNamePasswordResetTokensTokenHash: tokenHash}).
+		ForUpdate(goqu.Wait).
+		ScanStructContext(ctx, &passwordResetToken)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get password reset token")
+		return PasswordResetToken{}, status.Error(codes.Internal, "failed to get password reset token")
+	}
+	if !found {
+		logger.Warn("password reset token not found")
+		return PasswordResetToken{}, ErrPasswordResetTokenNotFound
+	}
+	return passwordResetToken, nil
+}
+func (p passwordResetTokenDataAccessor) UpdatePasswordResetToken(
+	ctx context.Context,
+	passwordResetToken PasswordResetToken,
+) error {
+	logger := utils.LoggerWithContext(ctx, p.logger).With(zap.Uint64("id", passwordResetToken.ID))
+
+	if _, err := p.database.
+		Update(TabNamePasswordResetTokens).
+		Set(passwordResetToken).
+		Where(goqu.Ex{ColNamePasswordResetTokensID: passwordResetToken.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update password reset token")
+		return status.Error(codes.Internal, "failed to update password reset token")
+	}
+	return nil
+}
+func (p passwordResetTokenDataAccessor) DeletePasswordResetTokensOfAccount(ctx context.Context, accountID uint64) error {
+	logger := utils.LoggerWithContext(ctx, p.logger).With(zap.Uint64("account_id", accountID))
+
+	if _, err := p.database.
+		Delete(TabNamePasswordResetTokens).
+		Where(goqu.Ex{ColNamePasswordResetTokensOfAccountID: accountID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete password reset tokens of account")
+		return status.Error(codes.Internal, "failed to delete password reset tokens of account")
+	}
+	return nil
+}
+func (p passwordResetTokenDataAccessor) WithDatabase(database Database) PasswordResetTokenDataAccessor {
+	return &passwordResetTokenDataAccessor{
+		database: database,
+		logger:   p.logger,
+	}
+}
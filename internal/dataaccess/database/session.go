@@ -0,0 +1,154 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameSessions    = goqu.T("sessions")
+	ErrSessionNotFound = status.Error(codes.NotFound, "session not found")
+)
+
+const (
+	ColNameSessionsID               = "id"
+	ColNameSessionsOfAccountID      = "of_account_id"
+	ColNameSessionsRefreshTokenHash = "refresh_token_hash"
+	ColNameSessionsExpireTime       = "expire_time"
+	ColNameSessionsRevoked          = "revoked"
+)
+
+type Session struct {
+	ID               uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID      uint64    `db:"of_account_id" goqu:"skipupdate"`
+	RefreshTokenHash string    `db:"refresh_token_hash"`
+	ExpireTime       time.Time `db:"expire_time"`
+	Revoked          bool      `db:"revoked"`
+}
+type SessionDataAccessor interface {
+	CreateSession(ctx context.Context, session Session) (uint64, error)
+	GetSession(ctx context.Context, id uint64) (Session, error)
+	GetSessionWithXLock(ctx context.Context, id uint64) (Session, error)
+	GetSessionByRefreshTokenHashWithXLock(ctx context.Context, refreshTokenHash string) (Session, error)
+	UpdateSession(ctx context.Context, session Session) error
+	GetUnrevokedSessionIDListOfAccount(ctx context.Context, accountID uint64) ([]uint64, error)
+	DeleteSessionsOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database Database) SessionDataAccessor
+}
+type sessionDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewSessionDataAccessor(database *goqu.Database, logger *zap.Logger) SessionDataAccessor {
+	return &sessionDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (s sessionDataAccessor) CreateSession(ctx context.Context, session Session) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.Uint64("of_account_id", session.OfAccountID))
+
+	result, err := s.database.
+		Insert(TabNameSessions).
+		Rows(session).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create session")
+		return 0, status.Error(codes.Internal, "failed to create session")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (s sessionDataAccessor) getSession(ctx context.Context, expression goqu.Ex, forUpdate bool) (Session, error) {
+	logger := utils.LoggerWithContext(ctx, s.logger)
+
+	query := s.database.
+		Select().
+		From(TabNameSessions).
+		Where(expression)
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	session := Session{}
+	found, err := query.ScanStructContext(ctx, &session)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get session")
+		return Session{}, status.Error(codes.Internal, "failed to get session")
+	}
+	if !found {
+		logger.Warn("session not found")
+		return Session{}, ErrSessionNotFound
+	}
+	return session, nil
+}
+func (s sessionDataAccessor) GetSession(ctx context.Context, id uint64) (Session, error) {
+	return s.getSession(ctx, goqu.Ex{ColNameSessionsID: id}, false)
+}
+func (s sessionDataAccessor) GetSessionWithXLock(ctx context.Context, id uint64) (Session, error) {
+	return s.getSession(ctx, goqu.Ex{ColNameSessionsID: id}, true)
+}
+func (s sessionDataAccessor) GetSessionByRefreshTokenHashWithXLock(ctx context.Context, refreshTokenHash string) (Session, error) {
+	return s.getSession(ctx, goqu.Ex{ColNameSessionsRefreshTokenHash: refreshTokenHash}, true)
+}
+func (s sessionDataAccessor) UpdateSession(ctx context.Context, session Session) error {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.Uint64("id", session.ID))
+
+	if _, err := s.database.
+		Update(TabNameSessions).
+		Set(session).
+		Where(goqu.Ex{ColNameSessionsID: session.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update session")
+		return status.Error(codes.Internal, "failed to update session")
+	}
+	return nil
+}
+func (s sessionDataAccessor) GetUnrevokedSessionIDListOfAccount(ctx context.Context, accountID uint64) ([]uint64, error) {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.Uint64("account_id", accountID))
+
+	sessionIDList := make([]uint64, 0)
+	if err := s.database.
+		Select(ColNameSessionsID).
+		From(TabNameSessions).
+		Where(goqu.Ex{
+			ColNameSessionsOfAccountID: accountID,
+			ColNameSessionsRevoked:     false,
+		}).
+		ScanValsContext(ctx, &sessionIDList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get unrevoked session id list of account")
+		return nil, status.Error(codes.Internal, "failed to get unrevoked session id list of account")
+	}
+	return sessionIDList, nil
+}
+func (s sessionDataAccessor) DeleteSessionsOfAccount(ctx context.Context, accountID uint64) error {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.Uint64("account_id", accountID))
+
+	if _, err := s.database.
+		Delete(TabNameSessions).
+		Where(goqu.Ex{ColNameSessionsOfAccountID: accountID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete sessions of account")
+		return status.Error(codes.Internal, "failed to delete sessions of account")
+	}
+	return nil
+}
+func (s sessionDataAccessor) WithDatabase(database Database) SessionDataAccessor {
+	return &sessionDataAccessor{
+		database: database,
+		logger:   s.logger,
+	}
+}
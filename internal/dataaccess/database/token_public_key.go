@@ -4,6 +4,7 @@ import (
 	"GoLoad/internal/utils"
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
@@ -17,17 +18,32 @@ var (
 )
 
 const (
-	ColNameTokenPublicKeysID        = "id"
-	ColNameTokenPublicKeysPublicKey = "public_key"
+	ColNameTokenPublicKeysID                   = "id"
+	ColNameTokenPublicKeysPublicKey            = "public_key"
+	ColNameTokenPublicKeysPrivateKeyCiphertext = "private_key_ciphertext"
+	ColNameTokenPublicKeysCreatedTime          = "created_time"
+	ColNameTokenPublicKeysRetireTime           = "retire_time"
+	ColNameTokenPublicKeysExpireTime           = "expire_time"
 )
 
+// TokenPublicKey is a token signing key pair. A key signs new tokens until its retire time, and tokens it signed are
+// accepted until its expire time.
 type TokenPublicKey struct {
-	ID        uint64 `db:"id" goqu:"skipinsert,skipupdate"`
-	PublicKey string `db:"public_key"`
+	ID                   uint64         `db:"id" goqu:"skipinsert,skipupdate"`
+	PublicKey            string         `db:"public_key"`
+	PrivateKeyCiphertext sql.NullString `db:"private_key_ciphertext"`
+	CreatedTime          time.Time      `db:"created_time"`
+	RetireTime           *time.Time     `db:"retire_time"`
+	ExpireTime           *time.Time     `db:"expire_time"`
 }
 type TokenPublicKeyDataAccessor interface {
 	CreatePublicKey(ctx context.Context, tokenPublicKey TokenPublicKey) (uint64, error)
 	GetPublicKey(ctx context.Context, id uint64) (TokenPublicKey, error)
+	GetActivePublicKey(ctx context.Context) (TokenPublicKey, error)
+	GetActivePublicKeyWithXLock(ctx context.Context) (TokenPublicKey, error)
+	GetUnexpiredPublicKeyList(ctx context.Context, now time.Time) ([]TokenPublicKey, error)
+	RetirePublicKeysExcept(ctx context.Context, id uint64, retireTime, expireTime time.Time) error
+	DeleteExpiredPublicKeys(ctx context.Context, now time.Time) error
 	WithDatabase(database Database) TokenPublicKeyDataAccessor
 }
 type tokenPublicKeyDataAccessor struct {
@@ -43,13 +59,10 @@ func NewTokenPublicKeyDataAccessor(database *goqu.Database, logger *zap.Logger)
 }
 func (a tokenPublicKeyDataAccessor) CreatePublicKey(ctx context.Context, tokenPublicKey TokenPublicKey) (uint64, error) {
 	logger := utils.LoggerWithContext(ctx, a.logger)
-	// log.Println(tokenPublicKey)
 
 	result, err := a.database.
 		Insert(TabNameTokenPublicKeys).
-		Rows(goqu.Record{
-			ColNameTokenPublicKeysPublicKey: tokenPublicKey.PublicKey,
-		}).
+		Rows(tokenPublicKey).
 		Executor().
 		ExecContext(ctx)
 
@@ -86,6 +99,90 @@ func (a tokenPublicKeyDataAccessor) GetPublicKey(ctx context.Context, id uint64)
 	}
 	return tokenPublicKey, nil
 }
+func (a tokenPublicKeyDataAccessor) getActivePublicKey(ctx context.Context, forUpdate bool) (TokenPublicKey, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	query := a.database.
+		Select().
+		From(TabNameTokenPublicKeys).
+		Where(
+			goqu.C(ColNameTokenPublicKeysRetireTime).IsNull(),
+			goqu.C(ColNameTokenPublicKeysPrivateKeyCiphertext).IsNotNull(),
+		).
+		Order(goqu.C(ColNameTokenPublicKeysID).Desc()).
+		Limit(1)
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	tokenPublicKey := TokenPublicKey{}
+	found, err := query.ScanStructContext(ctx, &tokenPublicKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get active public key")
+		return TokenPublicKey{}, status.Error(codes.Internal, "failed to get active public key")
+	}
+	if !found {
+		return TokenPublicKey{}, sql.ErrNoRows
+	}
+	return tokenPublicKey, nil
+}
+func (a tokenPublicKeyDataAccessor) GetActivePublicKey(ctx context.Context) (TokenPublicKey, error) {
+	return a.getActivePublicKey(ctx, false)
+}
+func (a tokenPublicKeyDataAccessor) GetActivePublicKeyWithXLock(ctx context.Context) (TokenPublicKey, error) {
+	return a.getActivePublicKey(ctx, true)
+}
+func (a tokenPublicKeyDataAccessor) GetUnexpiredPublicKeyList(ctx context.Context, now time.Time) ([]TokenPublicKey, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	tokenPublicKeyList := make([]TokenPublicKey, 0)
+	if err := a.database.
+		Select().
+		From(TabNameTokenPublicKeys).
+		Where(goqu.Or(
+			goqu.C(ColNameTokenPublicKeysExpireTime).IsNull(),
+			goqu.C(ColNameTokenPublicKeysExpireTime).Gt(now),
+		)).
+		Order(goqu.C(ColNameTokenPublicKeysID).Asc()).
+		Executor().
+		ScanStructsContext(ctx, &tokenPublicKeyList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get unexpired public key list")
+		return nil, status.Error(codes.Internal, "failed to get unexpired public key list")
+	}
+	return tokenPublicKeyList, nil
+}
+func (a tokenPublicKeyDataAccessor) RetirePublicKeysExcept(ctx context.Context, id uint64, retireTime, expireTime time.Time) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("id", id))
+
+	if _, err := a.database.
+		Update(TabNameTokenPublicKeys).
+		Set(goqu.Record{
+			ColNameTokenPublicKeysRetireTime: retireTime,
+			ColNameTokenPublicKeysExpireTime: expireTime,
+		}).
+		Where(
+			goqu.C(ColNameTokenPublicKeysID).Neq(id),
+			goqu.C(ColNameTokenPublicKeysRetireTime).IsNull(),
+		).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to retire public keys")
+		return status.Error(codes.Internal, "failed to retire public keys")
+	}
+	return nil
+}
+func (a tokenPublicKeyDataAccessor) DeleteExpiredPublicKeys(ctx context.Context, now time.Time) error {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	if _, err := a.database.
+		Delete(TabNameTokenPublicKeys).
+		Where(goqu.C(ColNameTokenPublicKeysExpireTime).Lte(now)).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete expired public keys")
+		return status.Error(codes.Internal, "failed to delete expired public keys")
+	}
+	return nil
+}
 func (a tokenPublicKeyDataAccessor) WithDatabase(database Database) TokenPublicKeyDataAccessor {
 	a.database = database
 	return a
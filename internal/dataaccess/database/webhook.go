@@ -0,0 +1,167 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameWebhooks    = goqu.T("webhooks")
+	ErrWebhookNotFound = status.Error(codes.NotFound, "webhook not found")
+)
+
+const (
+	ColNameWebhooksID               = "id"
+	ColNameWebhooksOfAccountID      = "of_account_id"
+	ColNameWebhooksOfDownloadTaskID = "of_download_task_id"
+	ColNameWebhooksURL              = "url"
+	ColNameWebhooksSecret           = "secret"
+	ColNameWebhooksCreatedTime      = "created_time"
+)
+
+// Webhook is notified of the download task OfDownloadTaskID, or of every download task of OfAccountID if it is nil.
+// Secret is stored as is, since it is needed to sign the payloads sent to the webhook.
+type Webhook struct {
+	ID               uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	OfAccountID      uint64    `db:"of_account_id" goqu:"skipupdate"`
+	OfDownloadTaskID *uint64   `db:"of_download_task_id" goqu:"skipupdate"`
+	URL              string    `db:"url" goqu:"skipupdate"`
+	Secret           string    `db:"secret" goqu:"skipupdate"`
+	CreatedTime      time.Time `db:"created_time" goqu:"skipupdate"`
+}
+type WebhookDataAccessor interface {
+	CreateWebhook(ctx context.Context, webhook Webhook) (uint64, error)
+	GetWebhook(ctx context.Context, id uint64) (Webhook, error)
+	GetWebhookWithXLock(ctx context.Context, id uint64) (Webhook, error)
+	GetWebhookListByIDList(ctx context.Context, idList []uint64) ([]Webhook, error)
+	GetWebhookListOfAccount(ctx context.Context, accountID uint64) ([]Webhook, error)
+	GetWebhookListOfDownloadTask(ctx context.Context, downloadTaskID uint64) ([]Webhook, error)
+	// GetWebhookListToNotifyOfDownloadTask returns the webhooks of the download task and the account-wide webhooks of the
+	// account that created it.
+	GetWebhookListToNotifyOfDownloadTask(ctx context.Context, accountID uint64, downloadTaskID uint64) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint64) error
+	WithDatabase(database Database) WebhookDataAccessor
+}
+type webhookDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewWebhookDataAccessor(database *goqu.Database, logger *zap.Logger) WebhookDataAccessor {
+	return &webhookDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (w webhookDataAccessor) CreateWebhook(ctx context.Context, webhook Webhook) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("of_account_id", webhook.OfAccountID))
+
+	result, err := w.database.
+		Insert(TabNameWebhooks).
+		Rows(webhook).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create webhook")
+		return 0, status.Error(codes.Internal, "failed to create webhook")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (w webhookDataAccessor) getWebhook(ctx context.Context, id uint64, forUpdate bool) (Webhook, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("id", id))
+
+	query := w.database.
+		Select().
+		From(TabNameWebhooks).
+		Where(goqu.Ex{ColNameWebhooksID: id})
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	webhook := Webhook{}
+	found, err := query.ScanStructContext(ctx, &webhook)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get webhook")
+		return Webhook{}, status.Error(codes.Internal, "failed to get webhook")
+	}
+	if !found {
+		logger.Warn("webhook not found")
+		return Webhook{}, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+func (w webhookDataAccessor) GetWebhook(ctx context.Context, id uint64) (Webhook, error) {
+	return w.getWebhook(ctx, id, false)
+}
+func (w webhookDataAccessor) GetWebhookWithXLock(ctx context.Context, id uint64) (Webhook, error) {
+	return w.getWebhook(ctx, id, true)
+}
+func (w webhookDataAccessor) getWebhookList(ctx context.Context, expression goqu.Expression) ([]Webhook, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	webhookList := make([]Webhook, 0)
+	if err := w.database.
+		Select().
+		From(TabNameWebhooks).
+		Where(expression).
+		Order(goqu.C(ColNameWebhooksID).Asc()).
+		ScanStructsContext(ctx, &webhookList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get webhook list")
+		return nil, status.Error(codes.Internal, "failed to get webhook list")
+	}
+	return webhookList, nil
+}
+func (w webhookDataAccessor) GetWebhookListByIDList(ctx context.Context, idList []uint64) ([]Webhook, error) {
+	if len(idList) == 0 {
+		return make([]Webhook, 0), nil
+	}
+	return w.getWebhookList(ctx, goqu.C(ColNameWebhooksID).In(idList))
+}
+func (w webhookDataAccessor) GetWebhookListOfAccount(ctx context.Context, accountID uint64) ([]Webhook, error) {
+	return w.getWebhookList(ctx, goqu.Ex{ColNameWebhooksOfAccountID: accountID})
+}
+func (w webhookDataAccessor) GetWebhookListOfDownloadTask(ctx context.Context, downloadTaskID uint64) ([]Webhook, error) {
+	return w.getWebhookList(ctx, goqu.Ex{ColNameWebhooksOfDownloadTaskID: downloadTaskID})
+}
+func (w webhookDataAccessor) GetWebhookListToNotifyOfDownloadTask(
+	ctx context.Context,
+	accountID uint64,
+	downloadTaskID uint64,
+) ([]Webhook, error) {
+	return w.getWebhookList(ctx, goqu.Or(
+		goqu.C(ColNameWebhooksOfDownloadTaskID).Eq(downloadTaskID),
+		goqu.And(
+			goqu.C(ColNameWebhooksOfAccountID).Eq(accountID),
+			goqu.C(ColNameWebhooksOfDownloadTaskID).IsNull(),
+		),
+	))
+}
+func (w webhookDataAccessor) DeleteWebhook(ctx context.Context, id uint64) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("id", id))
+
+	if _, err := w.database.
+		Delete(TabNameWebhooks).
+		Where(goqu.Ex{ColNameWebhooksID: id}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete webhook")
+		return status.Error(codes.Internal, "failed to delete webhook")
+	}
+	return nil
+}
+func (w webhookDataAccessor) WithDatabase(database Database) WebhookDataAccessor {
+	return &webhookDataAccessor{
+		database: database,
+		logger:   w.logger,
+	}
+}
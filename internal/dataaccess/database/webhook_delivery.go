@@ -0,0 +1,222 @@
+package database
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameWebhookDeliveries = goqu.T("webhook_deliveries")
+)
+
+const (
+	ColNameWebhookDeliveriesID                     = "id"
+	ColNameWebhookDeliveriesOfWebhookID            = "of_webhook_id"
+	ColNameWebhookDeliveriesOfDownloadTaskID       = "of_download_task_id"
+	ColNameWebhookDeliveriesEventID                = "event_id"
+	ColNameWebhookDeliveriesEventType              = "event_type"
+	ColNameWebhookDeliveriesPayload                = "payload"
+	ColNameWebhookDeliveriesStatus                 = "status"
+	ColNameWebhookDeliveriesAttemptCount           = "attempt_count"
+	ColNameWebhookDeliveriesNextAttemptTime        = "next_attempt_time"
+	ColNameWebhookDeliveriesLastResponseStatusCode = "last_response_status_code"
+	ColNameWebhookDeliveriesLastError              = "last_error"
+	ColNameWebhookDeliveriesCreatedTime            = "created_time"
+	ColNameWebhookDeliveriesDeliveredTime          = "delivered_time"
+)
+
+// WebhookDelivery is an event to be sent to a webhook, kept after it is sent as a log of the delivery. A webhook
+// receives each event at most once, EventID being unique per webhook.
+type WebhookDelivery struct {
+	ID                     uint64                        `db:"id" goqu:"skipinsert,skipupdate"`
+	OfWebhookID            uint64                        `db:"of_webhook_id" goqu:"skipupdate"`
+	OfDownloadTaskID       uint64                        `db:"of_download_task_id" goqu:"skipupdate"`
+	EventID                string                        `db:"event_id" goqu:"skipupdate"`
+	EventType              string                        `db:"event_type" goqu:"skipupdate"`
+	Payload                []byte                        `db:"payload" goqu:"skipupdate"`
+	Status                 go_load.WebhookDeliveryStatus `db:"status"`
+	AttemptCount           uint64                        `db:"attempt_count"`
+	NextAttemptTime        time.Time                     `db:"next_attempt_time"`
+	LastResponseStatusCode *int32                        `db:"last_response_status_code"`
+	LastError              *string                       `db:"last_error"`
+	CreatedTime            time.Time                     `db:"created_time" goqu:"skipupdate"`
+	DeliveredTime          *time.Time                    `db:"delivered_time"`
+}
+type WebhookDeliveryDataAccessor interface {
+	// CreateWebhookDelivery does nothing if the webhook already has a delivery of the event.
+	CreateWebhookDelivery(ctx context.Context, webhookDelivery WebhookDelivery) error
+	// GetPendingWebhookDeliveryListWithXLock skips deliveries locked by other transactions, so that several workers can
+	// run at the same time without sending a delivery twice.
+	GetPendingWebhookDeliveryListWithXLock(ctx context.Context, nextAttemptTime time.Time, limit uint64) ([]WebhookDelivery, error)
+	GetWebhookDeliveryListOfWebhook(ctx context.Context, webhookID uint64, offset, limit uint64) ([]WebhookDelivery, error)
+	GetWebhookDeliveryCountOfWebhook(ctx context.Context, webhookID uint64) (uint64, error)
+	UpdateWebhookDelivery(ctx context.Context, webhookDelivery WebhookDelivery) error
+	UpdateWebhookDeliveryListNextAttemptTime(ctx context.Context, idList []uint64, nextAttemptTime time.Time) error
+	DeleteWebhookDeliveriesOfWebhook(ctx context.Context, webhookID uint64) error
+	// DeleteFinishedWebhookDeliveriesBefore deletes the deliveries that are not pending anymore, created before
+	// createdTime.
+	DeleteFinishedWebhookDeliveriesBefore(ctx context.Context, createdTime time.Time) error
+	WithDatabase(database Database) WebhookDeliveryDataAccessor
+}
+type webhookDeliveryDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewWebhookDeliveryDataAccessor(database *goqu.Database, logger *zap.Logger) WebhookDeliveryDataAccessor {
+	return &webhookDeliveryDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (w webhookDeliveryDataAccessor) CreateWebhookDelivery(ctx context.Context, webhookDelivery WebhookDelivery) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("of_webhook_id", webhookDelivery.OfWebhookID)).
+		With(zap.String("event_id", webhookDelivery.EventID))
+
+	if _, err := w.database.
+		Insert(TabNameWebhookDeliveries).
+		Rows(webhookDelivery).
+		OnConflict(goqu.DoNothing()).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to create webhook delivery")
+		return status.Error(codes.Internal, "failed to create webhook delivery")
+	}
+	return nil
+}
+func (w webhookDeliveryDataAccessor) GetPendingWebhookDeliveryListWithXLock(
+	ctx context.Context,
+	nextAttemptTime time.Time,
+	limit uint64,
+) ([]WebhookDelivery, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	webhookDeliveryList := make([]WebhookDelivery, 0)
+	if err := w.database.
+		Select().
+		From(TabNameWebhookDeliveries).
+		Where(
+			goqu.C(ColNameWebhookDeliveriesStatus).Eq(go_load.WebhookDeliveryStatus_WebhookDeliveryPending),
+			goqu.C(ColNameWebhookDeliveriesNextAttemptTime).Lte(nextAttemptTime),
+		).
+		Order(goqu.C(ColNameWebhookDeliveriesID).Asc()).
+		Limit(uint(limit)).
+		ForUpdate(goqu.SkipLocked).
+		ScanStructsContext(ctx, &webhookDeliveryList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get pending webhook delivery list")
+		return nil, status.Error(codes.Internal, "failed to get pending webhook delivery list")
+	}
+	return webhookDeliveryList, nil
+}
+func (w webhookDeliveryDataAccessor) GetWebhookDeliveryListOfWebhook(
+	ctx context.Context,
+	webhookID uint64,
+	offset, limit uint64,
+) ([]WebhookDelivery, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("webhook_id", webhookID))
+
+	webhookDeliveryList := make([]WebhookDelivery, 0)
+	if err := w.database.
+		Select().
+		From(TabNameWebhookDeliveries).
+		Where(goqu.Ex{ColNameWebhookDeliveriesOfWebhookID: webhookID}).
+		Order(goqu.C(ColNameWebhookDeliveriesID).Desc()).
+		Offset(uint(offset)).
+		Limit(uint(limit)).
+		ScanStructsContext(ctx, &webhookDeliveryList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get webhook delivery list of webhook")
+		return nil, status.Error(codes.Internal, "failed to get webhook delivery list of webhook")
+	}
+	return webhookDeliveryList, nil
+}
+func (w webhookDeliveryDataAccessor) GetWebhookDeliveryCountOfWebhook(ctx context.Context, webhookID uint64) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("webhook_id", webhookID))
+
+	count, err := w.database.
+		From(TabNameWebhookDeliveries).
+		Where(goqu.Ex{ColNameWebhookDeliveriesOfWebhookID: webhookID}).
+		CountContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to count webhook deliveries of webhook")
+		return 0, status.Error(codes.Internal, "failed to count webhook deliveries of webhook")
+	}
+	return uint64(count), nil
+}
+func (w webhookDeliveryDataAccessor) UpdateWebhookDelivery(ctx context.Context, webhookDelivery WebhookDelivery) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("id", webhookDelivery.ID))
+
+	if _, err := w.database.
+		Update(TabNameWebhookDeliveries).
+		Set(webhookDelivery).
+		Where(goqu.Ex{ColNameWebhookDeliveriesID: webhookDelivery.ID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update webhook delivery")
+		return status.Error(codes.Internal, "failed to update webhook delivery")
+	}
+	return nil
+}
+func (w webhookDeliveryDataAccessor) UpdateWebhookDeliveryListNextAttemptTime(
+	ctx context.Context,
+	idList []uint64,
+	nextAttemptTime time.Time,
+) error {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	if len(idList) == 0 {
+		return nil
+	}
+	if _, err := w.database.
+		Update(TabNameWebhookDeliveries).
+		Set(goqu.Record{ColNameWebhookDeliveriesNextAttemptTime: nextAttemptTime}).
+		Where(goqu.C(ColNameWebhookDeliveriesID).In(idList)).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update webhook delivery list next attempt time")
+		return status.Error(codes.Internal, "failed to update webhook delivery list next attempt time")
+	}
+	return nil
+}
+func (w webhookDeliveryDataAccessor) DeleteWebhookDeliveriesOfWebhook(ctx context.Context, webhookID uint64) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("webhook_id", webhookID))
+
+	if _, err := w.database.
+		Delete(TabNameWebhookDeliveries).
+		Where(goqu.Ex{ColNameWebhookDeliveriesOfWebhookID: webhookID}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete webhook deliveries of webhook")
+		return status.Error(codes.Internal, "failed to delete webhook deliveries of webhook")
+	}
+	return nil
+}
+func (w webhookDeliveryDataAccessor) DeleteFinishedWebhookDeliveriesBefore(ctx context.Context, createdTime time.Time) error {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	if _, err := w.database.
+		Delete(TabNameWebhookDeliveries).
+		Where(
+			goqu.C(ColNameWebhookDeliveriesStatus).Neq(go_load.WebhookDeliveryStatus_WebhookDeliveryPending),
+			goqu.C(ColNameWebhookDeliveriesCreatedTime).Lt(createdTime),
+		).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete finished webhook deliveries")
+		return status.Error(codes.Internal, "failed to delete finished webhook deliveries")
+	}
+	return nil
+}
+func (w webhookDeliveryDataAccessor) WithDatabase(database Database) WebhookDeliveryDataAccessor {
+	return &webhookDeliveryDataAccessor{
+		database: database,
+		logger:   w.logger,
+	}
+}
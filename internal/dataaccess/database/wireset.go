@@ -12,4 +12,15 @@ var WireSet = wire.NewSet(
 	NewAccountPasswordDataAccessor,
 	NewDownloadTaskDataAccessor,
 	NewTokenPublicKeyDataAccessor,
+	NewSessionDataAccessor,
+	NewPasswordResetTokenDataAccessor,
+	NewAuditLogDataAccessor,
+	NewAPIKeyDataAccessor,
+	NewAccountIdentityDataAccessor,
+	NewWorkspaceDataAccessor,
+	NewWorkspaceMemberDataAccessor,
+	NewOutboxEventDataAccessor,
+	NewWebhookDataAccessor,
+	NewWebhookDeliveryDataAccessor,
+	NewLeaderLeaseDataAccessor,
 )
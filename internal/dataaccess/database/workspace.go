@@ -0,0 +1,118 @@
+package database
+
+import (
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameWorkspaces    = goqu.T("workspaces")
+	ErrWorkspaceNotFound = status.Error(codes.NotFound, "workspace not found")
+)
+
+const (
+	ColNameWorkspacesID            = "id"
+	ColNameWorkspacesWorkspaceName = "workspace_name"
+	ColNameWorkspacesCreatedTime   = "created_time"
+)
+
+type Workspace struct {
+	ID            uint64    `db:"id" goqu:"skipinsert,skipupdate"`
+	WorkspaceName string    `db:"workspace_name"`
+	CreatedTime   time.Time `db:"created_time" goqu:"skipupdate"`
+}
+type WorkspaceDataAccessor interface {
+	CreateWorkspace(ctx context.Context, workspace Workspace) (uint64, error)
+	GetWorkspace(ctx context.Context, id uint64) (Workspace, error)
+	GetWorkspaceWithXLock(ctx context.Context, id uint64) (Workspace, error)
+	GetWorkspaceListByIDList(ctx context.Context, idList []uint64) ([]Workspace, error)
+	WithDatabase(database Database) WorkspaceDataAccessor
+}
+type workspaceDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewWorkspaceDataAccessor(database *goqu.Database, logger *zap.Logger) WorkspaceDataAccessor {
+	return &workspaceDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (w workspaceDataAccessor) CreateWorkspace(ctx context.Context, workspace Workspace) (uint64, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.String("workspace_name", workspace.WorkspaceName))
+
+	result, err := w.database.
+		Insert(TabNameWorkspaces).
+		Rows(workspace).
+		Executor().
+		ExecContext(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create workspace")
+		return 0, status.Error(codes.Internal, "failed to create workspace")
+	}
+	lastInsertedID, err := result.LastInsertId()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get last inserted id")
+		return 0, status.Error(codes.Internal, "failed to get last inserted id")
+	}
+	return uint64(lastInsertedID), nil
+}
+func (w workspaceDataAccessor) getWorkspace(ctx context.Context, id uint64, forUpdate bool) (Workspace, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.Uint64("id", id))
+
+	query := w.database.
+		Select().
+		From(TabNameWorkspaces).
+		Where(goqu.Ex{ColNameWorkspacesID: id})
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	workspace := Workspace{}
+	found, err := query.ScanStructContext(ctx, &workspace)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get workspace")
+		return Workspace{}, status.Error(codes.Internal, "failed to get workspace")
+	}
+	if !found {
+		logger.Warn("workspace not found")
+		return Workspace{}, ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
+func (w workspaceDataAccessor) GetWorkspace(ctx context.Context, id uint64) (Workspace, error) {
+	return w.getWorkspace(ctx, id, false)
+}
+func (w workspaceDataAccessor) GetWorkspaceWithXLock(ctx context.Context, id uint64) (Workspace, error) {
+	return w.getWorkspace(ctx, id, true)
+}
+func (w workspaceDataAccessor) GetWorkspaceListByIDList(ctx context.Context, idList []uint64) ([]Workspace, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	workspaceList := make([]Workspace, 0)
+	if len(idList) == 0 {
+		return workspaceList, nil
+	}
+	if err := w.database.
+		Select().
+		From(TabNameWorkspaces).
+		Where(goqu.C(ColNameWorkspacesID).In(idList)).
+		Order(goqu.C(ColNameWorkspacesID).Asc()).
+		ScanStructsContext(ctx, &workspaceList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get workspace list by id list")
+		return nil, status.Error(codes.Internal, "failed to get workspace list by id list")
+	}
+	return workspaceList, nil
+}
+func (w workspaceDataAccessor) WithDatabase(database Database) WorkspaceDataAccessor {
+	return &workspaceDataAccessor{
+		database: database,
+		logger:   w.logger,
+	}
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	TabNameWorkspaceMembers    = goqu.T("workspace_members")
+	ErrWorkspaceMemberNotFound = status.Error(codes.NotFound, "workspace member not found")
+)
+
+const (
+	ColNameWorkspaceMembersOfWorkspaceID = "of_workspace_id"
+	ColNameWorkspaceMembersOfAccountID   = "of_account_id"
+	ColNameWorkspaceMembersRole          = "role"
+)
+
+type WorkspaceMember struct {
+	OfWorkspaceID uint64                `db:"of_workspace_id" goqu:"skipupdate"`
+	OfAccountID   uint64                `db:"of_account_id" goqu:"skipupdate"`
+	Role          go_load.WorkspaceRole `db:"role"`
+}
+type WorkspaceMemberDataAccessor interface {
+	CreateWorkspaceMember(ctx context.Context, workspaceMember WorkspaceMember) error
+	GetWorkspaceMember(ctx context.Context, workspaceID, accountID uint64) (WorkspaceMember, error)
+	GetWorkspaceMemberWithXLock(ctx context.Context, workspaceID, accountID uint64) (WorkspaceMember, error)
+	GetWorkspaceMemberListOfWorkspace(ctx context.Context, workspaceID uint64) ([]WorkspaceMember, error)
+	GetWorkspaceMemberListOfAccount(ctx context.Context, accountID uint64) ([]WorkspaceMember, error)
+	UpdateWorkspaceMember(ctx context.Context, workspaceMember WorkspaceMember) error
+	DeleteWorkspaceMember(ctx context.Context, workspaceID, accountID uint64) error
+	WithDatabase(database Database) WorkspaceMemberDataAccessor
+}
+type workspaceMemberDataAccessor struct {
+	database Database
+	logger   *zap.Logger
+}
+
+func NewWorkspaceMemberDataAccessor(database *goqu.Database, logger *zap.Logger) WorkspaceMemberDataAccessor {
+	return &workspaceMemberDataAccessor{
+		database: database,
+		logger:   logger,
+	}
+}
+func (w workspaceMemberDataAccessor) CreateWorkspaceMember(ctx context.Context, workspaceMember WorkspaceMember) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("of_workspace_id", workspaceMember.OfWorkspaceID)).
+		With(zap.Uint64("of_account_id", workspaceMember.OfAccountID))
+
+	if _, err := w.database.
+		Insert(TabNameWorkspaceMembers).
+		Rows(workspaceMember).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to create workspace member")
+		return status.Error(codes.Internal, "failed to create workspace member")
+	}
+	return nil
+}
+func (w workspaceMemberDataAccessor) getWorkspaceMember(
+	ctx context.Context,
+	workspaceID uint64,
+	accountID uint64,
+	forUpdate bool,
+) (WorkspaceMember, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("workspace_id", workspaceID)).
+		With(zap.Uint64("account_id", accountID))
+
+	query := w.database.
+		Select().
+		From(TabNameWorkspaceMembers).
+		Where(goqu.Ex{
+			ColNameWorkspaceMembersOfWorkspaceID: workspaceID,
+			ColNameWorkspaceMembersOfAccountID:   accountID,
+		})
+	if forUpdate {
+		query = query.ForUpdate(goqu.Wait)
+	}
+	workspaceMember := WorkspaceMember{}
+	found, err := query.ScanStructContext(ctx, &workspaceMember)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get workspace member")
+		return WorkspaceMember{}, status.Error(codes.Internal, "failed to get workspace member")
+	}
+	if !found {
+		logger.Warn("workspace member not found")
+		return WorkspaceMember{}, ErrWorkspaceMemberNotFound
+	}
+	return workspaceMember, nil
+}
+func (w workspaceMemberDataAccessor) GetWorkspaceMember(ctx context.Context, workspaceID, accountID uint64) (WorkspaceMember, error) {
+	return w.getWorkspaceMember(ctx, workspaceID, accountID, false)
+}
+func (w workspaceMemberDataAccessor) GetWorkspaceMemberWithXLock(ctx context.Context, workspaceID, accountID uint64) (WorkspaceMember, error) {
+	return w.getWorkspaceMember(ctx, workspaceID, accountID, true)
+}
+func (w workspaceMemberDataAccessor) getWorkspaceMemberList(ctx context.Context, expression goqu.Ex) ([]WorkspaceMember, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	workspaceMemberList := make([]WorkspaceMember, 0)
+	if err := w.database.
+		Select().
+		From(TabNameWorkspaceMembers).
+		Where(expression).
+		Order(goqu.C(ColNameWorkspaceMembersOfWorkspaceID).Asc(), goqu.C(ColNameWorkspaceMembersOfAccountID).Asc()).
+		ScanStructsContext(ctx, &workspaceMemberList); err != nil {
+		logger.With(zap.Error(err)).Error("failed to get workspace member list")
+		return nil, status.Error(codes.Internal, "failed to get workspace member list")
+	}
+	return workspaceMemberList, nil
+}
+func (w workspaceMemberDataAccessor) GetWorkspaceMemberListOfWorkspace(ctx context.Context, workspaceID uint64) ([]WorkspaceMember, error) {
+	return w.getWorkspaceMemberList(ctx, goqu.Ex{ColNameWorkspaceMembersOfWorkspaceID: workspaceID})
+}
+func (w workspaceMemberDataAccessor) GetWorkspaceMemberListOfAccount(ctx context.Context, accountID uint64) ([]WorkspaceMember, error) {
+	return w.getWorkspaceMemberList(ctx, goqu.Ex{ColNameWorkspaceMembersOfAccountID: accountID})
+}
+func (w workspaceMemberDataAccessor) UpdateWorkspaceMember(ctx context.Context, workspaceMember WorkspaceMember) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("of_workspace_id", workspaceMember.OfWorkspaceID)).
+		With(zap.Uint64("of_account_id", workspaceMember.OfAccountID))
+
+	if _, err := w.database.
+		Update(TabNameWorkspaceMembers).
+		Set(workspaceMember).
+		Where(goqu.Ex{
+			ColNameWorkspaceMembersOfWorkspaceID: workspaceMember.OfWorkspaceID,
+			ColNameWorkspaceMembersOfAccountID:   workspaceMember.OfAccountID,
+		}).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to update workspace member")
+		return status.Error(codes.Internal, "failed to update workspace member")
+	}
+	return nil
+}
+func (w workspaceMemberDataAccessor) deleteWorkspaceMembers(ctx context.Context, expression goqu.Ex) error {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	if _, err := w.database.
+		Delete(TabNameWorkspaceMembers).
+		Where(expression).
+		Executor().
+		ExecContext(ctx); err != nil {
+		logger.With(zap.Error(err)).Error("failed to delete workspace members")
+		return status.Error(codes.Internal, "failed to delete workspace members")
+	}
+	return nil
+}
+func (w workspaceMemberDataAccessor) DeleteWorkspaceMember(ctx context.Context, workspaceID, accountID uint64) error {
+	return w.deleteWorkspaceMembers(ctx, goqu.Ex{
+		ColNameWorkspaceMembersOfWorkspaceID: workspaceID,
+		ColNameWorkspaceMembersOfAccountID:   accountID,
+	})
+}
+func (w workspaceMemberDataAccessor) WithDatabase(database Database) WorkspaceMemberDataAccessor {
+	return &workspaceMemberDataAccessor{
+		database: database,
+		logger:   w.logger,
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"GoLoad/internal/configs"
 	"GoLoad/internal/utils"
@@ -18,20 +20,48 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+var (
+	ErrPresignedURLNotSupported = errors.New("presigned url is not supported")
+)
+
+type PresignedURLOptions struct {
+	ExpiresIn          time.Duration
+	ContentType        string
+	ContentDisposition string
+}
+
 type Client interface {
 	Write(ctx context.Context, filePath string) (io.WriteCloser, error)
-	Read(ctx context.Context, filePath string) (io.ReadCloser, error)
+	// Read returns a reader over length bytes of the file starting at offset. A non-positive length reads until
+	// the end of the file.
+	Read(ctx context.Context, filePath string, offset, length int64) (io.ReadCloser, error)
+	GetPresignedURL(ctx context.Context, filePath string, options PresignedURLOptions) (string, error)
+	// Delete removes the file, succeeding if it does not exist.
+	Delete(ctx context.Context, filePath string) error
+	// Ping reports whether the storage backend can be reached.
+	Ping(ctx context.Context) error
 }
 
 func NewClient(downloadConfig configs.Download, logger *zap.Logger) (Client, error) {
+	var (
+		client Client
+		err    error
+	)
 	switch downloadConfig.Mode {
 	case configs.DownloadModeLocal:
-		return NewLocalClient(downloadConfig, logger)
+		client, err = NewLocalClient(downloadConfig, logger)
 	case configs.DownloadModeS3:
-		return NewS3Client(downloadConfig, logger)
+		client, err = NewS3Client(downloadConfig, logger)
 	default:
 		return nil, fmt.Errorf("unsupported download mode: %s", downloadConfig.Mode)
 	}
+	if err != nil {
+		return nil, err
+	}
+	if !downloadConfig.Encryption.Enabled {
+		return client, nil
+	}
+	return NewEncryptedClient(client, downloadConfig.Encryption, logger)
 }
 
 type bufferedFileReader struct {
@@ -39,10 +69,14 @@ type bufferedFileReader struct {
 	bufferedReader io.Reader
 }
 
-func newBufferedFileReader(file *os.File) io.ReadCloser {
+func newBufferedFileReader(file *os.File, length int64) io.ReadCloser {
+	var bufferedReader io.Reader = bufio.NewReader(file)
+	if length > 0 {
+		bufferedReader = io.LimitReader(bufferedReader, length)
+	}
 	return &bufferedFileReader{
 		file:           file,
-		bufferedReader: bufio.NewReader(file),
+		bufferedReader: bufferedReader,
 	}
 }
 func (b bufferedFileReader) Close() error {
@@ -68,8 +102,11 @@ func NewLocalClient(downloadConfig configs.Download, logger *zap.Logger) (Client
 		logger:            logger,
 	}, nil
 }
-func (l LocalClient) Read(ctx context.Context, filePath string) (io.ReadCloser, error) {
-	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("file_path", filePath))
+func (l LocalClient) Read(ctx context.Context, filePath string, offset, length int64) (io.ReadCloser, error) {
+	logger := utils.LoggerWithContext(ctx, l.logger).
+		With(zap.String("file_path", filePath)).
+		With(zap.Int64("offset", offset)).
+		With(zap.Int64("length", length))
 
 	absolutePath := path.Join(l.downloadDirectory, filePath)
 	file, err := os.Open(absolutePath)
@@ -77,7 +114,39 @@ func (l LocalClient) Read(ctx context.Context, filePath string) (io.ReadCloser,
 		logger.With(zap.Error(err)).Error("failed to open file")
 		return nil, status.Error(codes.Internal, "failed to open file")
 	}
-	return newBufferedFileReader(file), nil
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		logger.With(zap.Error(err)).Error("failed to seek file")
+		return nil, status.Error(codes.Internal, "failed to seek file")
+	}
+	return newBufferedFileReader(file, length), nil
+}
+func (l LocalClient) GetPresignedURL(context.Context, string, PresignedURLOptions) (string, error) {
+	return "", ErrPresignedURLNotSupported
+}
+func (l LocalClient) Delete(ctx context.Context, filePath string) error {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("file_path", filePath))
+
+	absolutePath := path.Join(l.downloadDirectory, filePath)
+	if err := os.Remove(absolutePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.With(zap.Error(err)).Error("failed to delete file")
+		return status.Error(codes.Internal, "failed to delete file")
+	}
+	return nil
+}
+func (l LocalClient) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, l.logger)
+
+	fileInfo, err := os.Stat(l.downloadDirectory)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to stat download directory")
+		return status.Error(codes.Unavailable, "failed to stat download directory")
+	}
+	if !fileInfo.IsDir() {
+		logger.Warn("download directory is not a directory")
+		return status.Error(codes.Unavailable, "download directory is not a directory")
+	}
+	return nil
 }
 func (l *LocalClient) Write(ctx context.Context, filePath string) (io.WriteCloser, error) {
 	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("file_path", filePath))
@@ -150,16 +219,73 @@ func NewS3Client(downloadConfig configs.Download, logger *zap.Logger) (Client, e
 		logger:      logger,
 	}, nil
 }
-func (s S3Client) Read(ctx context.Context, filePath string) (io.ReadCloser, error) {
-	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.String("file_path", filePath))
+func (s S3Client) Read(ctx context.Context, filePath string, offset, length int64) (io.ReadCloser, error) {
+	logger := utils.LoggerWithContext(ctx, s.logger).
+		With(zap.String("file_path", filePath)).
+		With(zap.Int64("offset", offset)).
+		With(zap.Int64("length", length))
 
-	object, err := s.minioClient.GetObjectWithContext(ctx, s.bucket, filePath, minio.GetObjectOptions{})
+	getObjectOptions := minio.GetObjectOptions{}
+	var err error
+	switch {
+	case length > 0:
+		err = getObjectOptions.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = getObjectOptions.SetRange(offset, 0)
+	}
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to set s3 object range")
+		return nil, status.Error(codes.InvalidArgument, "invalid file range")
+	}
+	object, err := s.minioClient.GetObjectWithContext(ctx, s.bucket, filePath, getObjectOptions)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to get s3 object")
 		return nil, status.Error(codes.Internal, "failed to get s3 object")
 	}
 	return object, nil
 }
+func (s S3Client) GetPresignedURL(ctx context.Context, filePath string, options PresignedURLOptions) (string, error) {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.String("file_path", filePath))
+
+	requestParams := make(url.Values)
+	if options.ContentType != "" {
+		requestParams.Set("response-content-type", options.ContentType)
+	}
+	if options.ContentDisposition != "" {
+		requestParams.Set("response-content-disposition", options.ContentDisposition)
+	}
+	presignedURL, err := s.minioClient.PresignedGetObject(s.bucket, filePath, options.ExpiresIn, requestParams)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to presign s3 object url")
+		return "", status.Error(codes.Internal, "failed to presign s3 object url")
+	}
+	return presignedURL.String(), nil
+}
+
+func (s S3Client) Delete(ctx context.Context, filePath string) error {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.String("file_path", filePath))
+
+	if err := s.minioClient.RemoveObject(s.bucket, filePath); err != nil {
+		logger.With(zap.Error(err)).Error("failed to remove s3 object")
+		return status.Error(codes.Internal, "failed to remove s3 object")
+	}
+	return nil
+}
+
+func (s S3Client) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, s.logger).With(zap.String("bucket", s.bucket))
+
+	exists, err := s.minioClient.BucketExists(s.bucket)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to check if s3 bucket exists")
+		return status.Error(codes.Unavailable, "failed to check if s3 bucket exists")
+	}
+	if !exists {
+		logger.Warn("s3 bucket does not exist")
+		return status.Error(codes.Unavailable, "s3 bucket does not exist")
+	}
+	return nil
+}
 
 func (s S3Client) Write(ctx context.Context, filePath string) (io.WriteCloser, error) {
 	return newS3ClientReadWriteCloser(ctx, s.minioClient, s.logger, s.bucket, filePath), nil
@@ -0,0 +1,495 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Encrypted files are laid out as a header followed by a sequence of AES-GCM sealed chunks:
+//
+//	magic (4) | header length (4) | chunk size (4) | key id length (1) | key id | wrapped data key length (2) |
+//	wrapped data key | nonce prefix (8)
+//
+// Every chunk except the last holds exactly chunk size bytes of plaintext. The nonce of a chunk is the nonce prefix
+// followed by the chunk index, and the last chunk is sealed with a different additional data so that truncating the
+// file on a chunk boundary is detected.
+//
+// Files written before encryption was enabled do not start with the magic, and are read back as plaintext.
+const (
+	encryptedFileMagic           = "GLE1"
+	encryptedFilePrefixLength    = 8
+	encryptionDataKeyLength      = 32
+	encryptionNoncePrefixLength  = 8
+	defaultEncryptionChunkSize   = 64 * 1024
+	encryptionChunkTypeRegular   = 0
+	encryptionChunkTypeFinal     = 1
+	minEncryptedFileHeaderLength = encryptedFilePrefixLength + 4 + 1 + 1 + 2 + encryptionNoncePrefixLength
+	maxEncryptedFileHeaderLength = encryptedFilePrefixLength + 4 + 1 + math.MaxUint8 + 2 + math.MaxUint16 +
+		encryptionNoncePrefixLength
+)
+
+var (
+	errInvalidEncryptedFileHeader = errors.New("invalid encrypted file header")
+	errPlaintextFile              = errors.New("file is not encrypted")
+	errTruncatedEncryptedFile     = errors.New("encrypted file is truncated")
+	errCorruptedEncryptedChunk    = errors.New("failed to authenticate encrypted chunk")
+)
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newRandomBytes(length int) ([]byte, error) {
+	randomBytes := make([]byte, length)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+	return randomBytes, nil
+}
+
+type encryptionKeyring struct {
+	activeKeyID string
+	masterKeys  map[string]cipher.AEAD
+}
+
+func newEncryptionKeyring(encryptionConfig configs.Encryption) (*encryptionKeyring, error) {
+	keyringConfig, err := encryptionConfig.GetKeyring()
+	if err != nil {
+		return nil, err
+	}
+	masterKeys := make(map[string]cipher.AEAD)
+	for _, masterKey := range keyringConfig.MasterKeys {
+		if masterKey.ID == "" || len(masterKey.ID) > math.MaxUint8 {
+			return nil, fmt.Errorf("invalid length of master key id %q", masterKey.ID)
+		}
+		keyBytes, decodeErr := base64.StdEncoding.DecodeString(masterKey.Key)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to decode master key %s: %w", masterKey.ID, decodeErr)
+		}
+		if len(keyBytes) != encryptionDataKeyLength {
+			return nil, fmt.Errorf("master key %s must be %d bytes long", masterKey.ID, encryptionDataKeyLength)
+		}
+		masterKeys[masterKey.ID], err = newAESGCM(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize master key %s: %w", masterKey.ID, err)
+		}
+	}
+	if _, ok := masterKeys[keyringConfig.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not one of the master keys", keyringConfig.ActiveKeyID)
+	}
+	return &encryptionKeyring{
+		activeKeyID: keyringConfig.ActiveKeyID,
+		masterKeys:  masterKeys,
+	}, nil
+}
+func (k encryptionKeyring) wrapDataKey(dataKey []byte) (string, []byte, error) {
+	masterKey := k.masterKeys[k.activeKeyID]
+	nonce, err := newRandomBytes(masterKey.NonceSize())
+	if err != nil {
+		return "", nil, err
+	}
+	return k.activeKeyID, masterKey.Seal(nonce, nonce, dataKey, []byte(k.activeKeyID)), nil
+}
+func (k encryptionKeyring) unwrapDataKey(keyID string, wrappedDataKey []byte) ([]byte, error) {
+	masterKey, ok := k.masterKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	if len(wrappedDataKey) < masterKey.NonceSize() {
+		return nil, errInvalidEncryptedFileHeader
+	}
+	nonce, sealedDataKey := wrappedDataKey[:masterKey.NonceSize()], wrappedDataKey[masterKey.NonceSize():]
+	return masterKey.Open(nil, nonce, sealedDataKey, []byte(keyID))
+}
+
+type encryptedFileHeader struct {
+	chunkSize      uint32
+	keyID          string
+	wrappedDataKey []byte
+	noncePrefix    []byte
+}
+
+func (h encryptedFileHeader) marshal() []byte {
+	headerLength := encryptedFilePrefixLength + 4 + 1 + len(h.keyID) + 2 + len(h.wrappedDataKey) + len(h.noncePrefix)
+	header := make([]byte, 0, headerLength)
+	header = append(header, encryptedFileMagic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(headerLength))
+	header = binary.BigEndian.AppendUint32(header, h.chunkSize)
+	header = append(header, uint8(len(h.keyID)))
+	header = append(header, h.keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(h.wrappedDataKey)))
+	header = append(header, h.wrappedDataKey...)
+	header = append(header, h.noncePrefix...)
+	return header
+}
+
+func parseEncryptedFilePrefix(prefix []byte) (uint32, error) {
+	if len(prefix) != encryptedFilePrefixLength || string(prefix[:len(encryptedFileMagic)]) != encryptedFileMagic {
+		return 0, errPlaintextFile
+	}
+	headerLength := binary.BigEndian.Uint32(prefix[len(encryptedFileMagic):])
+	if headerLength < minEncryptedFileHeaderLength || headerLength > maxEncryptedFileHeaderLength {
+		return 0, errInvalidEncryptedFileHeader
+	}
+	return headerLength, nil
+}
+
+func parseEncryptedFileHeader(header []byte) (encryptedFileHeader, error) {
+	if len(header) < minEncryptedFileHeaderLength {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	reader := bytes.NewReader(header[encryptedFilePrefixLength:])
+	result := encryptedFileHeader{}
+	if err := binary.Read(reader, binary.BigEndian, &result.chunkSize); err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	keyIDLength, err := reader.ReadByte()
+	if err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	keyID := make([]byte, keyIDLength)
+	if _, err = io.ReadFull(reader, keyID); err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	result.keyID = string(keyID)
+	var wrappedDataKeyLength uint16
+	if err = binary.Read(reader, binary.BigEndian, &wrappedDataKeyLength); err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	result.wrappedDataKey = make([]byte, wrappedDataKeyLength)
+	if _, err = io.ReadFull(reader, result.wrappedDataKey); err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	result.noncePrefix = make([]byte, encryptionNoncePrefixLength)
+	if _, err = io.ReadFull(reader, result.noncePrefix); err != nil {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	if result.chunkSize == 0 || reader.Len() != 0 {
+		return encryptedFileHeader{}, errInvalidEncryptedFileHeader
+	}
+	return result, nil
+}
+
+func getEncryptionChunkNonce(noncePrefix []byte, chunkIndex uint32) []byte {
+	nonce := make([]byte, 0, len(noncePrefix)+4)
+	nonce = append(nonce, noncePrefix...)
+	return binary.BigEndian.AppendUint32(nonce, chunkIndex)
+}
+
+func getEncryptionChunkAdditionalData(isFinalChunk bool) []byte {
+	if isFinalChunk {
+		return []byte{encryptionChunkTypeFinal}
+	}
+	return []byte{encryptionChunkTypeRegular}
+}
+
+type encryptedWriteCloser struct {
+	baseWriteCloser io.WriteCloser
+	aead            cipher.AEAD
+	noncePrefix     []byte
+	chunkSize       int
+	chunkIndex      uint32
+	buffer          []byte
+}
+
+func (e *encryptedWriteCloser) sealAndWriteChunk(isFinalChunk bool) error {
+	sealedChunk := e.aead.Seal(
+		nil,
+		getEncryptionChunkNonce(e.noncePrefix, e.chunkIndex),
+		e.buffer,
+		getEncryptionChunkAdditionalData(isFinalChunk),
+	)
+	if _, err := e.baseWriteCloser.Write(sealedChunk); err != nil {
+		return err
+	}
+	e.chunkIndex++
+	e.buffer = e.buffer[:0]
+	return nil
+}
+func (e *encryptedWriteCloser) Write(p []byte) (int, error) {
+	writtenLength := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, as until then it might turn out to be the final one.
+		if len(e.buffer) == e.chunkSize {
+			if err := e.sealAndWriteChunk(false); err != nil {
+				return 0, err
+			}
+		}
+		copiedLength := min(e.chunkSize-len(e.buffer), len(p))
+		e.buffer = append(e.buffer, p[:copiedLength]...)
+		p = p[copiedLength:]
+	}
+	return writtenLength, nil
+}
+func (e *encryptedWriteCloser) Close() error {
+	sealErr := e.sealAndWriteChunk(true)
+	closeErr := e.baseWriteCloser.Close()
+	return errors.Join(sealErr, closeErr)
+}
+
+type encryptedReadCloser struct {
+	baseReadCloser  io.ReadCloser
+	baseReader      *bufio.Reader
+	aead            cipher.AEAD
+	noncePrefix     []byte
+	chunkSize       int
+	chunkIndex      uint32
+	skipLength      int
+	remainingLength int64
+	plaintext       []byte
+	isFinalChunk    bool
+}
+
+func (e *encryptedReadCloser) readChunk() error {
+	sealedChunk := make([]byte, e.chunkSize+e.aead.Overhead())
+	sealedChunkLength, err := io.ReadFull(e.baseReader, sealedChunk)
+	switch {
+	case err == nil:
+		if _, peekErr := e.baseReader.Peek(1); peekErr != nil {
+			if !errors.Is(peekErr, io.EOF) {
+				return peekErr
+			}
+			e.isFinalChunk = true
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		e.isFinalChunk = true
+	case errors.Is(err, io.EOF):
+		return errTruncatedEncryptedFile
+	default:
+		return err
+	}
+	plaintext, err := e.aead.Open(
+		sealedChunk[:0],
+		getEncryptionChunkNonce(e.noncePrefix, e.chunkIndex),
+		sealedChunk[:sealedChunkLength],
+		getEncryptionChunkAdditionalData(e.isFinalChunk),
+	)
+	if err != nil {
+		return errCorruptedEncryptedChunk
+	}
+	e.chunkIndex++
+	e.plaintext = plaintext[min(e.skipLength, len(plaintext)):]
+	e.skipLength = 0
+	return nil
+}
+func (e *encryptedReadCloser) Read(p []byte) (int, error) {
+	if e.remainingLength == 0 {
+		return 0, io.EOF
+	}
+	for len(e.plaintext) == 0 {
+		if e.isFinalChunk {
+			return 0, io.EOF
+		}
+		if err := e.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if e.remainingLength >= 0 && int64(len(p)) > e.remainingLength {
+		p = p[:e.remainingLength]
+	}
+	readLength := copy(p, e.plaintext)
+	e.plaintext = e.plaintext[readLength:]
+	if e.remainingLength >= 0 {
+		e.remainingLength -= int64(readLength)
+	}
+	return readLength, nil
+}
+func (e *encryptedReadCloser) Close() error {
+	return e.baseReadCloser.Close()
+}
+
+type EncryptedClient struct {
+	baseClient Client
+	keyring    *encryptionKeyring
+	chunkSize  uint32
+	logger     *zap.Logger
+}
+
+func NewEncryptedClient(baseClient Client, encryptionConfig configs.Encryption, logger *zap.Logger) (Client, error) {
+	keyring, err := newEncryptionKeyring(encryptionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keyring: %w", err)
+	}
+	chunkSize := uint64(defaultEncryptionChunkSize)
+	if encryptionConfig.ChunkSize != "" {
+		chunkSize, err = encryptionConfig.GetChunkSizeInBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse encryption chunk size: %w", err)
+		}
+	}
+	if chunkSize == 0 || chunkSize > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid encryption chunk size: %d", chunkSize)
+	}
+	return &EncryptedClient{
+		baseClient: baseClient,
+		keyring:    keyring,
+		chunkSize:  uint32(chunkSize),
+		logger:     logger,
+	}, nil
+}
+func (e EncryptedClient) readFull(ctx context.Context, filePath string, length int64) ([]byte, error) {
+	reader, err := e.baseClient.Read(ctx, filePath, 0, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data := make([]byte, length)
+	if _, err = io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readHeader returns errPlaintextFile for files written before encryption was enabled.
+func (e EncryptedClient) readHeader(ctx context.Context, filePath string) (encryptedFileHeader, uint32, error) {
+	prefix, err := e.readFull(ctx, filePath, encryptedFilePrefixLength)
+	if err != nil {
+		// Plaintext files can be shorter than the prefix.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return encryptedFileHeader{}, 0, errPlaintextFile
+		}
+		return encryptedFileHeader{}, 0, err
+	}
+	headerLength, err := parseEncryptedFilePrefix(prefix)
+	if err != nil {
+		return encryptedFileHeader{}, 0, err
+	}
+	header, err := e.readFull(ctx, filePath, int64(headerLength))
+	if err != nil {
+		return encryptedFileHeader{}, 0, err
+	}
+	parsedHeader, err := parseEncryptedFileHeader(header)
+	if err != nil {
+		return encryptedFileHeader{}, 0, err
+	}
+	return parsedHeader, headerLength, nil
+}
+func (e EncryptedClient) Read(ctx context.Context, filePath string, offset, length int64) (io.ReadCloser, error) {
+	logger := utils.LoggerWithContext(ctx, e.logger).
+		With(zap.String("file_path", filePath)).
+		With(zap.Int64("offset", offset)).
+		With(zap.Int64("length", length))
+
+	header, headerLength, err := e.readHeader(ctx, filePath)
+	if errors.Is(err, errPlaintextFile) {
+		logger.Debug("file is not encrypted, reading it as plaintext")
+		return e.baseClient.Read(ctx, filePath, offset, length)
+	}
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to read encrypted file header")
+		return nil, status.Error(codes.Internal, "failed to read encrypted file header")
+	}
+	dataKey, err := e.keyring.unwrapDataKey(header.keyID, header.wrappedDataKey)
+	if err != nil {
+		logger.With(zap.Error(err)).With(zap.String("key_id", header.keyID)).Error("failed to unwrap data key")
+		return nil, status.Error(codes.Internal, "failed to unwrap data key")
+	}
+	aead, err := newAESGCM(dataKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to initialize data key cipher")
+		return nil, status.Error(codes.Internal, "failed to initialize data key cipher")
+	}
+	chunkIndex, skipLength := offset/int64(header.chunkSize), offset%int64(header.chunkSize)
+	if chunkIndex > 0 && skipLength == 0 {
+		// Start from the previous chunk so that an offset at the very end of the file still reads a final chunk,
+		// without which truncation could not be detected.
+		chunkIndex, skipLength = chunkIndex-1, int64(header.chunkSize)
+	}
+	sealedChunkSize := int64(header.chunkSize) + int64(aead.Overhead())
+	baseReadCloser, err := e.baseClient.Read(ctx, filePath, int64(headerLength)+chunkIndex*sealedChunkSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	remainingLength := int64(-1)
+	if length > 0 {
+		remainingLength = length
+	}
+	return &encryptedReadCloser{
+		baseReadCloser:  baseReadCloser,
+		baseReader:      bufio.NewReaderSize(baseReadCloser, int(sealedChunkSize)),
+		aead:            aead,
+		noncePrefix:     header.noncePrefix,
+		chunkSize:       int(header.chunkSize),
+		chunkIndex:      uint32(chunkIndex),
+		skipLength:      int(skipLength),
+		remainingLength: remainingLength,
+	}, nil
+}
+func (e EncryptedClient) Write(ctx context.Context, filePath string) (io.WriteCloser, error) {
+	logger := utils.LoggerWithContext(ctx, e.logger).With(zap.String("file_path", filePath))
+
+	dataKey, err := newRandomBytes(encryptionDataKeyLength)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate data key")
+		return nil, status.Error(codes.Internal, "failed to generate data key")
+	}
+	noncePrefix, err := newRandomBytes(encryptionNoncePrefixLength)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate nonce prefix")
+		return nil, status.Error(codes.Internal, "failed to generate nonce prefix")
+	}
+	keyID, wrappedDataKey, err := e.keyring.wrapDataKey(dataKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to wrap data key")
+		return nil, status.Error(codes.Internal, "failed to wrap data key")
+	}
+	aead, err := newAESGCM(dataKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to initialize data key cipher")
+		return nil, status.Error(codes.Internal, "failed to initialize data key cipher")
+	}
+	baseWriteCloser, err := e.baseClient.Write(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	header := encryptedFileHeader{
+		chunkSize:      e.chunkSize,
+		keyID:          keyID,
+		wrappedDataKey: wrappedDataKey,
+		noncePrefix:    noncePrefix,
+	}
+	if _, err = baseWriteCloser.Write(header.marshal()); err != nil {
+		baseWriteCloser.Close()
+		logger.With(zap.Error(err)).Error("failed to write encrypted file header")
+		return nil, status.Error(codes.Internal, "failed to write encrypted file header")
+	}
+	return &encryptedWriteCloser{
+		baseWriteCloser: baseWriteCloser,
+		aead:            aead,
+		noncePrefix:     noncePrefix,
+		chunkSize:       int(e.chunkSize),
+		buffer:          make([]byte, 0, e.chunkSize),
+	}, nil
+}
+
+// GetPresignedURL is not supported since the storage backend would serve the ciphertext.
+func (e EncryptedClient) GetPresignedURL(context.Context, string, PresignedURLOptions) (string, error) {
+	return "", ErrPresignedURLNotSupported
+}
+func (e EncryptedClient) Delete(ctx context.Context, filePath string) error {
+	return e.baseClient.Delete(ctx, filePath)
+}
+func (e EncryptedClient) Ping(ctx context.Context) error {
+	return e.baseClient.Ping(ctx)
+}
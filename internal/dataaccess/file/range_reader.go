@@ -0,0 +1,90 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	errInvalidSeekWhence  = errors.New("invalid seek whence")
+	errNegativeSeekOffset = errors.New("negative seek offset")
+	errUnknownSectionSize = errors.New("cannot seek relative to the end of a section of unknown size")
+)
+
+// rangeReadSeekCloser exposes a section of a file as an io.ReadSeekCloser. Seeking is free: the underlying ranged
+// read is only issued on the first Read after the position changes.
+type rangeReadSeekCloser struct {
+	ctx      context.Context
+	client   Client
+	filePath string
+	offset   int64
+	length   int64
+	position int64
+	reader   io.ReadCloser
+}
+
+// NewRangeReadSeekCloser returns a reader over length bytes of the file starting at offset. A negative length means
+// the section size is unknown, in which case reads continue until the end of the file and io.SeekEnd is unsupported.
+func NewRangeReadSeekCloser(ctx context.Context, client Client, filePath string, offset, length int64) io.ReadSeekCloser {
+	return &rangeReadSeekCloser{
+		ctx:      ctx,
+		client:   client,
+		filePath: filePath,
+		offset:   offset,
+		length:   length,
+	}
+}
+func (r *rangeReadSeekCloser) Read(p []byte) (int, error) {
+	if r.length >= 0 && r.position >= r.length {
+		return 0, io.EOF
+	}
+	if r.reader == nil {
+		remainingLength := int64(0)
+		if r.length >= 0 {
+			remainingLength = r.length - r.position
+		}
+		reader, err := r.client.Read(r.ctx, r.filePath, r.offset+r.position, remainingLength)
+		if err != nil {
+			return 0, err
+		}
+		r.reader = reader
+	}
+	readByteCount, err := r.reader.Read(p)
+	r.position += int64(readByteCount)
+	return readByteCount, err
+}
+func (r *rangeReadSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	var newPosition int64
+	switch whence {
+	case io.SeekStart:
+		newPosition = offset
+	case io.SeekCurrent:
+		newPosition = r.position + offset
+	case io.SeekEnd:
+		if r.length < 0 {
+			return 0, errUnknownSectionSize
+		}
+		newPosition = r.length + offset
+	default:
+		return 0, errInvalidSeekWhence
+	}
+	if newPosition < 0 {
+		return 0, errNegativeSeekOffset
+	}
+	if newPosition != r.position {
+		if err := r.Close(); err != nil {
+			return 0, err
+		}
+		r.position = newPosition
+	}
+	return newPosition, nil
+}
+func (r *rangeReadSeekCloser) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
@@ -3,104 +3,176 @@ package consumer
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/signal"
+	"time"
 
 	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/inmemory"
+	"GoLoad/internal/dataaccess/mq/producer"
 	"GoLoad/internal/utils"
 
-	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
+const consumeRetryDelay = 5 * time.Second
+
 type HandlerFunc func(ctx context.Context, queueName string, payload []byte) error
 
-type consumerHandler struct {
-	handlerFunc       HandlerFunc
-	exitSignalChannel chan os.Signal
+var deadLetteredMessageCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "goload",
+	Subsystem: "consumer",
+	Name:      "dead_lettered_messages_total",
+	Help:      "Number of messages moved to a dead letter queue after failing to be handled too many times.",
+}, []string{"queue_name"})
+
+type retryPolicy struct {
+	maxAttempts uint64
+	baseDelay   time.Duration
+	maxDelay    time.Duration
 }
 
-func newConsumerHandler(handlerFunc HandlerFunc, exitSignalChannel chan os.Signal) *consumerHandler {
-	return &consumerHandler{
-		handlerFunc:       handlerFunc,
-		exitSignalChannel: exitSignalChannel,
+func newRetryPolicy(consumerRetry configs.ConsumerRetry) (retryPolicy, error) {
+	baseDelay, err := consumerRetry.GetBaseDelayDuration()
+	if err != nil {
+		return retryPolicy{}, err
 	}
+	maxDelay, err := consumerRetry.GetMaxDelayDuration()
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	return retryPolicy{
+		maxAttempts: max(consumerRetry.MaxAttempts, 1),
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+	}, nil
 }
-func (h consumerHandler) Setup(sarama.ConsumerGroupSession) error {
-	return nil
-}
-func (h consumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
-}
-func (h consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for {
-		select {
-		case message, ok := <-claim.Messages():
-			if !ok {
-				session.Commit()
-				return nil
-			}
-			if err := h.handlerFunc(session.Context(), message.Topic, message.Value); err != nil {
-				return err
-			}
-		case <-h.exitSignalChannel:
-			session.Commit()
-			break
-		}
+func (r retryPolicy) getDelay(attemptCount uint64) time.Duration {
+	delay := r.baseDelay
+	for i := uint64(1); i < attemptCount && delay < r.maxDelay; i++ {
+		delay *= 2
 	}
+	return min(delay, r.maxDelay)
 }
 
-type Consumer interface {
-	RegisterHandler(queueName string, handlerFunc HandlerFunc)
-	Start(ctx context.Context) error
+// message is a message received from any message queue. Partition and Offset are only meaningful to message queues
+// that have them, and are only used to locate dead-lettered messages.
+type message struct {
+	QueueName string
+	Partition int32
+	Offset    int64
+	Payload   []byte
 }
 
-type consumer struct {
-	saramaConsumer            sarama.ConsumerGroup
+// messageHandler runs the registered handlers, whatever the message queue the messages come from.
+type messageHandler struct {
 	queueNameToHandlerFuncMap map[string]HandlerFunc
+	queueNameToRetryPolicyMap map[string]retryPolicy
+	mqClient                  producer.Client
 	logger                    *zap.Logger
 }
 
-func newSaramaConfig(mqConfig configs.MQ) *sarama.Config {
-	saramaConfig := sarama.NewConfig()
-	saramaConfig.ClientID = mqConfig.ClientID
-	saramaConfig.Metadata.Full = true
-	return saramaConfig
-}
-func NewConsumer(mqConfig configs.MQ, logger *zap.Logger) (Consumer, error) {
-	saramaConsumer, err := sarama.NewConsumerGroup(mqConfig.Addresses, mqConfig.ClientID, newSaramaConfig(mqConfig))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create sarama consumer: %w", err)
+func newMessageHandler(
+	queueNameToHandlerFuncMap map[string]HandlerFunc,
+	mqConfig configs.MQ,
+	mqClient producer.Client,
+	logger *zap.Logger,
+) (messageHandler, error) {
+	queueNameToRetryPolicyMap := make(map[string]retryPolicy, len(queueNameToHandlerFuncMap))
+	for queueName := range queueNameToHandlerFuncMap {
+		queueRetryPolicy, err := newRetryPolicy(mqConfig.Consumer.GetRetryOfQueue(queueName))
+		if err != nil {
+			logger.With(zap.String("queue_name", queueName)).With(zap.Error(err)).Error("failed to parse consumer retry config")
+			return messageHandler{}, err
+		}
+		queueNameToRetryPolicyMap[queueName] = queueRetryPolicy
 	}
-	return &consumer{
-		saramaConsumer:            saramaConsumer,
-		queueNameToHandlerFuncMap: make(map[string]HandlerFunc),
+	return messageHandler{
+		queueNameToHandlerFuncMap: queueNameToHandlerFuncMap,
+		queueNameToRetryPolicyMap: queueNameToRetryPolicyMap,
+		mqClient:                  mqClient,
 		logger:                    logger,
 	}, nil
 }
-func (c *consumer) RegisterHandler(queueName string, handlerFunc HandlerFunc) {
-	c.queueNameToHandlerFuncMap[queueName] = handlerFunc
+func (h messageHandler) getQueueNameList() []string {
+	queueNameList := make([]string, 0, len(h.queueNameToHandlerFuncMap))
+	for queueName := range h.queueNameToHandlerFuncMap {
+		queueNameList = append(queueNameList, queueName)
+	}
+	return queueNameList
 }
 
-func (c consumer) Start(ctx context.Context) error {
-	logger := utils.LoggerWithContext(ctx, c.logger)
+// handle retries the handler with backoff, then moves the message to the dead letter queue of its queue, so that a
+// message that can never be handled does not block the ones after it. Handlers are not cancelled when sessionCtx is
+// done, only the waits between attempts are. It returns an error if the message could not be dead-lettered or
+// sessionCtx is done, in which case the message must be delivered again later.
+func (h messageHandler) handle(sessionCtx context.Context, message message) error {
+	logger := utils.LoggerWithContext(sessionCtx, h.logger).
+		With(zap.String("queue_name", message.QueueName)).
+		With(zap.Int32("partition", message.Partition)).
+		With(zap.Int64("offset", message.Offset))
 
-	exitSignalChannel := make(chan os.Signal, 1)
-	signal.Notify(exitSignalChannel, os.Interrupt)
-	for queueName, handlerFunc := range c.queueNameToHandlerFuncMap {
-		go func(queueName string, handlerFunc HandlerFunc) {
-			if err := c.saramaConsumer.Consume(
-				context.Background(),
-				[]string{queueName},
-				newConsumerHandler(handlerFunc, exitSignalChannel),
-			); err != nil {
-				logger.
-					With(zap.String("queue_name", queueName)).
-					With(zap.Error(err)).
-					Error("failed to consume message from queue")
-			}
-		}(queueName, handlerFunc)
+	ctx := context.WithoutCancel(sessionCtx)
+	handlerFunc := h.queueNameToHandlerFuncMap[message.QueueName]
+	retryPolicy := h.queueNameToRetryPolicyMap[message.QueueName]
+	var (
+		attemptCount uint64
+		handlerErr   error
+	)
+	for attemptCount = 1; ; attemptCount++ {
+		handlerErr = handlerFunc(ctx, message.QueueName, message.Payload)
+		if handlerErr == nil {
+			return nil
+		}
+		if attemptCount >= retryPolicy.maxAttempts {
+			break
+		}
+		retryDelay := retryPolicy.getDelay(attemptCount)
+		logger.
+			With(zap.Uint64("attempt_count", attemptCount)).
+			With(zap.Duration("retry_delay", retryDelay)).
+			With(zap.Error(handlerErr)).
+			Warn("failed to handle message, will retry")
+		select {
+		case <-time.After(retryDelay):
+		case <-sessionCtx.Done():
+			return sessionCtx.Err()
+		}
+	}
+	logger.
+		With(zap.Uint64("attempt_count", attemptCount)).
+		With(zap.Error(handlerErr)).
+		Error("failed to handle message too many times, moving it to the dead letter queue")
+	if err := h.mqClient.ProduceWithHeaders(
+		ctx,
+		DeadLetterQueueName(message.QueueName),
+		message.Payload,
+		newDeadLetterHeaders(message, attemptCount, handlerErr),
+	); err != nil {
+		return err
 	}
-	<-exitSignalChannel
+	deadLetteredMessageCounter.WithLabelValues(message.QueueName).Inc()
 	return nil
 }
+
+type Consumer interface {
+	RegisterHandler(queueName string, handlerFunc HandlerFunc)
+	// Start consumes the queues handlers were registered for until ctx is cancelled, then waits for the messages being
+	// handled, acknowledges them and closes the consumer. A Consumer can only be started once.
+	Start(ctx context.Context) error
+}
+
+func NewConsumer(mqConfig configs.MQ, mqClient producer.Client, inMemoryBroker inmemory.Broker, logger *zap.Logger) (Consumer, error) {
+	switch mqConfig.GetType() {
+	case configs.MQTypeKafka:
+		return NewKafkaConsumer(mqConfig, mqClient, logger)
+	case configs.MQTypeInMemory:
+		return NewInMemoryConsumer(mqConfig, mqClient, inMemoryBroker, logger), nil
+	case configs.MQTypeNATS:
+		return NewNATSConsumer(mqConfig, mqClient, logger)
+	case configs.MQTypeRabbitMQ:
+		return NewRabbitMQConsumer(mqConfig, mqClient, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported mq type: %s", mqConfig.Type)
+	}
+}
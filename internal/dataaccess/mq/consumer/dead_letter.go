@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/inmemory"
+
+	"go.uber.org/zap"
+)
+
+const (
+	deadLetterQueueNameSuffix = ".dead_letter"
+
+	deadLetterHeaderQueueName    = "goload-queue-name"
+	deadLetterHeaderPartition    = "goload-partition"
+	deadLetterHeaderOffset       = "goload-offset"
+	deadLetterHeaderError        = "goload-error"
+	deadLetterHeaderAttemptCount = "goload-attempt-count"
+	deadLetterHeaderFailedTime   = "goload-failed-time"
+)
+
+// DeadLetterQueueName returns the queue messages of queueName are moved to once they failed to be handled too many
+// times.
+func DeadLetterQueueName(queueName string) string {
+	return queueName + deadLetterQueueNameSuffix
+}
+
+// DeadLetterMessage is a message of a dead letter queue. Partition and Offset locate it in the dead letter queue,
+// QueueName, OriginalPartition and OriginalOffset in the queue it failed to be handled from.
+type DeadLetterMessage struct {
+	Partition         int32
+	Offset            int64
+	QueueName         string
+	OriginalPartition int32
+	OriginalOffset    int64
+	Payload           []byte
+	Error             string
+	AttemptCount      uint64
+	FailedTime        time.Time
+}
+
+func newDeadLetterHeaders(message message, attemptCount uint64, handlerErr error) map[string]string {
+	return map[string]string{
+		deadLetterHeaderQueueName:    message.QueueName,
+		deadLetterHeaderPartition:    strconv.FormatInt(int64(message.Partition), 10),
+		deadLetterHeaderOffset:       strconv.FormatInt(message.Offset, 10),
+		deadLetterHeaderError:        handlerErr.Error(),
+		deadLetterHeaderAttemptCount: strconv.FormatUint(attemptCount, 10),
+		deadLetterHeaderFailedTime:   time.Now().Format(time.RFC3339),
+	}
+}
+
+// newDeadLetterMessage ignores malformed headers, so that a message can still be inspected and replayed.
+func newDeadLetterMessage(partition int32, offset int64, payload []byte, headers map[string]string) DeadLetterMessage {
+	originalPartition, _ := strconv.ParseInt(headers[deadLetterHeaderPartition], 10, 32)
+	originalOffset, _ := strconv.ParseInt(headers[deadLetterHeaderOffset], 10, 64)
+	attemptCount, _ := strconv.ParseUint(headers[deadLetterHeaderAttemptCount], 10, 64)
+	failedTime, _ := time.Parse(time.RFC3339, headers[deadLetterHeaderFailedTime])
+	return DeadLetterMessage{
+		Partition:         partition,
+		Offset:            offset,
+		QueueName:         headers[deadLetterHeaderQueueName],
+		OriginalPartition: int32(originalPartition),
+		OriginalOffset:    originalOffset,
+		Payload:           payload,
+		Error:             headers[deadLetterHeaderError],
+		AttemptCount:      attemptCount,
+		FailedTime:        failedTime,
+	}
+}
+
+// DeadLetterReader reads dead letter queues without consuming them, so reading the same queue twice returns the same
+// messages.
+type DeadLetterReader interface {
+	GetDeadLetterMessageList(ctx context.Context, queueName string, limit uint64) ([]DeadLetterMessage, error)
+	GetDeadLetterMessage(ctx context.Context, queueName string, partition int32, offset int64) (DeadLetterMessage, error)
+}
+
+func NewDeadLetterReader(mqConfig configs.MQ, inMemoryBroker inmemory.Broker, logger *zap.Logger) (DeadLetterReader, error) {
+	switch mqConfig.GetType() {
+	case configs.MQTypeKafka:
+		return NewKafkaDeadLetterReader(mqConfig, logger), nil
+	case configs.MQTypeInMemory:
+		return NewInMemoryDeadLetterReader(inMemoryBroker), nil
+	case configs.MQTypeNATS:
+		return NewNATSDeadLetterReader(mqConfig, logger), nil
+	case configs.MQTypeRabbitMQ:
+		return NewRabbitMQDeadLetterReader(mqConfig, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported mq type: %s", mqConfig.Type)
+	}
+}
@@ -0,0 +1,129 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/inmemory"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// inMemoryConsumer handles the messages of each queue one at a time, from the first message the broker still keeps.
+// Consumers are told apart by the client ID of the config.
+type inMemoryConsumer struct {
+	inMemoryBroker            inmemory.Broker
+	queueNameToHandlerFuncMap map[string]HandlerFunc
+	mqClient                  producer.Client
+	mqConfig                  configs.MQ
+	logger                    *zap.Logger
+}
+
+func NewInMemoryConsumer(
+	mqConfig configs.MQ,
+	mqClient producer.Client,
+	inMemoryBroker inmemory.Broker,
+	logger *zap.Logger,
+) Consumer {
+	return &inMemoryConsumer{
+		inMemoryBroker:            inMemoryBroker,
+		queueNameToHandlerFuncMap: make(map[string]HandlerFunc),
+		mqClient:                  mqClient,
+		mqConfig:                  mqConfig,
+		logger:                    logger,
+	}
+}
+func (c *inMemoryConsumer) RegisterHandler(queueName string, handlerFunc HandlerFunc) {
+	c.queueNameToHandlerFuncMap[queueName] = handlerFunc
+}
+func (c inMemoryConsumer) consumeQueue(ctx context.Context, messageHandler messageHandler, queueName string) {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("queue_name", queueName))
+
+	c.inMemoryBroker.Subscribe(queueName, c.mqConfig.ClientID)
+	for offset := int64(0); ; {
+		inMemoryMessage, err := c.inMemoryBroker.Receive(ctx, queueName, offset)
+		if err != nil {
+			return
+		}
+		if err = messageHandler.handle(ctx, message{
+			QueueName: inMemoryMessage.QueueName,
+			Offset:    inMemoryMessage.Offset,
+			Payload:   inMemoryMessage.Payload,
+		}); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.With(zap.Error(err)).Error("failed to consume message, will retry")
+			select {
+			case <-time.After(consumeRetryDelay):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		offset = inMemoryMessage.Offset + 1
+		c.inMemoryBroker.Commit(queueName, c.mqConfig.ClientID, offset)
+	}
+}
+func (c inMemoryConsumer) Start(ctx context.Context) error {
+	messageHandler, err := newMessageHandler(c.queueNameToHandlerFuncMap, c.mqConfig, c.mqClient, c.logger)
+	if err != nil {
+		return err
+	}
+	var waitGroup sync.WaitGroup
+	for _, queueName := range messageHandler.getQueueNameList() {
+		waitGroup.Add(1)
+		go func(queueName string) {
+			defer waitGroup.Done()
+			c.consumeQueue(ctx, messageHandler, queueName)
+		}(queueName)
+	}
+	waitGroup.Wait()
+	return nil
+}
+
+type inMemoryDeadLetterReader struct {
+	inMemoryBroker inmemory.Broker
+}
+
+func NewInMemoryDeadLetterReader(inMemoryBroker inmemory.Broker) DeadLetterReader {
+	return &inMemoryDeadLetterReader{
+		inMemoryBroker: inMemoryBroker,
+	}
+}
+func newDeadLetterMessageFromInMemoryMessage(inMemoryMessage inmemory.Message) DeadLetterMessage {
+	return newDeadLetterMessage(0, inMemoryMessage.Offset, inMemoryMessage.Payload, inMemoryMessage.Headers)
+}
+func (d inMemoryDeadLetterReader) GetDeadLetterMessageList(
+	_ context.Context,
+	queueName string,
+	limit uint64,
+) ([]DeadLetterMessage, error) {
+	inMemoryMessageList := d.inMemoryBroker.GetMessageList(DeadLetterQueueName(queueName), 0, limit)
+	deadLetterMessageList := make([]DeadLetterMessage, 0, len(inMemoryMessageList))
+	for _, inMemoryMessage := range inMemoryMessageList {
+		deadLetterMessageList = append(deadLetterMessageList, newDeadLetterMessageFromInMemoryMessage(inMemoryMessage))
+	}
+	return deadLetterMessageList, nil
+}
+func (d inMemoryDeadLetterReader) GetDeadLetterMessage(
+	_ context.Context,
+	queueName string,
+	partition int32,
+	offset int64,
+) (DeadLetterMessage, error) {
+	if partition != 0 {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	inMemoryMessageList := d.inMemoryBroker.GetMessageList(DeadLetterQueueName(queueName), offset, 1)
+	if len(inMemoryMessageList) == 0 || inMemoryMessageList[0].Offset != offset {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	return newDeadLetterMessageFromInMemoryMessage(inMemoryMessageList[0]), nil
+}
@@ -0,0 +1,266 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newSaramaConfig(mqConfig configs.MQ) *sarama.Config {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = mqConfig.ClientID
+	saramaConfig.Metadata.Full = true
+	return saramaConfig
+}
+func newMessageFromSaramaMessage(saramaMessage *sarama.ConsumerMessage) message {
+	return message{
+		QueueName: saramaMessage.Topic,
+		Partition: saramaMessage.Partition,
+		Offset:    saramaMessage.Offset,
+		Payload:   saramaMessage.Value,
+	}
+}
+func newDeadLetterMessageFromSaramaMessage(saramaMessage *sarama.ConsumerMessage) DeadLetterMessage {
+	headers := make(map[string]string, len(saramaMessage.Headers))
+	for _, header := range saramaMessage.Headers {
+		headers[string(header.Key)] = string(header.Value)
+	}
+	return newDeadLetterMessage(saramaMessage.Partition, saramaMessage.Offset, saramaMessage.Value, headers)
+}
+
+// consumerGroupHandler handles the messages of every partition claimed in a consumer group session. A message is
+// marked only once it was handled or dead-lettered, so offsets committed never skip a message.
+type consumerGroupHandler struct {
+	messageHandler messageHandler
+}
+
+func (h consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup runs once every ConsumeClaim of the session returned, so the offsets of all handled messages are committed
+// before the partitions are handed over to another member.
+func (h consumerGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	session.Commit()
+	return nil
+}
+
+// ConsumeClaim returns once the session ends, after the message being handled if any, because of a rebalance or the
+// cancellation of the context passed to Consume.
+func (h consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case saramaMessage, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := h.messageHandler.handle(session.Context(), newMessageFromSaramaMessage(saramaMessage)); err != nil {
+				if session.Context().Err() != nil {
+					return nil
+				}
+				return err
+			}
+			session.MarkMessage(saramaMessage, "")
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+type kafkaConsumer struct {
+	saramaConsumer            sarama.ConsumerGroup
+	queueNameToHandlerFuncMap map[string]HandlerFunc
+	mqClient                  producer.Client
+	mqConfig                  configs.MQ
+	logger                    *zap.Logger
+}
+
+func NewKafkaConsumer(mqConfig configs.MQ, mqClient producer.Client, logger *zap.Logger) (Consumer, error) {
+	saramaConsumer, err := sarama.NewConsumerGroup(mqConfig.Addresses, mqConfig.ClientID, newSaramaConfig(mqConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sarama consumer: %w", err)
+	}
+	return &kafkaConsumer{
+		saramaConsumer:            saramaConsumer,
+		queueNameToHandlerFuncMap: make(map[string]HandlerFunc),
+		mqClient:                  mqClient,
+		mqConfig:                  mqConfig,
+		logger:                    logger,
+	}, nil
+}
+func (c *kafkaConsumer) RegisterHandler(queueName string, handlerFunc HandlerFunc) {
+	c.queueNameToHandlerFuncMap[queueName] = handlerFunc
+}
+
+func (c kafkaConsumer) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	defer func() {
+		if err := c.saramaConsumer.Close(); err != nil {
+			logger.With(zap.Error(err)).Error("failed to close sarama consumer")
+		}
+	}()
+	messageHandler, err := newMessageHandler(c.queueNameToHandlerFuncMap, c.mqConfig, c.mqClient, c.logger)
+	if err != nil {
+		return err
+	}
+	handler := consumerGroupHandler{messageHandler: messageHandler}
+	// Consume returns at the end of every session, which happens on each rebalance, so it is called again until ctx is
+	// cancelled.
+	for ctx.Err() == nil {
+		if err := c.saramaConsumer.Consume(ctx, messageHandler.getQueueNameList(), handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return err
+			}
+			logger.With(zap.Error(err)).Error("failed to consume messages, will retry")
+			select {
+			case <-time.After(consumeRetryDelay):
+			case <-ctx.Done():
+			}
+		}
+	}
+	return nil
+}
+
+type kafkaDeadLetterReader struct {
+	mqConfig configs.MQ
+	logger   *zap.Logger
+}
+
+func NewKafkaDeadLetterReader(mqConfig configs.MQ, logger *zap.Logger) DeadLetterReader {
+	return &kafkaDeadLetterReader{
+		mqConfig: mqConfig,
+		logger:   logger,
+	}
+}
+
+// readPartition reads the messages of a partition from offset until its end or until limit messages were read.
+func (d kafkaDeadLetterReader) readPartition(
+	ctx context.Context,
+	saramaClient sarama.Client,
+	topic string,
+	partition int32,
+	offset int64,
+	limit uint64,
+) ([]DeadLetterMessage, error) {
+	newestOffset, err := saramaClient.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= newestOffset || limit == 0 {
+		return []DeadLetterMessage{}, nil
+	}
+	saramaConsumer, err := sarama.NewConsumerFromClient(saramaClient)
+	if err != nil {
+		return nil, err
+	}
+	defer saramaConsumer.Close()
+	partitionConsumer, err := saramaConsumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer partitionConsumer.Close()
+	deadLetterMessageList := make([]DeadLetterMessage, 0)
+	for uint64(len(deadLetterMessageList)) < limit {
+		select {
+		case message := <-partitionConsumer.Messages():
+			deadLetterMessageList = append(deadLetterMessageList, newDeadLetterMessageFromSaramaMessage(message))
+			if message.Offset >= newestOffset-1 {
+				return deadLetterMessageList, nil
+			}
+		case consumerErr := <-partitionConsumer.Errors():
+			return nil, consumerErr
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return deadLetterMessageList, nil
+}
+func (d kafkaDeadLetterReader) GetDeadLetterMessageList(
+	ctx context.Context,
+	queueName string,
+	limit uint64,
+) ([]DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.String("queue_name", queueName))
+
+	saramaClient, err := sarama.NewClient(d.mqConfig.Addresses, newSaramaConfig(d.mqConfig))
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create sarama client")
+		return nil, status.Error(codes.Internal, "failed to create sarama client")
+	}
+	defer saramaClient.Close()
+	topic := DeadLetterQueueName(queueName)
+	partitionList, err := saramaClient.Partitions(topic)
+	if err != nil {
+		if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return []DeadLetterMessage{}, nil
+		}
+		logger.With(zap.Error(err)).Error("failed to get partitions of dead letter queue")
+		return nil, status.Error(codes.Internal, "failed to get partitions of dead letter queue")
+	}
+	deadLetterMessageList := make([]DeadLetterMessage, 0)
+	for _, partition := range partitionList {
+		oldestOffset, err := saramaClient.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to get oldest offset of dead letter queue")
+			return nil, status.Error(codes.Internal, "failed to get oldest offset of dead letter queue")
+		}
+		partitionDeadLetterMessageList, err := d.readPartition(
+			ctx, saramaClient, topic, partition, oldestOffset, limit-uint64(len(deadLetterMessageList)))
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to read dead letter queue")
+			return nil, status.Error(codes.Internal, "failed to read dead letter queue")
+		}
+		deadLetterMessageList = append(deadLetterMessageList, partitionDeadLetterMessageList...)
+	}
+	return deadLetterMessageList, nil
+}
+func (d kafkaDeadLetterReader) GetDeadLetterMessage(
+	ctx context.Context,
+	queueName string,
+	partition int32,
+	offset int64,
+) (DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.String("queue_name", queueName)).
+		With(zap.Int32("partition", partition)).
+		With(zap.Int64("offset", offset))
+
+	saramaClient, err := sarama.NewClient(d.mqConfig.Addresses, newSaramaConfig(d.mqConfig))
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to create sarama client")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to create sarama client")
+	}
+	defer saramaClient.Close()
+	topic := DeadLetterQueueName(queueName)
+	oldestOffset, err := saramaClient.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+		}
+		logger.With(zap.Error(err)).Error("failed to get oldest offset of dead letter queue")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to get oldest offset of dead letter queue")
+	}
+	if offset < oldestOffset {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	deadLetterMessageList, err := d.readPartition(ctx, saramaClient, topic, partition, offset, 1)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to read dead letter queue")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to read dead letter queue")
+	}
+	if len(deadLetterMessageList) == 0 || deadLetterMessageList[0].Offset != offset {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	return deadLetterMessageList[0], nil
+}
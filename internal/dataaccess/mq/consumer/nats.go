@@ -0,0 +1,252 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// natsInProgressInterval is shorter than the default JetStream ack wait of 30 seconds, so that messages taking longer
+// to handle are not redelivered to another instance.
+const natsInProgressInterval = 10 * time.Second
+
+// natsConsumer uses one durable JetStream consumer per queue, named after the client ID and shared by all instances.
+// At most one message of a queue is handled at a time, so messages are handled in order.
+type natsConsumer struct {
+	natsConn                  *nats.Conn
+	jetStream                 jetstream.JetStream
+	queueNameToHandlerFuncMap map[string]HandlerFunc
+	mqClient                  producer.Client
+	mqConfig                  configs.MQ
+	logger                    *zap.Logger
+}
+
+func NewNATSConsumer(mqConfig configs.MQ, mqClient producer.Client, logger *zap.Logger) (Consumer, error) {
+	natsConn, jetStream, err := producer.NewNATSJetStream(mqConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &natsConsumer{
+		natsConn:                  natsConn,
+		jetStream:                 jetStream,
+		queueNameToHandlerFuncMap: make(map[string]HandlerFunc),
+		mqClient:                  mqClient,
+		mqConfig:                  mqConfig,
+		logger:                    logger,
+	}, nil
+}
+func (c *natsConsumer) RegisterHandler(queueName string, handlerFunc HandlerFunc) {
+	c.queueNameToHandlerFuncMap[queueName] = handlerFunc
+}
+
+// keepInProgress tells the server the message is still being handled until the returned function is called.
+func (c natsConsumer) keepInProgress(natsMessage jetstream.Msg) func() {
+	doneChannel := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(natsInProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = natsMessage.InProgress()
+			case <-doneChannel:
+				return
+			}
+		}
+	}()
+	return func() { close(doneChannel) }
+}
+func (c natsConsumer) consumeQueue(ctx context.Context, messageHandler messageHandler, queueName string) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).With(zap.String("queue_name", queueName))
+
+	stream, err := producer.CreateOrUpdateNATSStream(ctx, c.jetStream, queueName)
+	if err != nil {
+		return err
+	}
+	jetStreamConsumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       c.mqConfig.ClientID,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxAckPending: 1,
+	})
+	if err != nil {
+		return err
+	}
+	messagesContext, err := jetStreamConsumer.Messages()
+	if err != nil {
+		return err
+	}
+	defer messagesContext.Stop()
+	stopAfterCtxDone := context.AfterFunc(ctx, messagesContext.Stop)
+	defer stopAfterCtxDone()
+	for {
+		natsMessage, err := messagesContext.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+			return err
+		}
+		metadata, err := natsMessage.Metadata()
+		if err != nil {
+			return err
+		}
+		stopInProgress := c.keepInProgress(natsMessage)
+		err = messageHandler.handle(ctx, message{
+			QueueName: queueName,
+			Offset:    int64(metadata.Sequence.Stream),
+			Payload:   natsMessage.Data(),
+		})
+		stopInProgress()
+		if err != nil {
+			_ = natsMessage.Nak()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err = natsMessage.DoubleAck(context.WithoutCancel(ctx)); err != nil {
+			logger.With(zap.Error(err)).Warn("failed to acknowledge message, it may be delivered again")
+		}
+	}
+}
+func (c natsConsumer) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	defer c.natsConn.Close()
+	messageHandler, err := newMessageHandler(c.queueNameToHandlerFuncMap, c.mqConfig, c.mqClient, c.logger)
+	if err != nil {
+		return err
+	}
+	var waitGroup sync.WaitGroup
+	for _, queueName := range messageHandler.getQueueNameList() {
+		waitGroup.Add(1)
+		go func(queueName string) {
+			defer waitGroup.Done()
+			for ctx.Err() == nil {
+				if err := c.consumeQueue(ctx, messageHandler, queueName); err != nil {
+					logger.With(zap.String("queue_name", queueName)).With(zap.Error(err)).
+						Error("failed to consume messages, will retry")
+					select {
+					case <-time.After(consumeRetryDelay):
+					case <-ctx.Done():
+					}
+				}
+			}
+		}(queueName)
+	}
+	waitGroup.Wait()
+	return nil
+}
+
+// natsDeadLetterReader reads dead letter streams by sequence number, which is used as offset in partition 0.
+type natsDeadLetterReader struct {
+	mqConfig configs.MQ
+	logger   *zap.Logger
+}
+
+func NewNATSDeadLetterReader(mqConfig configs.MQ, logger *zap.Logger) DeadLetterReader {
+	return &natsDeadLetterReader{
+		mqConfig: mqConfig,
+		logger:   logger,
+	}
+}
+func newDeadLetterMessageFromNATSMessage(rawStreamMessage *jetstream.RawStreamMsg) DeadLetterMessage {
+	headers := make(map[string]string, len(rawStreamMessage.Header))
+	for key := range rawStreamMessage.Header {
+		headers[key] = rawStreamMessage.Header.Get(key)
+	}
+	return newDeadLetterMessage(0, int64(rawStreamMessage.Sequence), rawStreamMessage.Data, headers)
+}
+func (d natsDeadLetterReader) getStream(ctx context.Context, queueName string) (*nats.Conn, jetstream.Stream, error) {
+	natsConn, jetStream, err := producer.NewNATSJetStream(d.mqConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := jetStream.Stream(ctx, producer.NATSStreamName(DeadLetterQueueName(queueName)))
+	if err != nil {
+		natsConn.Close()
+		return nil, nil, err
+	}
+	return natsConn, stream, nil
+}
+func (d natsDeadLetterReader) GetDeadLetterMessageList(
+	ctx context.Context,
+	queueName string,
+	limit uint64,
+) ([]DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.String("queue_name", queueName))
+
+	natsConn, stream, err := d.getStream(ctx, queueName)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			return []DeadLetterMessage{}, nil
+		}
+		logger.With(zap.Error(err)).Error("failed to get dead letter stream")
+		return nil, status.Error(codes.Internal, "failed to get dead letter stream")
+	}
+	defer natsConn.Close()
+	streamInfo, err := stream.Info(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to get dead letter stream info")
+		return nil, status.Error(codes.Internal, "failed to get dead letter stream info")
+	}
+	deadLetterMessageList := make([]DeadLetterMessage, 0)
+	for sequence := streamInfo.State.FirstSeq; sequence <= streamInfo.State.LastSeq; sequence++ {
+		if uint64(len(deadLetterMessageList)) >= limit {
+			break
+		}
+		rawStreamMessage, err := stream.GetMsg(ctx, sequence)
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgNotFound) {
+				continue
+			}
+			logger.With(zap.Error(err)).Error("failed to read dead letter stream")
+			return nil, status.Error(codes.Internal, "failed to read dead letter stream")
+		}
+		deadLetterMessageList = append(deadLetterMessageList, newDeadLetterMessageFromNATSMessage(rawStreamMessage))
+	}
+	return deadLetterMessageList, nil
+}
+func (d natsDeadLetterReader) GetDeadLetterMessage(
+	ctx context.Context,
+	queueName string,
+	partition int32,
+	offset int64,
+) (DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.String("queue_name", queueName)).
+		With(zap.Int64("offset", offset))
+
+	if partition != 0 || offset <= 0 {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	natsConn, stream, err := d.getStream(ctx, queueName)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+		}
+		logger.With(zap.Error(err)).Error("failed to get dead letter stream")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to get dead letter stream")
+	}
+	defer natsConn.Close()
+	rawStreamMessage, err := stream.GetMsg(ctx, uint64(offset))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+		}
+		logger.With(zap.Error(err)).Error("failed to read dead letter stream")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to read dead letter stream")
+	}
+	return newDeadLetterMessageFromNATSMessage(rawStreamMessage), nil
+}
@@ -0,0 +1,226 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errRabbitMQDeliveryChannelClosed = errors.New("rabbitmq delivery channel closed")
+
+// rabbitMQConsumer consumes each queue on its own connection with a prefetch count of 1, so that at most one message
+// of a queue is handled at a time by each instance. Messages not acknowledged when the connection closes are
+// requeued by the server.
+type rabbitMQConsumer struct {
+	queueNameToHandlerFuncMap map[string]HandlerFunc
+	mqClient                  producer.Client
+	mqConfig                  configs.MQ
+	logger                    *zap.Logger
+}
+
+func NewRabbitMQConsumer(mqConfig configs.MQ, mqClient producer.Client, logger *zap.Logger) Consumer {
+	return &rabbitMQConsumer{
+		queueNameToHandlerFuncMap: make(map[string]HandlerFunc),
+		mqClient:                  mqClient,
+		mqConfig:                  mqConfig,
+		logger:                    logger,
+	}
+}
+func (c *rabbitMQConsumer) RegisterHandler(queueName string, handlerFunc HandlerFunc) {
+	c.queueNameToHandlerFuncMap[queueName] = handlerFunc
+}
+func (c rabbitMQConsumer) consumeQueue(ctx context.Context, messageHandler messageHandler, queueName string) error {
+	url, err := producer.GetRabbitMQURL(c.mqConfig)
+	if err != nil {
+		return err
+	}
+	connection, err := amqp.DialConfig(url, amqp.Config{Properties: amqp.Table{"connection_name": c.mqConfig.ClientID}})
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	channel, err := connection.Channel()
+	if err != nil {
+		return err
+	}
+	if err = channel.Qos(1, 0, false); err != nil {
+		return err
+	}
+	if err = producer.DeclareRabbitMQQueue(channel, queueName); err != nil {
+		return err
+	}
+	deliveryChannel, err := channel.Consume(queueName, c.mqConfig.ClientID, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case delivery, ok := <-deliveryChannel:
+			if !ok {
+				return errRabbitMQDeliveryChannelClosed
+			}
+			if err = messageHandler.handle(ctx, message{
+				QueueName: queueName,
+				Offset:    int64(delivery.DeliveryTag),
+				Payload:   delivery.Body,
+			}); err != nil {
+				_ = delivery.Nack(false, true)
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if err = delivery.Ack(false); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+func (c rabbitMQConsumer) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	messageHandler, err := newMessageHandler(c.queueNameToHandlerFuncMap, c.mqConfig, c.mqClient, c.logger)
+	if err != nil {
+		return err
+	}
+	var waitGroup sync.WaitGroup
+	for _, queueName := range messageHandler.getQueueNameList() {
+		waitGroup.Add(1)
+		go func(queueName string) {
+			defer waitGroup.Done()
+			for ctx.Err() == nil {
+				if err := c.consumeQueue(ctx, messageHandler, queueName); err != nil {
+					logger.With(zap.String("queue_name", queueName)).With(zap.Error(err)).
+						Error("failed to consume messages, will retry")
+					select {
+					case <-time.After(consumeRetryDelay):
+					case <-ctx.Done():
+					}
+				}
+			}
+		}(queueName)
+	}
+	waitGroup.Wait()
+	return nil
+}
+
+// rabbitMQDeadLetterReader reads a dead letter queue by getting its messages without acknowledging them, then
+// requeueing all of them, which puts them back at their position in the queue. Messages are also requeued if the
+// connection closes before that. RabbitMQ messages have no offset, so the position of a message in its dead letter
+// queue is used as offset in partition 0.
+type rabbitMQDeadLetterReader struct {
+	mqConfig configs.MQ
+	logger   *zap.Logger
+}
+
+func NewRabbitMQDeadLetterReader(mqConfig configs.MQ, logger *zap.Logger) DeadLetterReader {
+	return &rabbitMQDeadLetterReader{
+		mqConfig: mqConfig,
+		logger:   logger,
+	}
+}
+func newDeadLetterMessageFromRabbitMQDelivery(offset int64, delivery amqp.Delivery) DeadLetterMessage {
+	headers := make(map[string]string, len(delivery.Headers))
+	for key, value := range delivery.Headers {
+		if stringValue, ok := value.(string); ok {
+			headers[key] = stringValue
+		}
+	}
+	return newDeadLetterMessage(0, offset, delivery.Body, headers)
+}
+
+// getDeadLetterMessageList returns up to limit messages from the start of the dead letter queue of queueName.
+func (d rabbitMQDeadLetterReader) getDeadLetterMessageList(queueName string, limit uint64) ([]DeadLetterMessage, error) {
+	url, err := producer.GetRabbitMQURL(d.mqConfig)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := amqp.DialConfig(url, amqp.Config{Properties: amqp.Table{"connection_name": d.mqConfig.ClientID}})
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	deadLetterQueueName := DeadLetterQueueName(queueName)
+	if _, err = channel.QueueDeclarePassive(deadLetterQueueName, true, false, false, false, nil); err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return []DeadLetterMessage{}, nil
+		}
+		return nil, err
+	}
+	deadLetterMessageList := make([]DeadLetterMessage, 0)
+	var lastDeliveryTag uint64
+	for uint64(len(deadLetterMessageList)) < limit {
+		delivery, ok, getErr := channel.Get(deadLetterQueueName, false)
+		if getErr != nil {
+			return nil, getErr
+		}
+		if !ok {
+			break
+		}
+		lastDeliveryTag = delivery.DeliveryTag
+		deadLetterMessageList = append(
+			deadLetterMessageList,
+			newDeadLetterMessageFromRabbitMQDelivery(int64(len(deadLetterMessageList)), delivery),
+		)
+	}
+	if lastDeliveryTag != 0 {
+		if err = channel.Nack(lastDeliveryTag, true, true); err != nil {
+			return nil, err
+		}
+	}
+	return deadLetterMessageList, nil
+}
+func (d rabbitMQDeadLetterReader) GetDeadLetterMessageList(
+	ctx context.Context,
+	queueName string,
+	limit uint64,
+) ([]DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.String("queue_name", queueName))
+
+	deadLetterMessageList, err := d.getDeadLetterMessageList(queueName, limit)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to read dead letter queue")
+		return nil, status.Error(codes.Internal, "failed to read dead letter queue")
+	}
+	return deadLetterMessageList, nil
+}
+func (d rabbitMQDeadLetterReader) GetDeadLetterMessage(
+	ctx context.Context,
+	queueName string,
+	partition int32,
+	offset int64,
+) (DeadLetterMessage, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.String("queue_name", queueName)).
+		With(zap.Int64("offset", offset))
+
+	if partition != 0 || offset < 0 {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	deadLetterMessageList, err := d.getDeadLetterMessageList(queueName, uint64(offset)+1)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to read dead letter queue")
+		return DeadLetterMessage{}, status.Error(codes.Internal, "failed to read dead letter queue")
+	}
+	if int64(len(deadLetterMessageList)) <= offset {
+		return DeadLetterMessage{}, status.Error(codes.NotFound, "dead letter message not found")
+	}
+	return deadLetterMessageList[offset], nil
+}
@@ -0,0 +1,143 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+type Message struct {
+	QueueName string
+	Offset    int64
+	Payload   []byte
+	Headers   map[string]string
+}
+
+// Broker is an in-process message queue shared by the in-memory producer and consumer. Every queue is an append-only
+// log kept in memory until the process exits. Messages are dropped once every consumer subscribed to their queue has
+// committed them, so that queues no one consumes, such as dead letter queues, keep all of their messages.
+type Broker interface {
+	Publish(queueName string, payload []byte, headers map[string]string)
+	// Subscribe must be called by a consumer before it receives the messages of a queue, for them to be kept until
+	// it commits them.
+	Subscribe(queueName string, consumerName string)
+	// Commit records that the consumer is done with the messages of a queue before offset.
+	Commit(queueName string, consumerName string, offset int64)
+	// Receive blocks until the message at offset is published or ctx is done. If the message at offset was dropped
+	// already, the first message kept is returned instead.
+	Receive(ctx context.Context, queueName string, offset int64) (Message, error)
+	// GetMessageList returns up to limit messages of a queue, starting at offset or at the first message kept.
+	GetMessageList(queueName string, offset int64, limit uint64) []Message
+}
+
+type queue struct {
+	// messageList holds the messages kept, the first one being at firstOffset.
+	messageList []Message
+	firstOffset int64
+	// publishedChannel is closed and replaced whenever a message is published, waking up the receivers.
+	publishedChannel              chan struct{}
+	consumerNameToCommitOffsetMap map[string]int64
+}
+
+func (q *queue) getNextOffset() int64 {
+	return q.firstOffset + int64(len(q.messageList))
+}
+
+// trim drops the messages every consumer has committed.
+func (q *queue) trim() {
+	if len(q.consumerNameToCommitOffsetMap) == 0 {
+		return
+	}
+	commitOffset := q.getNextOffset()
+	for _, consumerCommitOffset := range q.consumerNameToCommitOffsetMap {
+		commitOffset = min(commitOffset, consumerCommitOffset)
+	}
+	if commitOffset <= q.firstOffset {
+		return
+	}
+	q.messageList = slices.Clone(q.messageList[commitOffset-q.firstOffset:])
+	q.firstOffset = commitOffset
+}
+
+type broker struct {
+	queueNameToQueueMap map[string]*queue
+	mutex               sync.Mutex
+}
+
+func NewBroker() Broker {
+	return &broker{
+		queueNameToQueueMap: make(map[string]*queue),
+	}
+}
+func (b *broker) getQueue(queueName string) *queue {
+	q, ok := b.queueNameToQueueMap[queueName]
+	if !ok {
+		q = &queue{
+			publishedChannel:              make(chan struct{}),
+			consumerNameToCommitOffsetMap: make(map[string]int64),
+		}
+		b.queueNameToQueueMap[queueName] = q
+	}
+	return q
+}
+func (b *broker) Publish(queueName string, payload []byte, headers map[string]string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q := b.getQueue(queueName)
+	q.messageList = append(q.messageList, Message{
+		QueueName: queueName,
+		Offset:    q.getNextOffset(),
+		Payload:   payload,
+		Headers:   headers,
+	})
+	close(q.publishedChannel)
+	q.publishedChannel = make(chan struct{})
+}
+func (b *broker) Subscribe(queueName string, consumerName string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q := b.getQueue(queueName)
+	if _, ok := q.consumerNameToCommitOffsetMap[consumerName]; !ok {
+		q.consumerNameToCommitOffsetMap[consumerName] = q.firstOffset
+	}
+}
+func (b *broker) Commit(queueName string, consumerName string, offset int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q := b.getQueue(queueName)
+	if offset <= q.consumerNameToCommitOffsetMap[consumerName] {
+		return
+	}
+	q.consumerNameToCommitOffsetMap[consumerName] = offset
+	q.trim()
+}
+func (b *broker) Receive(ctx context.Context, queueName string, offset int64) (Message, error) {
+	for {
+		b.mutex.Lock()
+		q := b.getQueue(queueName)
+		offset = max(offset, q.firstOffset)
+		if offset < q.getNextOffset() {
+			message := q.messageList[offset-q.firstOffset]
+			b.mutex.Unlock()
+			return message, nil
+		}
+		publishedChannel := q.publishedChannel
+		b.mutex.Unlock()
+		select {
+		case <-publishedChannel:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+func (b *broker) GetMessageList(queueName string, offset int64, limit uint64) []Message {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q := b.getQueue(queueName)
+	start := max(offset, q.firstOffset) - q.firstOffset
+	if start >= int64(len(q.messageList)) {
+		return []Message{}
+	}
+	end := start + int64(min(limit, uint64(int64(len(q.messageList))-start)))
+	return append([]Message{}, q.messageList[start:end]...)
+}
@@ -0,0 +1,258 @@
+package mq_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/consumer"
+	"GoLoad/internal/dataaccess/mq/inmemory"
+	"GoLoad/internal/dataaccess/mq/producer"
+
+	"go.uber.org/zap"
+)
+
+const (
+	readyPayload  = "ready"
+	poisonPayload = "poison"
+	testTimeout   = 30 * time.Second
+	pollInterval  = 100 * time.Millisecond
+)
+
+var errPoisonMessage = errors.New("poison message")
+
+// testBackend is a message queue the suite runs against. Backends other than the in-memory one need a running server,
+// whose addresses are read from addressesEnv, and are skipped when it is not set.
+type testBackend struct {
+	mqType       configs.MQType
+	addressesEnv string
+}
+
+var testBackendList = []testBackend{
+	{mqType: configs.MQTypeInMemory},
+	{mqType: configs.MQTypeKafka, addressesEnv: "GOLOAD_TEST_KAFKA_ADDRESSES"},
+	{mqType: configs.MQTypeNATS, addressesEnv: "GOLOAD_TEST_NATS_ADDRESSES"},
+	{mqType: configs.MQTypeRabbitMQ, addressesEnv: "GOLOAD_TEST_RABBITMQ_ADDRESS"},
+}
+
+// testMQ is a producer, consumer and dead letter reader of one backend, consuming a queue of its own.
+type testMQ struct {
+	queueName        string
+	client           producer.Client
+	consumer         consumer.Consumer
+	deadLetterReader consumer.DeadLetterReader
+	payloadChannel   chan string
+}
+
+func newTestMQ(t *testing.T, backend testBackend) *testMQ {
+	t.Helper()
+	var addressList []string
+	if backend.addressesEnv != "" {
+		addresses := os.Getenv(backend.addressesEnv)
+		if addresses == "" {
+			t.Skipf("%s is not set", backend.addressesEnv)
+		}
+		addressList = strings.Split(addresses, ",")
+	}
+	suffix := fmt.Sprintf("%s_%d", strings.ReplaceAll(t.Name(), "/", "_"), time.Now().UnixNano())
+	mqConfig := configs.MQ{
+		Type:      backend.mqType,
+		Addresses: addressList,
+		ClientID:  "goload_test_" + suffix,
+		Consumer: configs.Consumer{
+			DefaultRetry: configs.ConsumerRetry{
+				MaxAttempts: 2,
+				BaseDelay:   "10ms",
+				MaxDelay:    "10ms",
+			},
+		},
+	}
+	logger := zap.NewNop()
+	inMemoryBroker := inmemory.NewBroker()
+	client, cleanup, err := producer.NewClient(mqConfig, inMemoryBroker, logger)
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	t.Cleanup(cleanup)
+	mqConsumer, err := consumer.NewConsumer(mqConfig, client, inMemoryBroker, logger)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	deadLetterReader, err := consumer.NewDeadLetterReader(mqConfig, inMemoryBroker, logger)
+	if err != nil {
+		t.Fatalf("failed to create dead letter reader: %v", err)
+	}
+	testMQ := &testMQ{
+		queueName:        "goload_test_" + suffix,
+		client:           client,
+		consumer:         mqConsumer,
+		deadLetterReader: deadLetterReader,
+		payloadChannel:   make(chan string, 100),
+	}
+	testMQ.consumer.RegisterHandler(testMQ.queueName, func(_ context.Context, _ string, payload []byte) error {
+		if string(payload) == poisonPayload {
+			return errPoisonMessage
+		}
+		testMQ.payloadChannel <- string(payload)
+		return nil
+	})
+	return testMQ
+}
+
+// start runs the consumer until the test ends. It returns once the consumer received a message, since some backends
+// only deliver the messages produced after the consumer joined.
+func (m *testMQ) start(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		if err := m.consumer.Start(ctx); err != nil {
+			t.Errorf("failed to start consumer: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		waitGroup.Wait()
+	})
+	deadline := time.After(testTimeout)
+	for {
+		m.produce(t, readyPayload)
+		select {
+		case payload := <-m.payloadChannel:
+			if payload == readyPayload {
+				return
+			}
+		case <-time.After(time.Second):
+		case <-deadline:
+			t.Fatal("timed out waiting for the consumer to start")
+		}
+	}
+}
+func (m *testMQ) produce(t *testing.T, payload string) {
+	t.Helper()
+	if err := m.client.Produce(context.Background(), m.queueName, []byte(payload)); err != nil {
+		t.Fatalf("failed to produce message: %v", err)
+	}
+}
+
+// receive returns the next payload other than the ones produced to start the consumer.
+func (m *testMQ) receive(t *testing.T) string {
+	t.Helper()
+	deadline := time.After(testTimeout)
+	for {
+		select {
+		case payload := <-m.payloadChannel:
+			if payload != readyPayload {
+				return payload
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for a message")
+		}
+	}
+}
+
+// waitForDeadLetterMessageList polls the dead letter queue until it holds count messages.
+func (m *testMQ) waitForDeadLetterMessageList(t *testing.T, count int) []consumer.DeadLetterMessage {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for {
+		deadLetterMessageList, err := m.deadLetterReader.GetDeadLetterMessageList(context.Background(), m.queueName, 10)
+		if err != nil {
+			t.Fatalf("failed to get dead letter message list: %v", err)
+		}
+		if len(deadLetterMessageList) >= count {
+			return deadLetterMessageList
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d dead letter messages, got %d", count, len(deadLetterMessageList))
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func TestMQ(t *testing.T) {
+	testCaseList := []struct {
+		name string
+		run  func(t *testing.T, m *testMQ)
+	}{
+		{
+			name: "delivers the messages of a queue in order",
+			run: func(t *testing.T, m *testMQ) {
+				payloadList := []string{"first", "second", "third"}
+				for _, payload := range payloadList {
+					m.produce(t, payload)
+				}
+				for _, expectedPayload := range payloadList {
+					if payload := m.receive(t); payload != expectedPayload {
+						t.Fatalf("expected payload %q, got %q", expectedPayload, payload)
+					}
+				}
+			},
+		},
+		{
+			name: "moves messages failing too many times to the dead letter queue",
+			run: func(t *testing.T, m *testMQ) {
+				m.produce(t, poisonPayload)
+				m.produce(t, "after poison")
+				if payload := m.receive(t); payload != "after poison" {
+					t.Fatalf("expected the message after the poison one, got %q", payload)
+				}
+				deadLetterMessage := m.waitForDeadLetterMessageList(t, 1)[0]
+				if string(deadLetterMessage.Payload) != poisonPayload {
+					t.Errorf("expected dead letter payload %q, got %q", poisonPayload, deadLetterMessage.Payload)
+				}
+				if deadLetterMessage.QueueName != m.queueName {
+					t.Errorf("expected dead letter queue name %q, got %q", m.queueName, deadLetterMessage.QueueName)
+				}
+				if deadLetterMessage.AttemptCount != 2 {
+					t.Errorf("expected 2 attempts, got %d", deadLetterMessage.AttemptCount)
+				}
+				if deadLetterMessage.Error != errPoisonMessage.Error() {
+					t.Errorf("expected error %q, got %q", errPoisonMessage.Error(), deadLetterMessage.Error)
+				}
+			},
+		},
+		{
+			name: "reads dead letter messages without consuming them",
+			run: func(t *testing.T, m *testMQ) {
+				m.produce(t, poisonPayload)
+				m.produce(t, poisonPayload)
+				deadLetterMessageList := m.waitForDeadLetterMessageList(t, 2)
+				if again := m.waitForDeadLetterMessageList(t, 2); len(again) != len(deadLetterMessageList) {
+					t.Fatalf("expected %d dead letter messages when reading again, got %d", len(deadLetterMessageList), len(again))
+				}
+				for _, deadLetterMessage := range deadLetterMessageList {
+					gotDeadLetterMessage, err := m.deadLetterReader.GetDeadLetterMessage(
+						context.Background(), m.queueName, deadLetterMessage.Partition, deadLetterMessage.Offset,
+					)
+					if err != nil {
+						t.Fatalf("failed to get dead letter message: %v", err)
+					}
+					if gotDeadLetterMessage.Offset != deadLetterMessage.Offset ||
+						string(gotDeadLetterMessage.Payload) != string(deadLetterMessage.Payload) {
+						t.Errorf("expected dead letter message %+v, got %+v", deadLetterMessage, gotDeadLetterMessage)
+					}
+				}
+			},
+		},
+	}
+	for _, backend := range testBackendList {
+		t.Run(string(backend.mqType), func(t *testing.T) {
+			for _, testCase := range testCaseList {
+				t.Run(testCase.name, func(t *testing.T) {
+					m := newTestMQ(t, backend)
+					m.start(t)
+					testCase.run(t, m)
+				})
+			}
+		})
+	}
+}
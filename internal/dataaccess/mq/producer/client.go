@@ -2,6 +2,7 @@ package producer
 
 import (
 	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/mq/inmemory"
 	"GoLoad/internal/utils"
 	"context"
 	"fmt"
@@ -14,8 +15,29 @@ import (
 
 type Client interface {
 	Produce(ctx context.Context, queueName string, payload []byte) error
+	ProduceWithHeaders(ctx context.Context, queueName string, payload []byte, headers map[string]string) error
+	// Ping reports whether the message queue can be reached.
+	Ping(ctx context.Context) error
 }
-type client struct {
+
+// NewClient returns a function closing the connections of the client, to be called once it is not used anymore.
+func NewClient(mqConfig configs.MQ, inMemoryBroker inmemory.Broker, logger *zap.Logger) (Client, func(), error) {
+	switch mqConfig.GetType() {
+	case configs.MQTypeKafka:
+		return NewKafkaClient(mqConfig, logger)
+	case configs.MQTypeInMemory:
+		return NewInMemoryClient(inMemoryBroker), func() {}, nil
+	case configs.MQTypeNATS:
+		return NewNATSClient(mqConfig, logger)
+	case configs.MQTypeRabbitMQ:
+		return NewRabbitMQClient(mqConfig, logger)
+	default:
+		return nil, nil, fmt.Errorf("unsupported mq type: %s", mqConfig.Type)
+	}
+}
+
+type kafkaClient struct {
+	saramaClient       sarama.Client
 	saramaSyncProducer sarama.SyncProducer
 	logger             *zap.Logger
 }
@@ -29,27 +51,65 @@ func newSaramaConfig(mqConfig configs.MQ) *sarama.Config {
 	saramaConfig.Metadata.Full = true
 	return saramaConfig
 }
-func NewClient(mqConfig configs.MQ, logger *zap.Logger) (Client, error) {
-	saramaSyncProducer, err := sarama.NewSyncProducer(mqConfig.Addresses, newSaramaConfig(mqConfig))
+func NewKafkaClient(mqConfig configs.MQ, logger *zap.Logger) (Client, func(), error) {
+	saramaClient, err := sarama.NewClient(mqConfig.Addresses, newSaramaConfig(mqConfig))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create sarama sync producer: %w", err)
+		return nil, nil, fmt.Errorf("failed to create sarama client: %w", err)
+	}
+	saramaSyncProducer, err := sarama.NewSyncProducerFromClient(saramaClient)
+	if err != nil {
+		_ = saramaClient.Close()
+		return nil, nil, fmt.Errorf("failed to create sarama sync producer: %w", err)
+	}
+	cleanup := func() {
+		_ = saramaSyncProducer.Close()
+		_ = saramaClient.Close()
 	}
-	return &client{
+	return &kafkaClient{
+		saramaClient:       saramaClient,
 		saramaSyncProducer: saramaSyncProducer,
 		logger:             logger,
-	}, nil
+	}, cleanup, nil
 }
-func (c client) Produce(ctx context.Context, queueName string, payload []byte) error {
+func (c kafkaClient) Produce(ctx context.Context, queueName string, payload []byte) error {
+	return c.ProduceWithHeaders(ctx, queueName, payload, nil)
+}
+func (c kafkaClient) ProduceWithHeaders(ctx context.Context, queueName string, payload []byte, headers map[string]string) error {
 	logger := utils.LoggerWithContext(ctx, c.logger).
 		With(zap.String("queue_name", queueName)).
 		With(zap.ByteString("payload", payload))
 
+	recordHeaderList := make([]sarama.RecordHeader, 0, len(headers))
+	for key, value := range headers {
+		recordHeaderList = append(recordHeaderList, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
 	if _, _, err := c.saramaSyncProducer.SendMessage(&sarama.ProducerMessage{
-		Topic: queueName,
-		Value: sarama.ByteEncoder(payload),
+		Topic:   queueName,
+		Value:   sarama.ByteEncoder(payload),
+		Headers: recordHeaderList,
 	}); err != nil {
 		logger.With(zap.Error(err)).Error("failed to produce message")
 		return status.Error(codes.Internal, "failed to produce message")
 	}
 	return nil
 }
+
+// Ping refreshes the metadata of the cluster, which takes a round trip to the brokers.
+func (c kafkaClient) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	refreshErrChannel := make(chan error, 1)
+	go func() {
+		refreshErrChannel <- c.saramaClient.RefreshMetadata()
+	}()
+	select {
+	case err := <-refreshErrChannel:
+		if err != nil {
+			logger.With(zap.Error(err)).Warn("failed to refresh kafka metadata")
+			return status.Error(codes.Unavailable, "failed to refresh kafka metadata")
+		}
+		return nil
+	case <-ctx.Done():
+		return status.Error(codes.DeadlineExceeded, "timed out refreshing kafka metadata")
+	}
+}
@@ -0,0 +1,43 @@
+package producer
+
+import "time"
+
+const (
+	// MessageQueueDownloadTaskLifecycleEvent is the public queue other services consume. Its name carries the schema
+	// version, so that a breaking change is published to a new queue alongside the old one.
+	MessageQueueDownloadTaskLifecycleEvent = "goload.download_task_lifecycle.v1"
+	// DownloadTaskLifecycleEventSchemaVersion matches api/events/download_task_lifecycle_event.v1.schema.json.
+	DownloadTaskLifecycleEventSchemaVersion = "1"
+)
+
+type DownloadTaskLifecycleEventType string
+
+const (
+	DownloadTaskLifecycleEventTypeCreated           DownloadTaskLifecycleEventType = "created"
+	DownloadTaskLifecycleEventTypeStarted           DownloadTaskLifecycleEventType = "started"
+	DownloadTaskLifecycleEventTypeProgressMilestone DownloadTaskLifecycleEventType = "progress_milestone"
+	DownloadTaskLifecycleEventTypeSucceeded         DownloadTaskLifecycleEventType = "succeeded"
+	DownloadTaskLifecycleEventTypeFailed            DownloadTaskLifecycleEventType = "failed"
+	DownloadTaskLifecycleEventTypeDeleted           DownloadTaskLifecycleEventType = "deleted"
+)
+
+// DownloadTaskLifecycleEvent is published through the outbox, at least once and not necessarily in order, so consumers
+// should deduplicate on EventID and order on EventTime. Fields not relevant to the event type are omitted.
+type DownloadTaskLifecycleEvent struct {
+	SchemaVersion  string                         `json:"schema_version"`
+	EventID        string                         `json:"event_id"`
+	EventType      DownloadTaskLifecycleEventType `json:"event_type"`
+	EventTime      time.Time                      `json:"event_time"`
+	DownloadTaskID uint64                         `json:"download_task_id"`
+	OfAccountID    uint64                         `json:"of_account_id"`
+	OfWorkspaceID  *uint64                        `json:"of_workspace_id,omitempty"`
+	URL            string                         `json:"url"`
+	// ProgressPercent is only set for progress_milestone events.
+	ProgressPercent uint64 `json:"progress_percent,omitempty"`
+	// DownloadedSize is set for progress_milestone and succeeded events.
+	DownloadedSize uint64 `json:"downloaded_size,omitempty"`
+	// FileName, ContentType and Checksum are only set for succeeded events.
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
+}
@@ -0,0 +1,26 @@
+package producer
+
+import (
+	"GoLoad/internal/dataaccess/mq/inmemory"
+	"context"
+)
+
+type inMemoryClient struct {
+	inMemoryBroker inmemory.Broker
+}
+
+func NewInMemoryClient(inMemoryBroker inmemory.Broker) Client {
+	return &inMemoryClient{
+		inMemoryBroker: inMemoryBroker,
+	}
+}
+func (c inMemoryClient) Produce(ctx context.Context, queueName string, payload []byte) error {
+	return c.ProduceWithHeaders(ctx, queueName, payload, nil)
+}
+func (c inMemoryClient) ProduceWithHeaders(_ context.Context, queueName string, payload []byte, headers map[string]string) error {
+	c.inMemoryBroker.Publish(queueName, payload, headers)
+	return nil
+}
+func (c inMemoryClient) Ping(context.Context) error {
+	return nil
+}
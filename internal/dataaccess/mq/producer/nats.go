@@ -0,0 +1,101 @@
+package producer
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var natsStreamNameReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_")
+
+// NATSStreamName returns the JetStream stream storing the messages of a queue. Every queue has its own stream, whose
+// only subject is the queue name.
+func NATSStreamName(queueName string) string {
+	return natsStreamNameReplacer.Replace(queueName)
+}
+
+// CreateOrUpdateNATSStream makes sure the stream of a queue exists, so that producers and consumers can be started in
+// any order.
+func CreateOrUpdateNATSStream(ctx context.Context, jetStream jetstream.JetStream, queueName string) (jetstream.Stream, error) {
+	return jetStream.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     NATSStreamName(queueName),
+		Subjects: []string{queueName},
+	})
+}
+
+// NewNATSJetStream connects to the NATS servers of the config. The connection is returned so that short-lived users
+// can close it.
+func NewNATSJetStream(mqConfig configs.MQ) (*nats.Conn, jetstream.JetStream, error) {
+	natsConn, err := nats.Connect(strings.Join(mqConfig.Addresses, ","), nats.Name(mqConfig.ClientID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	jetStream, err := jetstream.New(natsConn)
+	if err != nil {
+		natsConn.Close()
+		return nil, nil, fmt.Errorf("failed to create nats jetstream: %w", err)
+	}
+	return natsConn, jetStream, nil
+}
+
+type natsClient struct {
+	jetStream jetstream.JetStream
+	// createdStreamQueueNameSet holds the queues whose stream was already created by this client.
+	createdStreamQueueNameSet sync.Map
+	logger                    *zap.Logger
+}
+
+func NewNATSClient(mqConfig configs.MQ, logger *zap.Logger) (Client, func(), error) {
+	natsConn, jetStream, err := NewNATSJetStream(mqConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &natsClient{
+		jetStream: jetStream,
+		logger:    logger,
+	}, natsConn.Close, nil
+}
+func (c *natsClient) Produce(ctx context.Context, queueName string, payload []byte) error {
+	return c.ProduceWithHeaders(ctx, queueName, payload, nil)
+}
+func (c *natsClient) ProduceWithHeaders(ctx context.Context, queueName string, payload []byte, headers map[string]string) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).
+		With(zap.String("queue_name", queueName)).
+		With(zap.ByteString("payload", payload))
+
+	if _, ok := c.createdStreamQueueNameSet.Load(queueName); !ok {
+		if _, err := CreateOrUpdateNATSStream(ctx, c.jetStream, queueName); err != nil {
+			logger.With(zap.Error(err)).Error("failed to create nats stream")
+			return status.Error(codes.Internal, "failed to create nats stream")
+		}
+		c.createdStreamQueueNameSet.Store(queueName, struct{}{})
+	}
+	natsMessage := nats.NewMsg(queueName)
+	natsMessage.Data = payload
+	for key, value := range headers {
+		natsMessage.Header.Set(key, value)
+	}
+	if _, err := c.jetStream.PublishMsg(ctx, natsMessage); err != nil {
+		logger.With(zap.Error(err)).Error("failed to produce message")
+		return status.Error(codes.Internal, "failed to produce message")
+	}
+	return nil
+}
+func (c *natsClient) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	if _, err := c.jetStream.AccountInfo(ctx); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to get nats jetstream account info")
+		return status.Error(codes.Unavailable, "failed to get nats jetstream account info")
+	}
+	return nil
+}
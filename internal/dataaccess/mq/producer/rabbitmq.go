@@ -0,0 +1,140 @@
+package producer
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeclareRabbitMQQueue makes sure a durable queue exists, so that producers and consumers can be started in any
+// order. Messages are published to queues directly through the default exchange.
+func DeclareRabbitMQQueue(channel *amqp.Channel, queueName string) error {
+	_, err := channel.QueueDeclare(queueName, true, false, false, false, nil)
+	return err
+}
+
+// GetRabbitMQURL returns the AMQP URL of the config.
+func GetRabbitMQURL(mqConfig configs.MQ) (string, error) {
+	if len(mqConfig.Addresses) != 1 {
+		return "", fmt.Errorf("rabbitmq requires exactly one address, got %d", len(mqConfig.Addresses))
+	}
+	return mqConfig.Addresses[0], nil
+}
+
+// rabbitMQClient publishes with publisher confirms on a single channel, opening a new connection whenever the previous
+// one was closed.
+type rabbitMQClient struct {
+	mqConfig             configs.MQ
+	connection           *amqp.Connection
+	channel              *amqp.Channel
+	declaredQueueNameSet map[string]struct{}
+	mutex                sync.Mutex
+	logger               *zap.Logger
+}
+
+func NewRabbitMQClient(mqConfig configs.MQ, logger *zap.Logger) (Client, func(), error) {
+	client := &rabbitMQClient{
+		mqConfig:             mqConfig,
+		declaredQueueNameSet: make(map[string]struct{}),
+		logger:               logger,
+	}
+	return client, client.close, nil
+}
+func (c *rabbitMQClient) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.connection != nil {
+		_ = c.connection.Close()
+	}
+}
+func (c *rabbitMQClient) getChannel() (*amqp.Channel, error) {
+	if c.channel != nil && !c.channel.IsClosed() {
+		return c.channel, nil
+	}
+	if c.connection != nil {
+		_ = c.connection.Close()
+	}
+	url, err := GetRabbitMQURL(c.mqConfig)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := amqp.DialConfig(url, amqp.Config{Properties: amqp.Table{"connection_name": c.mqConfig.ClientID}})
+	if err != nil {
+		return nil, err
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+	if err = channel.Confirm(false); err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+	c.connection = connection
+	c.channel = channel
+	c.declaredQueueNameSet = make(map[string]struct{})
+	return channel, nil
+}
+func (c *rabbitMQClient) Produce(ctx context.Context, queueName string, payload []byte) error {
+	return c.ProduceWithHeaders(ctx, queueName, payload, nil)
+}
+func (c *rabbitMQClient) ProduceWithHeaders(ctx context.Context, queueName string, payload []byte, headers map[string]string) error {
+	logger := utils.LoggerWithContext(ctx, c.logger).
+		With(zap.String("queue_name", queueName)).
+		With(zap.ByteString("payload", payload))
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	channel, err := c.getChannel()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to open rabbitmq channel")
+		return status.Error(codes.Internal, "failed to open rabbitmq channel")
+	}
+	if _, ok := c.declaredQueueNameSet[queueName]; !ok {
+		if err = DeclareRabbitMQQueue(channel, queueName); err != nil {
+			logger.With(zap.Error(err)).Error("failed to declare rabbitmq queue")
+			return status.Error(codes.Internal, "failed to declare rabbitmq queue")
+		}
+		c.declaredQueueNameSet[queueName] = struct{}{}
+	}
+	headerTable := make(amqp.Table, len(headers))
+	for key, value := range headers {
+		headerTable[key] = value
+	}
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, "", queueName, false, false, amqp.Publishing{
+		Headers:      headerTable,
+		DeliveryMode: amqp.Persistent,
+		Body:         payload,
+	})
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to produce message")
+		return status.Error(codes.Internal, "failed to produce message")
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil || !acked {
+		logger.With(zap.Error(err)).Error("message was not confirmed by rabbitmq")
+		return status.Error(codes.Internal, "failed to produce message")
+	}
+	return nil
+}
+
+// Ping reconnects to RabbitMQ if the channel used to produce messages was closed.
+func (c *rabbitMQClient) Ping(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, c.logger)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, err := c.getChannel(); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to open rabbitmq channel")
+		return status.Error(codes.Unavailable, "failed to open rabbitmq channel")
+	}
+	return nil
+}
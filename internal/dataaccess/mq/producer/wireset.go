@@ -4,5 +4,4 @@ import "github.com/google/wire"
 
 var WireSet = wire.NewSet(
 	NewClient,
-	NewDownloadTaskCreatedProducer,
 )
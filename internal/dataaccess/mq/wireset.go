@@ -2,11 +2,13 @@ package mq
 
 import (
 	"GoLoad/internal/dataaccess/mq/consumer"
+	"GoLoad/internal/dataaccess/mq/inmemory"
 	"GoLoad/internal/dataaccess/mq/producer"
 	"github.com/google/wire"
 )
 
 var WireSet = wire.NewSet(
 	consumer.WireSet,
+	inmemory.WireSet,
 	producer.WireSet,
 )
@@ -19,13 +19,32 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GoLoadService_CreateAccount_FullMethodName       = "/go_load.GoLoadService/CreateAccount"
-	GoLoadService_CreateSession_FullMethodName       = "/go_load.GoLoadService/CreateSession"
-	GoLoadService_CreateDownloadTask_FullMethodName  = "/go_load.GoLoadService/CreateDownloadTask"
-	GoLoadService_GetDownloadTaskList_FullMethodName = "/go_load.GoLoadService/GetDownloadTaskList"
-	GoLoadService_UpdateDownloadTask_FullMethodName  = "/go_load.GoLoadService/UpdateDownloadTask"
-	GoLoadService_DeleteDownloadTask_FullMethodName  = "/go_load.GoLoadService/DeleteDownloadTask"
-	GoLoadService_GetDownloadTaskFile_FullMethodName = "/go_load.GoLoadService/GetDownloadTaskFile"
+	GoLoadService_CreateAccount_FullMethodName             = "/go_load.GoLoadService/CreateAccount"
+	GoLoadService_CreateSession_FullMethodName             = "/go_load.GoLoadService/CreateSession"
+	GoLoadService_RefreshSession_FullMethodName            = "/go_load.GoLoadService/RefreshSession"
+	GoLoadService_DeleteSession_FullMethodName             = "/go_load.GoLoadService/DeleteSession"
+	GoLoadService_ChangePassword_FullMethodName            = "/go_load.GoLoadService/ChangePassword"
+	GoLoadService_ResetPassword_FullMethodName             = "/go_load.GoLoadService/ResetPassword"
+	GoLoadService_DeleteAccount_FullMethodName             = "/go_load.GoLoadService/DeleteAccount"
+	GoLoadService_CreateAPIKey_FullMethodName              = "/go_load.GoLoadService/CreateAPIKey"
+	GoLoadService_GetAPIKeyList_FullMethodName             = "/go_load.GoLoadService/GetAPIKeyList"
+	GoLoadService_RevokeAPIKey_FullMethodName              = "/go_load.GoLoadService/RevokeAPIKey"
+	GoLoadService_CreateDownloadTask_FullMethodName        = "/go_load.GoLoadService/CreateDownloadTask"
+	GoLoadService_GetDownloadTaskList_FullMethodName       = "/go_load.GoLoadService/GetDownloadTaskList"
+	GoLoadService_UpdateDownloadTask_FullMethodName        = "/go_load.GoLoadService/UpdateDownloadTask"
+	GoLoadService_DeleteDownloadTask_FullMethodName        = "/go_load.GoLoadService/DeleteDownloadTask"
+	GoLoadService_GetDownloadTaskFile_FullMethodName       = "/go_load.GoLoadService/GetDownloadTaskFile"
+	GoLoadService_GetDownloadTaskFileURL_FullMethodName    = "/go_load.GoLoadService/GetDownloadTaskFileURL"
+	GoLoadService_CreateWorkspace_FullMethodName           = "/go_load.GoLoadService/CreateWorkspace"
+	GoLoadService_GetWorkspaceList_FullMethodName          = "/go_load.GoLoadService/GetWorkspaceList"
+	GoLoadService_GetWorkspaceMemberList_FullMethodName    = "/go_load.GoLoadService/GetWorkspaceMemberList"
+	GoLoadService_AddWorkspaceMember_FullMethodName        = "/go_load.GoLoadService/AddWorkspaceMember"
+	GoLoadService_UpdateWorkspaceMemberRole_FullMethodName = "/go_load.GoLoadService/UpdateWorkspaceMemberRole"
+	GoLoadService_RemoveWorkspaceMember_FullMethodName     = "/go_load.GoLoadService/RemoveWorkspaceMember"
+	GoLoadService_CreateWebhook_FullMethodName             = "/go_load.GoLoadService/CreateWebhook"
+	GoLoadService_GetWebhookList_FullMethodName            = "/go_load.GoLoadService/GetWebhookList"
+	GoLoadService_DeleteWebhook_FullMethodName             = "/go_load.GoLoadService/DeleteWebhook"
+	GoLoadService_GetWebhookDeliveryList_FullMethodName    = "/go_load.GoLoadService/GetWebhookDeliveryList"
 )
 
 // GoLoadServiceClient is the client API for GoLoadService service.
@@ -34,11 +53,30 @@ const (
 type GoLoadServiceClient interface {
 	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error)
 	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error)
+	RefreshSession(ctx context.Context, in *RefreshSessionRequest, opts ...grpc.CallOption) (*RefreshSessionResponse, error)
+	DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error)
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResponse, error)
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*ResetPasswordResponse, error)
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResponse, error)
+	CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error)
+	GetAPIKeyList(ctx context.Context, in *GetAPIKeyListRequest, opts ...grpc.CallOption) (*GetAPIKeyListResponse, error)
+	RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error)
 	CreateDownloadTask(ctx context.Context, in *CreateDownloadTaskRequest, opts ...grpc.CallOption) (*CreateDownloadTaskResponse, error)
 	GetDownloadTaskList(ctx context.Context, in *GetDownloadTaskListRequest, opts ...grpc.CallOption) (*GetDownloadTaskListResponse, error)
 	UpdateDownloadTask(ctx context.Context, in *UpdateDownloadTaskRequest, opts ...grpc.CallOption) (*UpdateDownloadTaskResponse, error)
 	DeleteDownloadTask(ctx context.Context, in *DeleteDownloadTaskRequest, opts ...grpc.CallOption) (*DeleteDownloadTaskResponse, error)
 	GetDownloadTaskFile(ctx context.Context, in *GetDownloadTaskFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetDownloadTaskFileResponse], error)
+	GetDownloadTaskFileURL(ctx context.Context, in *GetDownloadTaskFileURLRequest, opts ...grpc.CallOption) (*GetDownloadTaskFileURLResponse, error)
+	CreateWorkspace(ctx context.Context, in *CreateWorkspaceRequest, opts ...grpc.CallOption) (*CreateWorkspaceResponse, error)
+	GetWorkspaceList(ctx context.Context, in *GetWorkspaceListRequest, opts ...grpc.CallOption) (*GetWorkspaceListResponse, error)
+	GetWorkspaceMemberList(ctx context.Context, in *GetWorkspaceMemberListRequest, opts ...grpc.CallOption) (*GetWorkspaceMemberListResponse, error)
+	AddWorkspaceMember(ctx context.Context, in *AddWorkspaceMemberRequest, opts ...grpc.CallOption) (*AddWorkspaceMemberResponse, error)
+	UpdateWorkspaceMemberRole(ctx context.Context, in *UpdateWorkspaceMemberRoleRequest, opts ...grpc.CallOption) (*UpdateWorkspaceMemberRoleResponse, error)
+	RemoveWorkspaceMember(ctx context.Context, in *RemoveWorkspaceMemberRequest, opts ...grpc.CallOption) (*RemoveWorkspaceMemberResponse, error)
+	CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*CreateWebhookResponse, error)
+	GetWebhookList(ctx context.Context, in *GetWebhookListRequest, opts ...grpc.CallOption) (*GetWebhookListResponse, error)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+	GetWebhookDeliveryList(ctx context.Context, in *GetWebhookDeliveryListRequest, opts ...grpc.CallOption) (*GetWebhookDeliveryListResponse, error)
 }
 
 type goLoadServiceClient struct {
@@ -69,6 +107,86 @@ func (c *goLoadServiceClient) CreateSession(ctx context.Context, in *CreateSessi
 	return out, nil
 }
 
+func (c *goLoadServiceClient) RefreshSession(ctx context.Context, in *RefreshSessionRequest, opts ...grpc.CallOption) (*RefreshSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshSessionResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_RefreshSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSessionResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_DeleteSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*ChangePasswordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangePasswordResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_ChangePassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*ResetPasswordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetPasswordResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_ResetPassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAccountResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_DeleteAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateAPIKeyResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_CreateAPIKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) GetAPIKeyList(ctx context.Context, in *GetAPIKeyListRequest, opts ...grpc.CallOption) (*GetAPIKeyListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAPIKeyListResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetAPIKeyList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeAPIKeyResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_RevokeAPIKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *goLoadServiceClient) CreateDownloadTask(ctx context.Context, in *CreateDownloadTaskRequest, opts ...grpc.CallOption) (*CreateDownloadTaskResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CreateDownloadTaskResponse)
@@ -128,17 +246,146 @@ func (c *goLoadServiceClient) GetDownloadTaskFile(ctx context.Context, in *GetDo
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type GoLoadService_GetDownloadTaskFileClient = grpc.ServerStreamingClient[GetDownloadTaskFileResponse]
 
+func (c *goLoadServiceClient) GetDownloadTaskFileURL(ctx context.Context, in *GetDownloadTaskFileURLRequest, opts ...grpc.CallOption) (*GetDownloadTaskFileURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDownloadTaskFileURLResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetDownloadTaskFileURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) CreateWorkspace(ctx context.Context, in *CreateWorkspaceRequest, opts ...grpc.CallOption) (*CreateWorkspaceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateWorkspaceResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_CreateWorkspace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) GetWorkspaceList(ctx context.Context, in *GetWorkspaceListRequest, opts ...grpc.CallOption) (*GetWorkspaceListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWorkspaceListResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetWorkspaceList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) GetWorkspaceMemberList(ctx context.Context, in *GetWorkspaceMemberListRequest, opts ...grpc.CallOption) (*GetWorkspaceMemberListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWorkspaceMemberListResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetWorkspaceMemberList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) AddWorkspaceMember(ctx context.Context, in *AddWorkspaceMemberRequest, opts ...grpc.CallOption) (*AddWorkspaceMemberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddWorkspaceMemberResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_AddWorkspaceMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) UpdateWorkspaceMemberRole(ctx context.Context, in *UpdateWorkspaceMemberRoleRequest, opts ...grpc.CallOption) (*UpdateWorkspaceMemberRoleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateWorkspaceMemberRoleResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_UpdateWorkspaceMemberRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) RemoveWorkspaceMember(ctx context.Context, in *RemoveWorkspaceMemberRequest, opts ...grpc.CallOption) (*RemoveWorkspaceMemberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveWorkspaceMemberResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_RemoveWorkspaceMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*CreateWebhookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateWebhookResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_CreateWebhook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) GetWebhookList(ctx context.Context, in *GetWebhookListRequest, opts ...grpc.CallOption) (*GetWebhookListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWebhookListResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetWebhookList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_DeleteWebhook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLoadServiceClient) GetWebhookDeliveryList(ctx context.Context, in *GetWebhookDeliveryListRequest, opts ...grpc.CallOption) (*GetWebhookDeliveryListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWebhookDeliveryListResponse)
+	err := c.cc.Invoke(ctx, GoLoadService_GetWebhookDeliveryList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GoLoadServiceServer is the server API for GoLoadService service.
 // All implementations must embed UnimplementedGoLoadServiceServer
 // for forward compatibility.
 type GoLoadServiceServer interface {
 	CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error)
 	CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error)
+	RefreshSession(context.Context, *RefreshSessionRequest) (*RefreshSessionResponse, error)
+	DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error)
+	ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordResponse, error)
+	ResetPassword(context.Context, *ResetPasswordRequest) (*ResetPasswordResponse, error)
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error)
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	GetAPIKeyList(context.Context, *GetAPIKeyListRequest) (*GetAPIKeyListResponse, error)
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*RevokeAPIKeyResponse, error)
 	CreateDownloadTask(context.Context, *CreateDownloadTaskRequest) (*CreateDownloadTaskResponse, error)
 	GetDownloadTaskList(context.Context, *GetDownloadTaskListRequest) (*GetDownloadTaskListResponse, error)
 	UpdateDownloadTask(context.Context, *UpdateDownloadTaskRequest) (*UpdateDownloadTaskResponse, error)
 	DeleteDownloadTask(context.Context, *DeleteDownloadTaskRequest) (*DeleteDownloadTaskResponse, error)
 	GetDownloadTaskFile(*GetDownloadTaskFileRequest, grpc.ServerStreamingServer[GetDownloadTaskFileResponse]) error
+	GetDownloadTaskFileURL(context.Context, *GetDownloadTaskFileURLRequest) (*GetDownloadTaskFileURLResponse, error)
+	CreateWorkspace(context.Context, *CreateWorkspaceRequest) (*CreateWorkspaceResponse, error)
+	GetWorkspaceList(context.Context, *GetWorkspaceListRequest) (*GetWorkspaceListResponse, error)
+	GetWorkspaceMemberList(context.Context, *GetWorkspaceMemberListRequest) (*GetWorkspaceMemberListResponse, error)
+	AddWorkspaceMember(context.Context, *AddWorkspaceMemberRequest) (*AddWorkspaceMemberResponse, error)
+	UpdateWorkspaceMemberRole(context.Context, *UpdateWorkspaceMemberRoleRequest) (*UpdateWorkspaceMemberRoleResponse, error)
+	RemoveWorkspaceMember(context.Context, *RemoveWorkspaceMemberRequest) (*RemoveWorkspaceMemberResponse, error)
+	CreateWebhook(context.Context, *CreateWebhookRequest) (*CreateWebhookResponse, error)
+	GetWebhookList(context.Context, *GetWebhookListRequest) (*GetWebhookListResponse, error)
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	GetWebhookDeliveryList(context.Context, *GetWebhookDeliveryListRequest) (*GetWebhookDeliveryListResponse, error)
 	mustEmbedUnimplementedGoLoadServiceServer()
 }
 
@@ -155,6 +402,30 @@ func (UnimplementedGoLoadServiceServer) CreateAccount(context.Context, *CreateAc
 func (UnimplementedGoLoadServiceServer) CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
 }
+func (UnimplementedGoLoadServiceServer) RefreshSession(context.Context, *RefreshSessionRequest) (*RefreshSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshSession not implemented")
+}
+func (UnimplementedGoLoadServiceServer) DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSession not implemented")
+}
+func (UnimplementedGoLoadServiceServer) ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedGoLoadServiceServer) ResetPassword(context.Context, *ResetPasswordRequest) (*ResetPasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedGoLoadServiceServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
+func (UnimplementedGoLoadServiceServer) CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAPIKey not implemented")
+}
+func (UnimplementedGoLoadServiceServer) GetAPIKeyList(context.Context, *GetAPIKeyListRequest) (*GetAPIKeyListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAPIKeyList not implemented")
+}
+func (UnimplementedGoLoadServiceServer) RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*RevokeAPIKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAPIKey not implemented")
+}
 func (UnimplementedGoLoadServiceServer) CreateDownloadTask(context.Context, *CreateDownloadTaskRequest) (*CreateDownloadTaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateDownloadTask not implemented")
 }
@@ -170,6 +441,39 @@ func (UnimplementedGoLoadServiceServer) DeleteDownloadTask(context.Context, *Del
 func (UnimplementedGoLoadServiceServer) GetDownloadTaskFile(*GetDownloadTaskFileRequest, grpc.ServerStreamingServer[GetDownloadTaskFileResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method GetDownloadTaskFile not implemented")
 }
+func (UnimplementedGoLoadServiceServer) GetDownloadTaskFileURL(context.Context, *GetDownloadTaskFileURLRequest) (*GetDownloadTaskFileURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDownloadTaskFileURL not implemented")
+}
+func (UnimplementedGoLoadServiceServer) CreateWorkspace(context.Context, *CreateWorkspaceRequest) (*CreateWorkspaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWorkspace not implemented")
+}
+func (UnimplementedGoLoadServiceServer) GetWorkspaceList(context.Context, *GetWorkspaceListRequest) (*GetWorkspaceListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkspaceList not implemented")
+}
+func (UnimplementedGoLoadServiceServer) GetWorkspaceMemberList(context.Context, *GetWorkspaceMemberListRequest) (*GetWorkspaceMemberListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkspaceMemberList not implemented")
+}
+func (UnimplementedGoLoadServiceServer) AddWorkspaceMember(context.Context, *AddWorkspaceMemberRequest) (*AddWorkspaceMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddWorkspaceMember not implemented")
+}
+func (UnimplementedGoLoadServiceServer) UpdateWorkspaceMemberRole(context.Context, *UpdateWorkspaceMemberRoleRequest) (*UpdateWorkspaceMemberRoleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWorkspaceMemberRole not implemented")
+}
+func (UnimplementedGoLoadServiceServer) RemoveWorkspaceMember(context.Context, *RemoveWorkspaceMemberRequest) (*RemoveWorkspaceMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveWorkspaceMember not implemented")
+}
+func (UnimplementedGoLoadServiceServer) CreateWebhook(context.Context, *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedGoLoadServiceServer) GetWebhookList(context.Context, *GetWebhookListRequest) (*GetWebhookListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookList not implemented")
+}
+func (UnimplementedGoLoadServiceServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedGoLoadServiceServer) GetWebhookDeliveryList(context.Context, *GetWebhookDeliveryListRequest) (*GetWebhookDeliveryListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookDeliveryList not implemented")
+}
 func (UnimplementedGoLoadServiceServer) mustEmbedUnimplementedGoLoadServiceServer() {}
 func (UnimplementedGoLoadServiceServer) testEmbeddedByValue()                       {}
 
@@ -227,6 +531,150 @@ func _GoLoadService_CreateSession_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GoLoadService_RefreshSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).RefreshSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_RefreshSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).RefreshSession(ctx, req.(*RefreshSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_DeleteSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).DeleteSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_DeleteSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).DeleteSession(ctx, req.(*DeleteSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_ChangePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_ResetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_DeleteAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_CreateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).CreateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_CreateAPIKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).CreateAPIKey(ctx, req.(*CreateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_GetAPIKeyList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAPIKeyListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetAPIKeyList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetAPIKeyList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetAPIKeyList(ctx, req.(*GetAPIKeyListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_RevokeAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).RevokeAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_RevokeAPIKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).RevokeAPIKey(ctx, req.(*RevokeAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _GoLoadService_CreateDownloadTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateDownloadTaskRequest)
 	if err := dec(in); err != nil {
@@ -310,6 +758,204 @@ func _GoLoadService_GetDownloadTaskFile_Handler(srv interface{}, stream grpc.Ser
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type GoLoadService_GetDownloadTaskFileServer = grpc.ServerStreamingServer[GetDownloadTaskFileResponse]
 
+func _GoLoadService_GetDownloadTaskFileURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDownloadTaskFileURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetDownloadTaskFileURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetDownloadTaskFileURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetDownloadTaskFileURL(ctx, req.(*GetDownloadTaskFileURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_CreateWorkspace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWorkspaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).CreateWorkspace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_CreateWorkspace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).CreateWorkspace(ctx, req.(*CreateWorkspaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_GetWorkspaceList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkspaceListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetWorkspaceList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetWorkspaceList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetWorkspaceList(ctx, req.(*GetWorkspaceListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_GetWorkspaceMemberList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkspaceMemberListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetWorkspaceMemberList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetWorkspaceMemberList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetWorkspaceMemberList(ctx, req.(*GetWorkspaceMemberListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_AddWorkspaceMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddWorkspaceMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).AddWorkspaceMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_AddWorkspaceMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).AddWorkspaceMember(ctx, req.(*AddWorkspaceMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_UpdateWorkspaceMemberRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateWorkspaceMemberRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).UpdateWorkspaceMemberRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_UpdateWorkspaceMemberRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).UpdateWorkspaceMemberRole(ctx, req.(*UpdateWorkspaceMemberRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_RemoveWorkspaceMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveWorkspaceMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).RemoveWorkspaceMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_RemoveWorkspaceMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).RemoveWorkspaceMember(ctx, req.(*RemoveWorkspaceMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_CreateWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).CreateWebhook(ctx, req.(*CreateWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_GetWebhookList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetWebhookList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetWebhookList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetWebhookList(ctx, req.(*GetWebhookListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_DeleteWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLoadService_GetWebhookDeliveryList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookDeliveryListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLoadServiceServer).GetWebhookDeliveryList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoLoadService_GetWebhookDeliveryList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLoadServiceServer).GetWebhookDeliveryList(ctx, req.(*GetWebhookDeliveryListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GoLoadService_ServiceDesc is the grpc.ServiceDesc for GoLoadService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -325,6 +971,38 @@ var GoLoadService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateSession",
 			Handler:    _GoLoadService_CreateSession_Handler,
 		},
+		{
+			MethodName: "RefreshSession",
+			Handler:    _GoLoadService_RefreshSession_Handler,
+		},
+		{
+			MethodName: "DeleteSession",
+			Handler:    _GoLoadService_DeleteSession_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _GoLoadService_ChangePassword_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _GoLoadService_ResetPassword_Handler,
+		},
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _GoLoadService_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    _GoLoadService_CreateAPIKey_Handler,
+		},
+		{
+			MethodName: "GetAPIKeyList",
+			Handler:    _GoLoadService_GetAPIKeyList_Handler,
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    _GoLoadService_RevokeAPIKey_Handler,
+		},
 		{
 			MethodName: "CreateDownloadTask",
 			Handler:    _GoLoadService_CreateDownloadTask_Handler,
@@ -341,6 +1019,50 @@ var GoLoadService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteDownloadTask",
 			Handler:    _GoLoadService_DeleteDownloadTask_Handler,
 		},
+		{
+			MethodName: "GetDownloadTaskFileURL",
+			Handler:    _GoLoadService_GetDownloadTaskFileURL_Handler,
+		},
+		{
+			MethodName: "CreateWorkspace",
+			Handler:    _GoLoadService_CreateWorkspace_Handler,
+		},
+		{
+			MethodName: "GetWorkspaceList",
+			Handler:    _GoLoadService_GetWorkspaceList_Handler,
+		},
+		{
+			MethodName: "GetWorkspaceMemberList",
+			Handler:    _GoLoadService_GetWorkspaceMemberList_Handler,
+		},
+		{
+			MethodName: "AddWorkspaceMember",
+			Handler:    _GoLoadService_AddWorkspaceMember_Handler,
+		},
+		{
+			MethodName: "UpdateWorkspaceMemberRole",
+			Handler:    _GoLoadService_UpdateWorkspaceMemberRole_Handler,
+		},
+		{
+			MethodName: "RemoveWorkspaceMember",
+			Handler:    _GoLoadService_RemoveWorkspaceMember_Handler,
+		},
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _GoLoadService_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhookList",
+			Handler:    _GoLoadService_GetWebhookList_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _GoLoadService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhookDeliveryList",
+			Handler:    _GoLoadService_GetWebhookDeliveryList_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -351,3 +1073,375 @@ var GoLoadService_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "api/go_load.proto",
 }
+
+const (
+	AdminService_GetAccountList_FullMethodName           = "/go_load.AdminService/GetAccountList"
+	AdminService_UpdateAccountRole_FullMethodName        = "/go_load.AdminService/UpdateAccountRole"
+	AdminService_DisableAccount_FullMethodName           = "/go_load.AdminService/DisableAccount"
+	AdminService_EnableAccount_FullMethodName            = "/go_load.AdminService/EnableAccount"
+	AdminService_GetAllDownloadTaskList_FullMethodName   = "/go_load.AdminService/GetAllDownloadTaskList"
+	AdminService_RequeueDownloadTask_FullMethodName      = "/go_load.AdminService/RequeueDownloadTask"
+	AdminService_DeleteAnyDownloadTask_FullMethodName    = "/go_load.AdminService/DeleteAnyDownloadTask"
+	AdminService_CreatePasswordResetToken_FullMethodName = "/go_load.AdminService/CreatePasswordResetToken"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService can only be used by accounts with the Admin role.
+type AdminServiceClient interface {
+	GetAccountList(ctx context.Context, in *GetAccountListRequest, opts ...grpc.CallOption) (*GetAccountListResponse, error)
+	UpdateAccountRole(ctx context.Context, in *UpdateAccountRoleRequest, opts ...grpc.CallOption) (*UpdateAccountRoleResponse, error)
+	DisableAccount(ctx context.Context, in *DisableAccountRequest, opts ...grpc.CallOption) (*DisableAccountResponse, error)
+	EnableAccount(ctx context.Context, in *EnableAccountRequest, opts ...grpc.CallOption) (*EnableAccountResponse, error)
+	GetAllDownloadTaskList(ctx context.Context, in *GetAllDownloadTaskListRequest, opts ...grpc.CallOption) (*GetAllDownloadTaskListResponse, error)
+	RequeueDownloadTask(ctx context.Context, in *RequeueDownloadTaskRequest, opts ...grpc.CallOption) (*RequeueDownloadTaskResponse, error)
+	DeleteAnyDownloadTask(ctx context.Context, in *DeleteAnyDownloadTaskRequest, opts ...grpc.CallOption) (*DeleteAnyDownloadTaskResponse, error)
+	CreatePasswordResetToken(ctx context.Context, in *CreatePasswordResetTokenRequest, opts ...grpc.CallOption) (*CreatePasswordResetTokenResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) GetAccountList(ctx context.Context, in *GetAccountListRequest, opts ...grpc.CallOption) (*GetAccountListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAccountListResponse)
+	err := c.cc.Invoke(ctx, AdminService_GetAccountList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) UpdateAccountRole(ctx context.Context, in *UpdateAccountRoleRequest, opts ...grpc.CallOption) (*UpdateAccountRoleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateAccountRoleResponse)
+	err := c.cc.Invoke(ctx, AdminService_UpdateAccountRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DisableAccount(ctx context.Context, in *DisableAccountRequest, opts ...grpc.CallOption) (*DisableAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisableAccountResponse)
+	err := c.cc.Invoke(ctx, AdminService_DisableAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) EnableAccount(ctx context.Context, in *EnableAccountRequest, opts ...grpc.CallOption) (*EnableAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EnableAccountResponse)
+	err := c.cc.Invoke(ctx, AdminService_EnableAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetAllDownloadTaskList(ctx context.Context, in *GetAllDownloadTaskListRequest, opts ...grpc.CallOption) (*GetAllDownloadTaskListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAllDownloadTaskListResponse)
+	err := c.cc.Invoke(ctx, AdminService_GetAllDownloadTaskList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RequeueDownloadTask(ctx context.Context, in *RequeueDownloadTaskRequest, opts ...grpc.CallOption) (*RequeueDownloadTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequeueDownloadTaskResponse)
+	err := c.cc.Invoke(ctx, AdminService_RequeueDownloadTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeleteAnyDownloadTask(ctx context.Context, in *DeleteAnyDownloadTaskRequest, opts ...grpc.CallOption) (*DeleteAnyDownloadTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAnyDownloadTaskResponse)
+	err := c.cc.Invoke(ctx, AdminService_DeleteAnyDownloadTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreatePasswordResetToken(ctx context.Context, in *CreatePasswordResetTokenRequest, opts ...grpc.CallOption) (*CreatePasswordResetTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreatePasswordResetTokenResponse)
+	err := c.cc.Invoke(ctx, AdminService_CreatePasswordResetToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService can only be used by accounts with the Admin role.
+type AdminServiceServer interface {
+	GetAccountList(context.Context, *GetAccountListRequest) (*GetAccountListResponse, error)
+	UpdateAccountRole(context.Context, *UpdateAccountRoleRequest) (*UpdateAccountRoleResponse, error)
+	DisableAccount(context.Context, *DisableAccountRequest) (*DisableAccountResponse, error)
+	EnableAccount(context.Context, *EnableAccountRequest) (*EnableAccountResponse, error)
+	GetAllDownloadTaskList(context.Context, *GetAllDownloadTaskListRequest) (*GetAllDownloadTaskListResponse, error)
+	RequeueDownloadTask(context.Context, *RequeueDownloadTaskRequest) (*RequeueDownloadTaskResponse, error)
+	DeleteAnyDownloadTask(context.Context, *DeleteAnyDownloadTaskRequest) (*DeleteAnyDownloadTaskResponse, error)
+	CreatePasswordResetToken(context.Context, *CreatePasswordResetTokenRequest) (*CreatePasswordResetTokenResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) GetAccountList(context.Context, *GetAccountListRequest) (*GetAccountListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountList not implemented")
+}
+func (UnimplementedAdminServiceServer) UpdateAccountRole(context.Context, *UpdateAccountRoleRequest) (*UpdateAccountRoleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAccountRole not implemented")
+}
+func (UnimplementedAdminServiceServer) DisableAccount(context.Context, *DisableAccountRequest) (*DisableAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableAccount not implemented")
+}
+func (UnimplementedAdminServiceServer) EnableAccount(context.Context, *EnableAccountRequest) (*EnableAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableAccount not implemented")
+}
+func (UnimplementedAdminServiceServer) GetAllDownloadTaskList(context.Context, *GetAllDownloadTaskListRequest) (*GetAllDownloadTaskListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllDownloadTaskList not implemented")
+}
+func (UnimplementedAdminServiceServer) RequeueDownloadTask(context.Context, *RequeueDownloadTaskRequest) (*RequeueDownloadTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequeueDownloadTask not implemented")
+}
+func (UnimplementedAdminServiceServer) DeleteAnyDownloadTask(context.Context, *DeleteAnyDownloadTaskRequest) (*DeleteAnyDownloadTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAnyDownloadTask not implemented")
+}
+func (UnimplementedAdminServiceServer) CreatePasswordResetToken(context.Context, *CreatePasswordResetTokenRequest) (*CreatePasswordResetTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePasswordResetToken not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_GetAccountList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetAccountList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetAccountList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetAccountList(ctx, req.(*GetAccountListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_UpdateAccountRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAccountRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).UpdateAccountRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_UpdateAccountRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).UpdateAccountRole(ctx, req.(*UpdateAccountRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DisableAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DisableAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DisableAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DisableAccount(ctx, req.(*DisableAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_EnableAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).EnableAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_EnableAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).EnableAccount(ctx, req.(*EnableAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetAllDownloadTaskList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllDownloadTaskListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetAllDownloadTaskList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetAllDownloadTaskList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetAllDownloadTaskList(ctx, req.(*GetAllDownloadTaskListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RequeueDownloadTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequeueDownloadTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RequeueDownloadTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RequeueDownloadTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RequeueDownloadTask(ctx, req.(*RequeueDownloadTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeleteAnyDownloadTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAnyDownloadTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeleteAnyDownloadTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DeleteAnyDownloadTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeleteAnyDownloadTask(ctx, req.(*DeleteAnyDownloadTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreatePasswordResetToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePasswordResetTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreatePasswordResetToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_CreatePasswordResetToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreatePasswordResetToken(ctx, req.(*CreatePasswordResetTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "go_load.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAccountList",
+			Handler:    _AdminService_GetAccountList_Handler,
+		},
+		{
+			MethodName: "UpdateAccountRole",
+			Handler:    _AdminService_UpdateAccountRole_Handler,
+		},
+		{
+			MethodName: "DisableAccount",
+			Handler:    _AdminService_DisableAccount_Handler,
+		},
+		{
+			MethodName: "EnableAccount",
+			Handler:    _AdminService_EnableAccount_Handler,
+		},
+		{
+			MethodName: "GetAllDownloadTaskList",
+			Handler:    _AdminService_GetAllDownloadTaskList_Handler,
+		},
+		{
+			MethodName: "RequeueDownloadTask",
+			Handler:    _AdminService_RequeueDownloadTask_Handler,
+		},
+		{
+			MethodName: "DeleteAnyDownloadTask",
+			Handler:    _AdminService_DeleteAnyDownloadTask_Handler,
+		},
+		{
+			MethodName: "CreatePasswordResetToken",
+			Handler:    _AdminService_CreatePasswordResetToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/go_load.proto",
+}
@@ -1,8 +1,10 @@
 package consumers
 
 import (
+	"GoLoad/internal/configs"
 	"GoLoad/internal/dataaccess/mq/consumer"
 	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
 	"context"
 	"encoding/json"
 
@@ -10,31 +12,72 @@ import (
 )
 
 type Root interface {
+	// Start consumes until ctx is cancelled, then waits for the messages being handled. Handlers still running after the
+	// shutdown timeout are interrupted.
 	Start(ctx context.Context) error
 }
 type root struct {
-	downloadTaskCreatedHandler DownloadTaskCreated
-	mqConsumer                 consumer.Consumer
-	logger                     *zap.Logger
+	downloadTaskCreatedHandler        DownloadTaskCreated
+	downloadTaskLifecycleEventHandler DownloadTaskLifecycleEvent
+	mqConsumer                        consumer.Consumer
+	shutdownConfig                    configs.Shutdown
+	logger                            *zap.Logger
 }
 
-func NewRoot(downloadTaskCreatedHandler DownloadTaskCreated, mqConsumer consumer.Consumer, logger *zap.Logger) Root {
+func NewRoot(
+	downloadTaskCreatedHandler DownloadTaskCreated,
+	downloadTaskLifecycleEventHandler DownloadTaskLifecycleEvent,
+	mqConsumer consumer.Consumer,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) Root {
 	return &root{
-		downloadTaskCreatedHandler: downloadTaskCreatedHandler,
-		mqConsumer:                 mqConsumer,
-		logger:                     logger,
+		downloadTaskCreatedHandler:        downloadTaskCreatedHandler,
+		downloadTaskLifecycleEventHandler: downloadTaskLifecycleEventHandler,
+		mqConsumer:                        mqConsumer,
+		shutdownConfig:                    shutdownConfig,
+		logger:                            logger,
+	}
+}
+
+// withInterruption cancels the context of handlerFunc once interruptCtx is done.
+func withInterruption(interruptCtx context.Context, handlerFunc consumer.HandlerFunc) consumer.HandlerFunc {
+	return func(ctx context.Context, queueName string, payload []byte) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(interruptCtx, cancel)()
+		return handlerFunc(ctx, queueName, payload)
 	}
 }
 func (r root) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, r.logger)
+
+	shutdownTimeout, err := r.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	interruptCtx, cancelInterrupt := utils.WithDrainTimeout(ctx, shutdownTimeout)
+	defer cancelInterrupt()
 	r.mqConsumer.RegisterHandler(
 		producer.MessageQueueDownloadTaskCreated,
-		func(ctx context.Context, queueName string, payload []byte) error {
+		withInterruption(interruptCtx, func(ctx context.Context, queueName string, payload []byte) error {
 			var event producer.DownloadTaskCreated
 			if err := json.Unmarshal(payload, &event); err != nil {
 				return err
 			}
 			return r.downloadTaskCreatedHandler.Handle(ctx, event)
-		},
+		}),
+	)
+	r.mqConsumer.RegisterHandler(
+		producer.MessageQueueDownloadTaskLifecycleEvent,
+		withInterruption(interruptCtx, func(ctx context.Context, queueName string, payload []byte) error {
+			var event producer.DownloadTaskLifecycleEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return err
+			}
+			return r.downloadTaskLifecycleEventHandler.Handle(ctx, event)
+		}),
 	)
 	return r.mqConsumer.Start(ctx)
 }
@@ -0,0 +1,36 @@
+package consumers
+
+import (
+	"context"
+
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+type DownloadTaskLifecycleEvent interface {
+	Handle(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error
+}
+type downloadTaskLifecycleEvent struct {
+	webhookLogic logic.Webhook
+	logger       *zap.Logger
+}
+
+func NewDownloadTaskLifecycleEvent(webhookLogic logic.Webhook, logger *zap.Logger) DownloadTaskLifecycleEvent {
+	return &downloadTaskLifecycleEvent{
+		webhookLogic: webhookLogic,
+		logger:       logger,
+	}
+}
+func (d downloadTaskLifecycleEvent) Handle(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Any("event", event))
+	logger.Debug("download task lifecycle event received")
+
+	if err := d.webhookLogic.CreateWebhookDeliveryListOfEvent(ctx, event); err != nil {
+		logger.With(zap.Error(err)).Error("failed to handle download task lifecycle event")
+		return err
+	}
+	return nil
+}
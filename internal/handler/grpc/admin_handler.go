@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/logic"
+	"context"
+)
+
+type AdminHandler struct {
+	go_load.UnimplementedAdminServiceServer
+	adminLogic        logic.Admin
+	downloadTaskLogic logic.DownloadTask
+}
+
+func NewAdminHandler(
+	adminLogic logic.Admin,
+	downloadTaskLogic logic.DownloadTask,
+) go_load.AdminServiceServer {
+	return &AdminHandler{
+		adminLogic:        adminLogic,
+		downloadTaskLogic: downloadTaskLogic,
+	}
+}
+
+// GetAccountList implements go_load.AdminServiceServer.
+func (a *AdminHandler) GetAccountList(ctx context.Context, request *go_load.GetAccountListRequest) (*go_load.GetAccountListResponse, error) {
+	output, err := a.adminLogic.GetAccountList(ctx, logic.GetAccountListParams{
+		Offset: request.GetOffset(),
+		Limit:  request.GetLimit(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetAccountListResponse{
+		AccountList:       output.AccountList,
+		TotalAccountCount: output.TotalAccountCount,
+	}, nil
+}
+
+// UpdateAccountRole implements go_load.AdminServiceServer.
+func (a *AdminHandler) UpdateAccountRole(ctx context.Context, request *go_load.UpdateAccountRoleRequest) (*go_load.UpdateAccountRoleResponse, error) {
+	output, err := a.adminLogic.UpdateAccountRole(ctx, logic.UpdateAccountRoleParams{
+		AccountID: request.GetAccountId(),
+		Role:      request.GetRole(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.UpdateAccountRoleResponse{
+		Account: output.Account,
+	}, nil
+}
+
+// DisableAccount implements go_load.AdminServiceServer.
+func (a *AdminHandler) DisableAccount(ctx context.Context, request *go_load.DisableAccountRequest) (*go_load.DisableAccountResponse, error) {
+	if err := a.adminLogic.DisableAccount(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+	return &go_load.DisableAccountResponse{}, nil
+}
+
+// EnableAccount implements go_load.AdminServiceServer.
+func (a *AdminHandler) EnableAccount(ctx context.Context, request *go_load.EnableAccountRequest) (*go_load.EnableAccountResponse, error) {
+	if err := a.adminLogic.EnableAccount(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+	return &go_load.EnableAccountResponse{}, nil
+}
+
+// GetAllDownloadTaskList implements go_load.AdminServiceServer.
+func (a *AdminHandler) GetAllDownloadTaskList(ctx context.Context, request *go_load.GetAllDownloadTaskListRequest) (*go_load.GetAllDownloadTaskListResponse, error) {
+	output, err := a.downloadTaskLogic.GetAllDownloadTaskList(ctx, logic.GetAllDownloadTaskListParams{
+		Offset: request.GetOffset(),
+		Limit:  request.GetLimit(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetAllDownloadTaskListResponse{
+		DownloadTaskList:       output.DownloadTaskList,
+		TotalDownloadTaskCount: output.TotalDownloadTaskCount,
+	}, nil
+}
+
+// RequeueDownloadTask implements go_load.AdminServiceServer.
+func (a *AdminHandler) RequeueDownloadTask(ctx context.Context, request *go_load.RequeueDownloadTaskRequest) (*go_load.RequeueDownloadTaskResponse, error) {
+	output, err := a.downloadTaskLogic.RequeueDownloadTask(ctx, request.GetDownloadTaskId())
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.RequeueDownloadTaskResponse{
+		DownloadTask: output.DownloadTask,
+	}, nil
+}
+
+// DeleteAnyDownloadTask implements go_load.AdminServiceServer.
+func (a *AdminHandler) DeleteAnyDownloadTask(ctx context.Context, request *go_load.DeleteAnyDownloadTaskRequest) (*go_load.DeleteAnyDownloadTaskResponse, error) {
+	if err := a.downloadTaskLogic.DeleteAnyDownloadTask(ctx, request.GetDownloadTaskId()); err != nil {
+		return nil, err
+	}
+	return &go_load.DeleteAnyDownloadTaskResponse{}, nil
+}
+
+// CreatePasswordResetToken implements go_load.AdminServiceServer.
+func (a *AdminHandler) CreatePasswordResetToken(ctx context.Context, request *go_load.CreatePasswordResetTokenRequest) (*go_load.CreatePasswordResetTokenResponse, error) {
+	output, err := a.adminLogic.CreatePasswordResetToken(ctx, request.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.CreatePasswordResetTokenResponse{
+		ResetToken: output.ResetToken,
+		ExpireTime: output.ExpireTime.Unix(),
+	}, nil
+}
@@ -0,0 +1,147 @@
+package grpc
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/logic"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	adminAndUserAccountRoleList = []go_load.AccountRole{go_load.AccountRole_Admin, go_load.AccountRole_User}
+	adminAccountRoleList        = []go_load.AccountRole{go_load.AccountRole_Admin}
+)
+
+// methodAllowedAccountRoleList maps each method to the account roles allowed to call it. A nil list marks a public
+// method, and methods missing from the map are always denied. Read-only accounts can only manage their own account and
+// sessions besides reading.
+var methodAllowedAccountRoleList = map[string][]go_load.AccountRole{
+	go_load.GoLoadService_CreateAccount_FullMethodName:             nil,
+	go_load.GoLoadService_CreateSession_FullMethodName:             nil,
+	go_load.GoLoadService_RefreshSession_FullMethodName:            nil,
+	go_load.GoLoadService_ResetPassword_FullMethodName:             nil,
+	go_load.GoLoadService_DeleteSession_FullMethodName:             logic.AllAccountRoleList,
+	go_load.GoLoadService_ChangePassword_FullMethodName:            logic.AllAccountRoleList,
+	go_load.GoLoadService_DeleteAccount_FullMethodName:             logic.AllAccountRoleList,
+	go_load.GoLoadService_CreateAPIKey_FullMethodName:              logic.AllAccountRoleList,
+	go_load.GoLoadService_GetAPIKeyList_FullMethodName:             logic.AllAccountRoleList,
+	go_load.GoLoadService_RevokeAPIKey_FullMethodName:              logic.AllAccountRoleList,
+	go_load.GoLoadService_GetDownloadTaskList_FullMethodName:       logic.AllAccountRoleList,
+	go_load.GoLoadService_GetDownloadTaskFile_FullMethodName:       logic.AllAccountRoleList,
+	go_load.GoLoadService_GetDownloadTaskFileURL_FullMethodName:    logic.AllAccountRoleList,
+	go_load.GoLoadService_CreateDownloadTask_FullMethodName:        adminAndUserAccountRoleList,
+	go_load.GoLoadService_UpdateDownloadTask_FullMethodName:        adminAndUserAccountRoleList,
+	go_load.GoLoadService_DeleteDownloadTask_FullMethodName:        adminAndUserAccountRoleList,
+	go_load.GoLoadService_CreateWorkspace_FullMethodName:           adminAndUserAccountRoleList,
+	go_load.GoLoadService_GetWorkspaceList_FullMethodName:          logic.AllAccountRoleList,
+	go_load.GoLoadService_GetWorkspaceMemberList_FullMethodName:    logic.AllAccountRoleList,
+	go_load.GoLoadService_AddWorkspaceMember_FullMethodName:        adminAndUserAccountRoleList,
+	go_load.GoLoadService_UpdateWorkspaceMemberRole_FullMethodName: adminAndUserAccountRoleList,
+	go_load.GoLoadService_RemoveWorkspaceMember_FullMethodName:     adminAndUserAccountRoleList,
+	go_load.GoLoadService_CreateWebhook_FullMethodName:             adminAndUserAccountRoleList,
+	go_load.GoLoadService_GetWebhookList_FullMethodName:            logic.AllAccountRoleList,
+	go_load.GoLoadService_DeleteWebhook_FullMethodName:             adminAndUserAccountRoleList,
+	go_load.GoLoadService_GetWebhookDeliveryList_FullMethodName:    logic.AllAccountRoleList,
+	go_load.AdminService_GetAccountList_FullMethodName:             adminAccountRoleList,
+	go_load.AdminService_UpdateAccountRole_FullMethodName:          adminAccountRoleList,
+	go_load.AdminService_DisableAccount_FullMethodName:             adminAccountRoleList,
+	go_load.AdminService_EnableAccount_FullMethodName:              adminAccountRoleList,
+	go_load.AdminService_GetAllDownloadTaskList_FullMethodName:     adminAccountRoleList,
+	go_load.AdminService_RequeueDownloadTask_FullMethodName:        adminAccountRoleList,
+	go_load.AdminService_DeleteAnyDownloadTask_FullMethodName:      adminAccountRoleList,
+	go_load.AdminService_CreatePasswordResetToken_FullMethodName:   adminAccountRoleList,
+	grpc_health_v1.Health_Check_FullMethodName:                     nil,
+	grpc_health_v1.Health_Watch_FullMethodName:                     nil,
+}
+
+// sessionRequiredMethodSet holds the methods API keys cannot call whatever their scope, so that a leaked key of an
+// administrator cannot be used to administrate the service.
+var sessionRequiredMethodSet = map[string]struct{}{
+	go_load.AdminService_GetAccountList_FullMethodName:           {},
+	go_load.AdminService_UpdateAccountRole_FullMethodName:        {},
+	go_load.AdminService_DisableAccount_FullMethodName:           {},
+	go_load.AdminService_EnableAccount_FullMethodName:            {},
+	go_load.AdminService_GetAllDownloadTaskList_FullMethodName:   {},
+	go_load.AdminService_RequeueDownloadTask_FullMethodName:      {},
+	go_load.AdminService_DeleteAnyDownloadTask_FullMethodName:    {},
+	go_load.AdminService_CreatePasswordResetToken_FullMethodName: {},
+}
+
+var errSessionRequired = status.Error(codes.PermissionDenied, "api keys cannot be used for this operation")
+
+// getAuthTokenMetadata prefers the auth token set from the cookie, falling back to the Authorization bearer token.
+func getAuthTokenMetadata(ctx context.Context) string {
+	metadata, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if metadataValues := metadata.Get(AuthTokenMetadataName); len(metadataValues) > 0 && metadataValues[0] != "" {
+		return metadataValues[0]
+	}
+	for _, authorization := range metadata.Get(AuthorizationMetadataName) {
+		if strings.HasPrefix(authorization, AuthorizationBearerPrefix) {
+			return strings.TrimPrefix(authorization, AuthorizationBearerPrefix)
+		}
+	}
+	return ""
+}
+
+// authenticate validates the token of the request once, returning a context carrying the principal for the logic layer.
+// Public methods are let through without a principal.
+func authenticate(ctx context.Context, authorizationLogic logic.Authorization, fullMethod string) (context.Context, error) {
+	allowedAccountRoleList, ok := methodAllowedAccountRoleList[fullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	if allowedAccountRoleList == nil {
+		return ctx, nil
+	}
+	principal, err := authorizationLogic.Authorize(ctx, getAuthTokenMetadata(ctx), allowedAccountRoleList)
+	if err != nil {
+		return nil, err
+	}
+	if _, sessionRequired := sessionRequiredMethodSet[fullMethod]; sessionRequired && principal.APIKeyID != 0 {
+		return nil, errSessionRequired
+	}
+	return logic.ContextWithPrincipal(ctx, principal), nil
+}
+
+// authenticatedServerStream overrides the context of a stream with the one carrying the principal.
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a authenticatedServerStream) Context() context.Context {
+	return a.ctx
+}
+
+// newAuthUnaryInterceptor rejects unauthenticated requests and requests whose account role is not allowed to call the
+// method.
+func newAuthUnaryInterceptor(authorizationLogic logic.Authorization) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		authenticatedCtx, err := authenticate(ctx, authorizationLogic, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(authenticatedCtx, req)
+	}
+}
+func newAuthStreamInterceptor(authorizationLogic logic.Authorization) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		authenticatedCtx, err := authenticate(ss.Context(), authorizationLogic, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, authenticatedServerStream{
+			ServerStream: ss,
+			ctx:          authenticatedCtx,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// authTokenRenewalSkippedMethods set the auth token header themselves, so they must not be renewed on top of.
+var authTokenRenewalSkippedMethods = map[string]struct{}{
+	go_load.GoLoadService_CreateSession_FullMethodName:  {},
+	go_load.GoLoadService_RefreshSession_FullMethodName: {},
+	go_load.GoLoadService_DeleteSession_FullMethodName:  {},
+	go_load.GoLoadService_DeleteAccount_FullMethodName:  {},
+}
+
+// newAuthTokenRenewalUnaryInterceptor sends back a renewed auth token in the response header when the request's token
+// nears expiry. The gateway turns the header into the auth cookie, giving browser sessions a sliding expiry.
+func newAuthTokenRenewalUnaryInterceptor(tokenLogic logic.Token, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		response, err := handler(ctx, req)
+		if err != nil {
+			return response, err
+		}
+		if _, ok := authTokenRenewalSkippedMethods[info.FullMethod]; ok {
+			return response, nil
+		}
+		incomingMetadata, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return response, nil
+		}
+		authTokenValues := incomingMetadata.Get(AuthTokenMetadataName)
+		if len(authTokenValues) == 0 || authTokenValues[0] == "" {
+			return response, nil
+		}
+		renewedToken, renewed, renewErr := tokenLogic.RenewTokenIfNearExpiry(ctx, authTokenValues[0])
+		if renewErr != nil {
+			utils.LoggerWithContext(ctx, logger).With(zap.Error(renewErr)).Warn("failed to renew auth token")
+			return response, nil
+		}
+		if !renewed {
+			return response, nil
+		}
+		if setHeaderErr := grpc.SetHeader(ctx, metadata.Pairs(AuthTokenMetadataName, renewedToken)); setHeaderErr != nil {
+			utils.LoggerWithContext(ctx, logger).With(zap.Error(setHeaderErr)).Warn("failed to set renewed auth token header")
+		}
+		return response, nil
+	}
+}
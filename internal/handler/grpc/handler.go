@@ -7,45 +7,115 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 const (
 	//nolint:gosec // This is just to specify the metadata name
 	AuthTokenMetadataName = "GOLOAD_AUTH"
+	// AuthorizationMetadataName carries "Bearer <token>", with either a session token or an API key. The HTTP gateway
+	// forwards the Authorization header under this name.
+	AuthorizationMetadataName = "authorization"
+	AuthorizationBearerPrefix = "Bearer "
+	forwardedForMetadataName  = "x-forwarded-for"
 )
 
 type Handler struct {
 	go_load.UnimplementedGoLoadServiceServer
 	accountLogic                                 logic.Account
+	apiKeyLogic                                  logic.APIKey
 	downloadTaskLogic                            logic.DownloadTask
+	workspaceLogic                               logic.Workspace
+	webhookLogic                                 logic.Webhook
 	getDownloadTaskFileResponseBufferSizeInBytes uint64
+	trustedProxyPrefixList                       []netip.Prefix
 }
 
-func NewHandler(accountLogic logic.Account, downloadTaskLogic logic.DownloadTask, grpcConfig configs.GRPC) (go_load.GoLoadServiceServer, error) {
+func NewHandler(
+	accountLogic logic.Account,
+	apiKeyLogic logic.APIKey,
+	downloadTaskLogic logic.DownloadTask,
+	workspaceLogic logic.Workspace,
+	webhookLogic logic.Webhook,
+	grpcConfig configs.GRPC,
+) (go_load.GoLoadServiceServer, error) {
 	getDownloadTaskFileResponseBufferSizeInBytes, err := grpcConfig.GetDownloadTaskFile.GetResponseBufferSizeInBytes()
 	if err != nil {
 		return nil, err
 	}
+	trustedProxyPrefixList, err := grpcConfig.GetTrustedProxyPrefixList()
+	if err != nil {
+		return nil, err
+	}
 	return &Handler{
 		accountLogic:      accountLogic,
+		apiKeyLogic:       apiKeyLogic,
 		downloadTaskLogic: downloadTaskLogic,
+		workspaceLogic:    workspaceLogic,
+		webhookLogic:      webhookLogic,
 		getDownloadTaskFileResponseBufferSizeInBytes: getDownloadTaskFileResponseBufferSizeInBytes,
+		trustedProxyPrefixList:                       trustedProxyPrefixList,
 	}, nil
 }
 
-func (a *Handler) getAuthTokenMetadata(ctx context.Context) string {
+func (a *Handler) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return true
+	}
+	for _, trustedProxyPrefix := range a.trustedProxyPrefixList {
+		if trustedProxyPrefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientIP only honors X-Forwarded-For if the request comes from a trusted proxy, such as the HTTP gateway. Entries
+// are then read from the last one, each added by the proxy the request went through, and the first one not added by a
+// trusted proxy is the client. Earlier entries are set by the client and cannot be trusted.
+func (a *Handler) getClientIP(ctx context.Context) string {
+	peer, ok := peer.FromContext(ctx)
+	if !ok || peer.Addr == nil {
+		return ""
+	}
+	clientIP, _, err := net.SplitHostPort(peer.Addr.String())
+	if err != nil {
+		clientIP = peer.Addr.String()
+	}
+	if !a.isTrustedProxy(clientIP) {
+		return clientIP
+	}
 	metadata, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return ""
+		return clientIP
 	}
-	metadataValues := metadata.Get(AuthTokenMetadataName)
-	if len(metadataValues) == 0 {
-		return ""
+	forwardedForList := make([]string, 0)
+	for _, metadataValue := range metadata.Get(forwardedForMetadataName) {
+		forwardedForList = append(forwardedForList, strings.Split(metadataValue, ",")...)
+	}
+	for i := len(forwardedForList) - 1; i >= 0; i-- {
+		forwardedFor := strings.TrimSpace(forwardedForList[i])
+		if forwardedFor == "" {
+			continue
+		}
+		clientIP = forwardedFor
+		if !a.isTrustedProxy(forwardedFor) {
+			break
+		}
 	}
-	return metadataValues[0]
+	return clientIP
 }
 
 // CreateAccount implements go_load.GoLoadServiceServer.
@@ -65,9 +135,10 @@ func (a *Handler) CreateAccount(ctx context.Context, request *go_load.CreateAcco
 // CreateDownloadTask implements go_load.GoLoadServiceServer.
 func (a *Handler) CreateDownloadTask(ctx context.Context, request *go_load.CreateDownloadTaskRequest) (*go_load.CreateDownloadTaskResponse, error) {
 	output, err := a.downloadTaskLogic.CreateDownloadTask(ctx, logic.CreateDownloadTaskParams{
-		Token:        a.getAuthTokenMetadata(ctx),
 		DownloadType: request.GetDownloadType(),
 		URL:          request.GetUrl(),
+		TTL:          time.Duration(request.GetTtlInSeconds()) * time.Second,
+		WorkspaceID:  request.GetWorkspaceId(),
 	})
 	if err != nil {
 		return nil, err
@@ -82,6 +153,7 @@ func (a *Handler) CreateSession(ctx context.Context, request *go_load.CreateSess
 	output, err := a.accountLogic.CreateSession(ctx, logic.CreateSessionParams{
 		AccountName: request.GetAccountName(),
 		Password:    request.GetPassword(),
+		ClientIP:    a.getClientIP(ctx),
 	})
 	if err != nil {
 		return nil, err
@@ -91,14 +163,116 @@ func (a *Handler) CreateSession(ctx context.Context, request *go_load.CreateSess
 		return nil, err
 	}
 	return &go_load.CreateSessionResponse{
-		Account: output.Account,
+		Account:      output.Account,
+		RefreshToken: output.RefreshToken,
+	}, nil
+}
+
+// RefreshSession implements go_load.GoLoadServiceServer.
+func (a *Handler) RefreshSession(ctx context.Context, request *go_load.RefreshSessionRequest) (*go_load.RefreshSessionResponse, error) {
+	output, err := a.accountLogic.RefreshSession(ctx, logic.RefreshSessionParams{
+		RefreshToken: request.GetRefreshToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = grpc.SetHeader(ctx, metadata.Pairs(AuthTokenMetadataName, output.Token))
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.RefreshSessionResponse{
+		Account:      output.Account,
+		RefreshToken: output.RefreshToken,
+	}, nil
+}
+
+// DeleteSession implements go_load.GoLoadServiceServer.
+func (a *Handler) DeleteSession(ctx context.Context, _ *go_load.DeleteSessionRequest) (*go_load.DeleteSessionResponse, error) {
+	if err := a.accountLogic.DeleteSession(ctx); err != nil {
+		return nil, err
+	}
+	// An empty token tells the gateway to clear the auth cookie.
+	if err := grpc.SetHeader(ctx, metadata.Pairs(AuthTokenMetadataName, "")); err != nil {
+		return nil, err
+	}
+	return &go_load.DeleteSessionResponse{}, nil
+}
+
+// ChangePassword implements go_load.GoLoadServiceServer.
+func (a *Handler) ChangePassword(ctx context.Context, request *go_load.ChangePasswordRequest) (*go_load.ChangePasswordResponse, error) {
+	if err := a.accountLogic.ChangePassword(ctx, logic.ChangePasswordParams{
+		OldPassword: request.GetOldPassword(),
+		NewPassword: request.GetNewPassword(),
+	}); err != nil {
+		return nil, err
+	}
+	return &go_load.ChangePasswordResponse{}, nil
+}
+
+// ResetPassword implements go_load.GoLoadServiceServer.
+func (a *Handler) ResetPassword(ctx context.Context, request *go_load.ResetPasswordRequest) (*go_load.ResetPasswordResponse, error) {
+	if err := a.accountLogic.ResetPassword(ctx, logic.ResetPasswordParams{
+		ResetToken:  request.GetResetToken(),
+		NewPassword: request.GetNewPassword(),
+	}); err != nil {
+		return nil, err
+	}
+	return &go_load.ResetPasswordResponse{}, nil
+}
+
+// DeleteAccount implements go_load.GoLoadServiceServer.
+func (a *Handler) DeleteAccount(ctx context.Context, request *go_load.DeleteAccountRequest) (*go_load.DeleteAccountResponse, error) {
+	if err := a.accountLogic.DeleteAccount(ctx, logic.DeleteAccountParams{
+		Password: request.GetPassword(),
+	}); err != nil {
+		return nil, err
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(AuthTokenMetadataName, "")); err != nil {
+		return nil, err
+	}
+	return &go_load.DeleteAccountResponse{}, nil
+}
+
+// CreateAPIKey implements go_load.GoLoadServiceServer.
+func (a *Handler) CreateAPIKey(ctx context.Context, request *go_load.CreateAPIKeyRequest) (*go_load.CreateAPIKeyResponse, error) {
+	output, err := a.apiKeyLogic.CreateAPIKey(ctx, logic.CreateAPIKeyParams{
+		Name:   request.GetName(),
+		Scopes: request.GetScopes(),
+		TTL:    time.Duration(request.GetTtlInSeconds()) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.CreateAPIKeyResponse{
+		ApiKey: output.APIKey,
+		Key:    output.Key,
 	}, nil
 }
 
+// GetAPIKeyList implements go_load.GoLoadServiceServer.
+func (a *Handler) GetAPIKeyList(ctx context.Context, request *go_load.GetAPIKeyListRequest) (*go_load.GetAPIKeyListResponse, error) {
+	output, err := a.apiKeyLogic.GetAPIKeyList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetAPIKeyListResponse{
+		ApiKeyList: output.APIKeyList,
+	}, nil
+}
+
+// RevokeAPIKey implements go_load.GoLoadServiceServer.
+func (a *Handler) RevokeAPIKey(ctx context.Context, request *go_load.RevokeAPIKeyRequest) (*go_load.RevokeAPIKeyResponse, error) {
+	if err := a.apiKeyLogic.RevokeAPIKey(ctx, logic.RevokeAPIKeyParams{
+		APIKeyID: request.GetApiKeyId(),
+	}); err != nil {
+		return nil, err
+	}
+	return &go_load.RevokeAPIKeyResponse{}, nil
+}
+
 // DeleteDownloadTask implements go_load.GoLoadServiceServer.
 func (a *Handler) DeleteDownloadTask(ctx context.Context, request *go_load.DeleteDownloadTaskRequest) (*go_load.DeleteDownloadTaskResponse, error) {
 	if err := a.downloadTaskLogic.DeleteDownloadTask(ctx, logic.DeleteDownloadTaskParams{
-		Token:          a.getAuthTokenMetadata(ctx),
 		DownloadTaskID: request.GetDownloadTaskId(),
 	}); err != nil {
 		return nil, err
@@ -108,21 +282,32 @@ func (a *Handler) DeleteDownloadTask(ctx context.Context, request *go_load.Delet
 
 // GetDownloadTaskFile implements go_load.GoLoadServiceServer.
 func (a *Handler) GetDownloadTaskFile(request *go_load.GetDownloadTaskFileRequest, server go_load.GoLoadService_GetDownloadTaskFileServer) error {
-	outputReader, err := a.downloadTaskLogic.GetDownloadTaskFile(server.Context(), logic.GetDownloadTaskFileParams{
-		Token:          a.getAuthTokenMetadata(server.Context()),
+	output, err := a.downloadTaskLogic.GetDownloadTaskFile(server.Context(), logic.GetDownloadTaskFileParams{
 		DownloadTaskID: request.GetDownloadTaskId(),
+		Offset:         request.GetOffset(),
+		Length:         request.GetLength(),
 	})
 	if err != nil {
 		return err
 	}
-	defer outputReader.Close()
+	defer output.Reader.Close()
+	// The first response carries the file information so that clients can resume and validate the download.
+	firstResponse := &go_load.GetDownloadTaskFileResponse{
+		TotalSize:   output.FileSize,
+		ContentType: output.ContentType,
+		Checksum:    output.Checksum,
+		FileName:    output.FileName,
+	}
 	for {
 		dataBuffer := make([]byte, a.getDownloadTaskFileResponseBufferSizeInBytes)
-		readByteCount, readErr := outputReader.Read(dataBuffer)
+		readByteCount, readErr := output.Reader.Read(dataBuffer)
 		if readByteCount > 0 {
-			sendErr := server.Send(&go_load.GetDownloadTaskFileResponse{
-				Data: dataBuffer[:readByteCount],
-			})
+			response := &go_load.GetDownloadTaskFileResponse{}
+			if firstResponse != nil {
+				response, firstResponse = firstResponse, nil
+			}
+			response.Data = dataBuffer[:readByteCount]
+			sendErr := server.Send(response)
 			if sendErr != nil {
 				return sendErr
 			}
@@ -135,15 +320,32 @@ func (a *Handler) GetDownloadTaskFile(request *go_load.GetDownloadTaskFileReques
 			return readErr
 		}
 	}
+	if firstResponse != nil {
+		return server.Send(firstResponse)
+	}
 	return nil
 }
 
+// GetDownloadTaskFileURL implements go_load.GoLoadServiceServer.
+func (a *Handler) GetDownloadTaskFileURL(ctx context.Context, request *go_load.GetDownloadTaskFileURLRequest) (*go_load.GetDownloadTaskFileURLResponse, error) {
+	output, err := a.downloadTaskLogic.GetDownloadTaskFileURL(ctx, logic.GetDownloadTaskFileURLParams{
+		DownloadTaskID: request.GetDownloadTaskId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetDownloadTaskFileURLResponse{
+		Url:        output.URL,
+		ExpireTime: output.ExpireTime.Unix(),
+	}, nil
+}
+
 // GetDownloadTaskList implements go_load.GoLoadServiceServer.
 func (a *Handler) GetDownloadTaskList(ctx context.Context, request *go_load.GetDownloadTaskListRequest) (*go_load.GetDownloadTaskListResponse, error) {
 	output, err := a.downloadTaskLogic.GetDownloadTaskList(ctx, logic.GetDownloadTaskListParams{
-		Token:  a.getAuthTokenMetadata(ctx),
-		Offset: request.GetOffset(),
-		Limit:  request.GetLimit(),
+		Offset:      request.GetOffset(),
+		Limit:       request.GetLimit(),
+		WorkspaceID: request.GetWorkspaceId(),
 	})
 	if err != nil {
 		return nil, err
@@ -157,7 +359,6 @@ func (a *Handler) GetDownloadTaskList(ctx context.Context, request *go_load.GetD
 // UpdateDownloadTask implements go_load.GoLoadServiceServer.
 func (a *Handler) UpdateDownloadTask(ctx context.Context, request *go_load.UpdateDownloadTaskRequest) (*go_load.UpdateDownloadTaskResponse, error) {
 	output, err := a.downloadTaskLogic.UpdateDownloadTask(ctx, logic.UpdateDownloadTaskParams{
-		Token:          a.getAuthTokenMetadata(ctx),
 		DownloadTaskID: request.GetDownloadTaskId(),
 		URL:            request.GetUrl(),
 	})
@@ -168,3 +369,134 @@ func (a *Handler) UpdateDownloadTask(ctx context.Context, request *go_load.Updat
 		DownloadTask: output.DownloadTask,
 	}, nil
 }
+
+// CreateWorkspace implements go_load.GoLoadServiceServer.
+func (a *Handler) CreateWorkspace(ctx context.Context, request *go_load.CreateWorkspaceRequest) (*go_load.CreateWorkspaceResponse, error) {
+	output, err := a.workspaceLogic.CreateWorkspace(ctx, logic.CreateWorkspaceParams{
+		WorkspaceName: request.GetWorkspaceName(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.CreateWorkspaceResponse{
+		Workspace: output.Workspace,
+	}, nil
+}
+
+// GetWorkspaceList implements go_load.GoLoadServiceServer.
+func (a *Handler) GetWorkspaceList(ctx context.Context, _ *go_load.GetWorkspaceListRequest) (*go_load.GetWorkspaceListResponse, error) {
+	output, err := a.workspaceLogic.GetWorkspaceList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetWorkspaceListResponse{
+		WorkspaceList: output.WorkspaceList,
+	}, nil
+}
+
+// GetWorkspaceMemberList implements go_load.GoLoadServiceServer.
+func (a *Handler) GetWorkspaceMemberList(ctx context.Context, request *go_load.GetWorkspaceMemberListRequest) (*go_load.GetWorkspaceMemberListResponse, error) {
+	output, err := a.workspaceLogic.GetWorkspaceMemberList(ctx, logic.GetWorkspaceMemberListParams{
+		WorkspaceID: request.GetWorkspaceId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetWorkspaceMemberListResponse{
+		WorkspaceMemberList: output.WorkspaceMemberList,
+	}, nil
+}
+
+// AddWorkspaceMember implements go_load.GoLoadServiceServer.
+func (a *Handler) AddWorkspaceMember(ctx context.Context, request *go_load.AddWorkspaceMemberRequest) (*go_load.AddWorkspaceMemberResponse, error) {
+	output, err := a.workspaceLogic.AddWorkspaceMember(ctx, logic.AddWorkspaceMemberParams{
+		WorkspaceID: request.GetWorkspaceId(),
+		AccountName: request.GetAccountName(),
+		Role:        request.GetRole(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.AddWorkspaceMemberResponse{
+		WorkspaceMember: output.WorkspaceMember,
+	}, nil
+}
+
+// UpdateWorkspaceMemberRole implements go_load.GoLoadServiceServer.
+func (a *Handler) UpdateWorkspaceMemberRole(ctx context.Context, request *go_load.UpdateWorkspaceMemberRoleRequest) (*go_load.UpdateWorkspaceMemberRoleResponse, error) {
+	err := a.workspaceLogic.UpdateWorkspaceMemberRole(ctx, logic.UpdateWorkspaceMemberRoleParams{
+		WorkspaceID: request.GetWorkspaceId(),
+		AccountID:   request.GetAccountId(),
+		Role:        request.GetRole(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.UpdateWorkspaceMemberRoleResponse{}, nil
+}
+
+// RemoveWorkspaceMember implements go_load.GoLoadServiceServer.
+func (a *Handler) RemoveWorkspaceMember(ctx context.Context, request *go_load.RemoveWorkspaceMemberRequest) (*go_load.RemoveWorkspaceMemberResponse, error) {
+	err := a.workspaceLogic.RemoveWorkspaceMember(ctx, logic.RemoveWorkspaceMemberParams{
+		WorkspaceID: request.GetWorkspaceId(),
+		AccountID:   request.GetAccountId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.RemoveWorkspaceMemberResponse{}, nil
+}
+
+// CreateWebhook implements go_load.GoLoadServiceServer.
+func (a *Handler) CreateWebhook(ctx context.Context, request *go_load.CreateWebhookRequest) (*go_load.CreateWebhookResponse, error) {
+	output, err := a.webhookLogic.CreateWebhook(ctx, logic.CreateWebhookParams{
+		URL:            request.GetUrl(),
+		Secret:         request.GetSecret(),
+		DownloadTaskID: request.GetDownloadTaskId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.CreateWebhookResponse{
+		Webhook: output.Webhook,
+		Secret:  output.Secret,
+	}, nil
+}
+
+// GetWebhookList implements go_load.GoLoadServiceServer.
+func (a *Handler) GetWebhookList(ctx context.Context, request *go_load.GetWebhookListRequest) (*go_load.GetWebhookListResponse, error) {
+	output, err := a.webhookLogic.GetWebhookList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetWebhookListResponse{
+		WebhookList: output.WebhookList,
+	}, nil
+}
+
+// DeleteWebhook implements go_load.GoLoadServiceServer.
+func (a *Handler) DeleteWebhook(ctx context.Context, request *go_load.DeleteWebhookRequest) (*go_load.DeleteWebhookResponse, error) {
+	err := a.webhookLogic.DeleteWebhook(ctx, logic.DeleteWebhookParams{
+		WebhookID: request.GetWebhookId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.DeleteWebhookResponse{}, nil
+}
+
+// GetWebhookDeliveryList implements go_load.GoLoadServiceServer.
+func (a *Handler) GetWebhookDeliveryList(ctx context.Context, request *go_load.GetWebhookDeliveryListRequest) (*go_load.GetWebhookDeliveryListResponse, error) {
+	output, err := a.webhookLogic.GetWebhookDeliveryList(ctx, logic.GetWebhookDeliveryListParams{
+		WebhookID: request.GetWebhookId(),
+		Offset:    request.GetOffset(),
+		Limit:     request.GetLimit(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &go_load.GetWebhookDeliveryListResponse{
+		WebhookDeliveryList:       output.WebhookDeliveryList,
+		TotalWebhookDeliveryCount: output.TotalWebhookDeliveryCount,
+	}, nil
+}
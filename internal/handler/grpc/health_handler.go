@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthWatchInterval is how often the health of a watched service is checked again.
+const healthWatchInterval = 5 * time.Second
+
+// HealthHandler implements the standard gRPC health service. The empty service name reports the readiness of the
+// instance, and each dependency name the health of that dependency.
+type HealthHandler struct {
+	grpc_health_v1.UnimplementedHealthServer
+	healthLogic logic.Health
+}
+
+func NewHealthHandler(healthLogic logic.Health) grpc_health_v1.HealthServer {
+	return &HealthHandler{
+		healthLogic: healthLogic,
+	}
+}
+func (h *HealthHandler) getServingStatus(ctx context.Context, service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, error) {
+	if service == "" {
+		if h.healthLogic.GetReadiness(ctx).Ready {
+			return grpc_health_v1.HealthCheckResponse_SERVING, nil
+		}
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, nil
+	}
+	dependencyHealth, err := h.healthLogic.GetDependencyHealth(ctx, service)
+	if err != nil {
+		return grpc_health_v1.HealthCheckResponse_UNKNOWN, err
+	}
+	if dependencyHealth.Healthy {
+		return grpc_health_v1.HealthCheckResponse_SERVING, nil
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING, nil
+}
+
+// Check implements grpc_health_v1.HealthServer.
+func (h *HealthHandler) Check(ctx context.Context, request *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	servingStatus, err := h.getServingStatus(ctx, request.GetService())
+	if err != nil {
+		return nil, err
+	}
+	return &grpc_health_v1.HealthCheckResponse{
+		Status: servingStatus,
+	}, nil
+}
+
+// Watch implements grpc_health_v1.HealthServer. It sends the status of the service right away, then each time it
+// changes, until the client cancels the stream.
+func (h *HealthHandler) Watch(request *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+	lastServingStatus := grpc_health_v1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		servingStatus, err := h.getServingStatus(ctx, request.GetService())
+		if err != nil {
+			servingStatus = grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if servingStatus != lastServingStatus {
+			if err = stream.Send(&grpc_health_v1.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			lastServingStatus = servingStatus
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,35 +3,64 @@ package grpc
 import (
 	"context"
 	"net"
+	"time"
 
 	"GoLoad/internal/configs"
 	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/logic"
 	"GoLoad/internal/utils"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Server interface {
+	// Start serves until ctx is cancelled, then stops accepting requests and waits for the running ones to finish, up to
+	// the shutdown timeout after which they are aborted.
 	Start(ctx context.Context) error
 }
 type server struct {
-	handler    go_load.GoLoadServiceServer
-	grpcConfig configs.GRPC
-	logger     *zap.Logger
+	handler            go_load.GoLoadServiceServer
+	adminHandler       go_load.AdminServiceServer
+	healthHandler      grpc_health_v1.HealthServer
+	tokenLogic         logic.Token
+	authorizationLogic logic.Authorization
+	grpcConfig         configs.GRPC
+	shutdownConfig     configs.Shutdown
+	logger             *zap.Logger
 }
 
-func NewServer(handler go_load.GoLoadServiceServer, grpcConfig configs.GRPC, logger *zap.Logger) Server {
+func NewServer(
+	handler go_load.GoLoadServiceServer,
+	adminHandler go_load.AdminServiceServer,
+	healthHandler grpc_health_v1.HealthServer,
+	tokenLogic logic.Token,
+	authorizationLogic logic.Authorization,
+	grpcConfig configs.GRPC,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) Server {
 	return &server{
-		handler:    handler,
-		grpcConfig: grpcConfig,
-		logger:     logger,
+		handler:            handler,
+		adminHandler:       adminHandler,
+		healthHandler:      healthHandler,
+		tokenLogic:         tokenLogic,
+		authorizationLogic: authorizationLogic,
+		grpcConfig:         grpcConfig,
+		shutdownConfig:     shutdownConfig,
+		logger:             logger,
 	}
 }
 func (s *server) Start(ctx context.Context) error {
 	logger := utils.LoggerWithContext(ctx, s.logger)
 
+	shutdownTimeout, err := s.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
 	listener, err := net.Listen("tcp", s.grpcConfig.Address)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to open tcp listener")
@@ -42,13 +71,40 @@ func (s *server) Start(ctx context.Context) error {
 	server := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
 			validator.UnaryServerInterceptor(),
+			newAuthUnaryInterceptor(s.authorizationLogic),
+			newAuthTokenRenewalUnaryInterceptor(s.tokenLogic, s.logger),
 		),
 		grpc.ChainStreamInterceptor(
 			validator.StreamServerInterceptor(),
+			newAuthStreamInterceptor(s.authorizationLogic),
 		),
 	)
 	go_load.RegisterGoLoadServiceServer(server, s.handler)
+	go_load.RegisterAdminServiceServer(server, s.adminHandler)
+	grpc_health_v1.RegisterHealthServer(server, s.healthHandler)
 
 	logger.With(zap.String("address", s.grpcConfig.Address)).Info("starting grpc server")
-	return server.Serve(listener)
+	serveErrChannel := make(chan error, 1)
+	go func() {
+		serveErrChannel <- server.Serve(listener)
+	}()
+	select {
+	case err = <-serveErrChannel:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Info("stopping grpc server")
+	stoppedChannel := make(chan struct{})
+	go func() {
+		defer close(stoppedChannel)
+		server.GracefulStop()
+	}()
+	select {
+	case <-stoppedChannel:
+	case <-time.After(shutdownTimeout):
+		logger.Warn("grpc server did not stop in time, aborting running requests")
+		server.Stop()
+		<-stoppedChannel
+	}
+	return <-serveErrChannel
 }
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	handlerGRPC "GoLoad/internal/handler/grpc"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	downloadTaskFilePathPattern      = "/download-task-files/{download_task_id}"
+	downloadTaskFilePathParamTaskID  = "download_task_id"
+	responseHeaderContentType        = "Content-Type"
+	responseHeaderContentDisposition = "Content-Disposition"
+	responseHeaderETag               = "ETag"
+	responseHeaderFinalURL           = "X-Final-Url"
+	responseHeaderAcceptRanges       = "Accept-Ranges"
+	authorizationHeader              = "Authorization"
+)
+
+type DownloadTaskFile interface {
+	Handle(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+type downloadTaskFile struct {
+	downloadTaskLogic  logic.DownloadTask
+	authorizationLogic logic.Authorization
+	logger             *zap.Logger
+}
+
+func NewDownloadTaskFile(
+	downloadTaskLogic logic.DownloadTask,
+	authorizationLogic logic.Authorization,
+	logger *zap.Logger,
+) DownloadTaskFile {
+	return &downloadTaskFile{
+		downloadTaskLogic:  downloadTaskLogic,
+		authorizationLogic: authorizationLogic,
+		logger:             logger,
+	}
+}
+func (d downloadTaskFile) getDownloadTaskFile(
+	ctx context.Context,
+	r *http.Request,
+	downloadTaskID uint64,
+) (logic.GetDownloadTaskFileOutput, error) {
+	query := r.URL.Query()
+	if query.Has(logic.DownloadTaskFileURLQuerySignature) {
+		expireTime, err := strconv.ParseInt(query.Get(logic.DownloadTaskFileURLQueryExpireTime), 10, 64)
+		if err != nil {
+			return logic.GetDownloadTaskFileOutput{}, status.Error(codes.InvalidArgument, "invalid expire time")
+		}
+		return d.downloadTaskLogic.GetDownloadTaskFileWithSignature(ctx, logic.GetDownloadTaskFileWithSignatureParams{
+			DownloadTaskID: downloadTaskID,
+			ExpireTime:     expireTime,
+			Signature:      query.Get(logic.DownloadTaskFileURLQuerySignature),
+		})
+	}
+	token := ""
+	if cookie, err := r.Cookie(AuthTokenCookieName); err == nil {
+		token = cookie.Value
+	} else if bearerToken, ok := strings.CutPrefix(r.Header.Get(authorizationHeader), handlerGRPC.AuthorizationBearerPrefix); ok {
+		token = bearerToken
+	}
+	// This path does not go through the gRPC server, so its auth interceptor has to be applied here.
+	principal, err := d.authorizationLogic.Authorize(ctx, token, logic.AllAccountRoleList)
+	if err != nil {
+		return logic.GetDownloadTaskFileOutput{}, err
+	}
+	return d.downloadTaskLogic.GetDownloadTaskFile(logic.ContextWithPrincipal(ctx, principal), logic.GetDownloadTaskFileParams{
+		DownloadTaskID: downloadTaskID,
+	})
+}
+func (d downloadTaskFile) Handle(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	ctx := r.Context()
+	logger := utils.LoggerWithContext(ctx, d.logger)
+
+	downloadTaskID, err := strconv.ParseUint(pathParams[downloadTaskFilePathParamTaskID], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid download task id", http.StatusBadRequest)
+		return
+	}
+	output, err := d.getDownloadTaskFile(ctx, r, downloadTaskID)
+	if err != nil {
+		errStatus := status.Convert(err)
+		http.Error(w, errStatus.Message(), runtime.HTTPStatusFromCode(errStatus.Code()))
+		return
+	}
+	defer output.Reader.Close()
+
+	if output.ContentType != "" {
+		w.Header().Set(responseHeaderContentType, output.ContentType)
+	}
+	w.Header().Set(
+		responseHeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": output.FileName}),
+	)
+	if output.Checksum != "" {
+		w.Header().Set(responseHeaderETag, fmt.Sprintf("%q", output.Checksum))
+	}
+	if output.FinalURL != "" {
+		w.Header().Set(responseHeaderFinalURL, output.FinalURL)
+	}
+	logger.With(zap.Uint64("download_task_id", downloadTaskID)).Info("serving download task file")
+	if !output.FileSizeKnown {
+		// Range requests cannot be answered without the size of the file, so it is served whole.
+		w.Header().Set(responseHeaderAcceptRanges, "none")
+		w.WriteHeader(http.StatusOK)
+		if _, err = io.Copy(w, output.Reader); err != nil {
+			logger.With(zap.Error(err)).Warn("failed to write download task file")
+		}
+		return
+	}
+	http.ServeContent(w, r, output.FileName, time.Time{}, output.Reader)
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	livenessPathPattern  = "/healthz"
+	readinessPathPattern = "/readyz"
+)
+
+type dependencyHealthResponse struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+type readinessResponse struct {
+	Ready        bool                       `json:"ready"`
+	ShuttingDown bool                       `json:"shutting_down"`
+	Dependencies []dependencyHealthResponse `json:"dependencies"`
+}
+
+// Health serves the probes of the orchestrator. Liveness does not check the dependencies, so that an outage of one of
+// them makes the instances stop receiving requests instead of being restarted.
+type Health interface {
+	HandleLiveness(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+	HandleReadiness(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+type health struct {
+	healthLogic logic.Health
+	logger      *zap.Logger
+}
+
+func NewHealth(healthLogic logic.Health, logger *zap.Logger) Health {
+	return &health{
+		healthLogic: healthLogic,
+		logger:      logger,
+	}
+}
+func (h health) writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, response any) {
+	logger := utils.LoggerWithContext(r.Context(), h.logger)
+
+	w.Header().Set(responseHeaderContentType, "application/json")
+	w.Header().Set(responseHeaderCacheControl, "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.With(zap.Error(err)).Error("failed to write health response")
+	}
+}
+func (h health) HandleLiveness(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	h.writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+func (h health) HandleReadiness(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	output := h.healthLogic.GetReadiness(r.Context())
+	response := readinessResponse{
+		Ready:        output.Ready,
+		ShuttingDown: output.ShuttingDown,
+		Dependencies: make([]dependencyHealthResponse, 0, len(output.DependencyHealthList)),
+	}
+	for _, dependencyHealth := range output.DependencyHealthList {
+		response.Dependencies = append(response.Dependencies, dependencyHealthResponse{
+			Name:    dependencyHealth.Name,
+			Healthy: dependencyHealth.Healthy,
+			Error:   dependencyHealth.Error,
+		})
+	}
+	statusCode := http.StatusOK
+	if !output.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	h.writeJSON(w, r, statusCode, response)
+}
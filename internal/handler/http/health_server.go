@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// HealthServer serves the liveness and readiness probes of the commands without an HTTP server of their own.
+type HealthServer interface {
+	// Start serves until ctx is cancelled, then stops accepting requests and waits for the running ones to finish, up to
+	// the shutdown timeout after which they are aborted.
+	Start(ctx context.Context) error
+}
+type healthServer struct {
+	healthHandler  Health
+	healthConfig   configs.Health
+	shutdownConfig configs.Shutdown
+	logger         *zap.Logger
+}
+
+func NewHealthServer(
+	healthHandler Health,
+	healthConfig configs.Health,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) HealthServer {
+	return &healthServer{
+		healthHandler:  healthHandler,
+		healthConfig:   healthConfig,
+		shutdownConfig: shutdownConfig,
+		logger:         logger,
+	}
+}
+func (h healthServer) getHandler() http.Handler {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(http.MethodGet+" "+livenessPathPattern, func(w http.ResponseWriter, r *http.Request) {
+		h.healthHandler.HandleLiveness(w, r, nil)
+	})
+	serveMux.HandleFunc(http.MethodGet+" "+readinessPathPattern, func(w http.ResponseWriter, r *http.Request) {
+		h.healthHandler.HandleReadiness(w, r, nil)
+	})
+	return serveMux
+}
+func (h healthServer) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, h.logger)
+
+	shutdownTimeout, err := h.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	return listenAndServeUntilDone(ctx, "health", &http.Server{
+		Addr:              h.healthConfig.GetAddress(),
+		ReadHeaderTimeout: time.Minute,
+		Handler:           h.getHandler(),
+	}, shutdownTimeout, logger)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	jwksPathPattern            = "/.well-known/jwks.json"
+	responseHeaderCacheControl = "Cache-Control"
+	// jwksCacheControl lets verifiers cache the key set for less time than the key reload interval of signers.
+	jwksCacheControl = "public, max-age=60"
+)
+
+type JWKS interface {
+	Handle(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+type jwks struct {
+	tokenLogic logic.Token
+	logger     *zap.Logger
+}
+
+func NewJWKS(tokenLogic logic.Token, logger *zap.Logger) JWKS {
+	return &jwks{
+		tokenLogic: tokenLogic,
+		logger:     logger,
+	}
+}
+func (j jwks) Handle(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ctx := r.Context()
+	logger := utils.LoggerWithContext(ctx, j.logger)
+
+	jsonWebKeySet, err := j.tokenLogic.GetJSONWebKeySet(ctx)
+	if err != nil {
+		errStatus := status.Convert(err)
+		http.Error(w, errStatus.Message(), runtime.HTTPStatusFromCode(errStatus.Code()))
+		return
+	}
+	w.Header().Set(responseHeaderContentType, "application/json")
+	w.Header().Set(responseHeaderCacheControl, jwksCacheControl)
+	if err = json.NewEncoder(w).Encode(jsonWebKeySet); err != nil {
+		logger.With(zap.Error(err)).Error("failed to write json web key set")
+	}
+}
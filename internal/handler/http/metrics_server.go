@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	metricsPathPattern = "/metrics"
+)
+
+// MetricsServer serves the Prometheus metrics of the instance on a listener of its own, since they are not meant for
+// the clients of the public HTTP server.
+type MetricsServer interface {
+	// Start serves until ctx is cancelled, then stops accepting requests and waits for the running ones to finish, up to
+	// the shutdown timeout after which they are aborted.
+	Start(ctx context.Context) error
+}
+type metricsServer struct {
+	metricsConfig  configs.Metrics
+	shutdownConfig configs.Shutdown
+	logger         *zap.Logger
+}
+
+func NewMetricsServer(
+	metricsConfig configs.Metrics,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) MetricsServer {
+	return &metricsServer{
+		metricsConfig:  metricsConfig,
+		shutdownConfig: shutdownConfig,
+		logger:         logger,
+	}
+}
+func (m metricsServer) Start(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, m.logger)
+
+	shutdownTimeout, err := m.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	serveMux := http.NewServeMux()
+	serveMux.Handle(http.MethodGet+" "+metricsPathPattern, promhttp.Handler())
+	return listenAndServeUntilDone(ctx, "metrics", &http.Server{
+		Addr:              m.metricsConfig.GetAddress(),
+		ReadHeaderTimeout: time.Minute,
+		Handler:           serveMux,
+	}, shutdownTimeout, logger)
+}
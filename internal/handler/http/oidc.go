@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/logic"
+	"GoLoad/internal/utils"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	oidcLoginPathPattern    = "/auth/oidc/login"
+	oidcCallbackPathPattern = "/auth/oidc/callback"
+	oidcCookiePath          = "/auth/oidc"
+	//nolint:gosec // This is just to specify the cookie name
+	oidcStateCookieName      = "GOLOAD_OIDC_STATE"
+	oidcCallbackQueryState   = "state"
+	oidcCallbackQueryCode    = "code"
+	oidcCallbackQueryError   = "error"
+	defaultPostLoginRedirect = "/"
+)
+
+// OIDC serves the browser side of OIDC login. The state is also kept in a cookie, tying the callback to the browser
+// that started the login.
+type OIDC interface {
+	HandleLogin(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+	HandleCallback(w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+type oidcHandler struct {
+	oidcLogic  logic.OIDC
+	authConfig configs.Auth
+	logger     *zap.Logger
+}
+
+func NewOIDC(oidcLogic logic.OIDC, authConfig configs.Auth, logger *zap.Logger) OIDC {
+	return &oidcHandler{
+		oidcLogic:  oidcLogic,
+		authConfig: authConfig,
+		logger:     logger,
+	}
+}
+func (o oidcHandler) writeError(w http.ResponseWriter, err error) {
+	errStatus := status.Convert(err)
+	http.Error(w, errStatus.Message(), runtime.HTTPStatusFromCode(errStatus.Code()))
+}
+func (o oidcHandler) HandleLogin(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	output, err := o.oidcLogic.StartOIDCLogin(r.Context())
+	if err != nil {
+		o.writeError(w, err)
+		return
+	}
+	// The callback is a cross-site navigation from the identity provider, so the state cookie must be SameSite=Lax.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    output.State,
+		Path:     oidcCookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  output.ExpireTime,
+	})
+	http.Redirect(w, r, output.AuthCodeURL, http.StatusFound)
+}
+func (o oidcHandler) HandleCallback(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ctx := r.Context()
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	query := r.URL.Query()
+	if query.Has(oidcCallbackQueryError) {
+		logger.With(zap.String("error", query.Get(oidcCallbackQueryError))).Warn("identity provider returned an error")
+		http.Error(w, "login with identity provider failed", http.StatusUnauthorized)
+		return
+	}
+	expectedState := ""
+	if cookie, err := r.Cookie(oidcStateCookieName); err == nil {
+		expectedState = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Path:     oidcCookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	output, err := o.oidcLogic.FinishOIDCLogin(ctx, logic.FinishOIDCLoginParams{
+		State:         query.Get(oidcCallbackQueryState),
+		ExpectedState: expectedState,
+		Code:          query.Get(oidcCallbackQueryCode),
+	})
+	if err != nil {
+		o.writeError(w, err)
+		return
+	}
+	tokenExpiresIn, err := o.authConfig.Token.GetExpiresInDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse token expires_in")
+		http.Error(w, "failed to parse token expires_in", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthTokenCookieName,
+		Value:    output.Token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(tokenExpiresIn),
+	})
+	postLoginRedirectURL := o.authConfig.OIDC.PostLoginRedirectURL
+	if postLoginRedirectURL == "" {
+		postLoginRedirectURL = defaultPostLoginRedirect
+	}
+	http.Redirect(w, r, postLoginRedirectURL, http.StatusFound)
+}
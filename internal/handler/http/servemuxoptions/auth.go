@@ -31,9 +31,20 @@ func WithAuthMetadataToAuthCookie(authMetadataName, authCookieName string, expir
 		if len(authMetadataValues) == 0 {
 			return nil
 		}
+		if authMetadataValues[0] == "" {
+			http.SetCookie(w, &http.Cookie{
+				Name:     authCookieName,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+				MaxAge:   -1,
+			})
+			return nil
+		}
 		http.SetCookie(w, &http.Cookie{
 			Name:     authCookieName,
 			Value:    authMetadataValues[0],
+			Path:     "/",
 			HttpOnly: true,
 			SameSite: http.SameSiteStrictMode,
 			Expires:  time.Now().Add(expiresInDuration),
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -24,21 +25,43 @@ const (
 )
 
 type Server interface {
+	// Start serves until ctx is cancelled, then stops accepting requests and waits for the running ones to finish, up to
+	// the shutdown timeout after which they are aborted.
 	Start(ctx context.Context) error
 }
 type server struct {
-	grpcConfig configs.GRPC
-	httpConfig configs.HTTP
-	authConfig configs.Auth
-	logger     *zap.Logger
+	downloadTaskFileHandler DownloadTaskFile
+	jwksHandler             JWKS
+	oidcHandler             OIDC
+	healthHandler           Health
+	grpcConfig              configs.GRPC
+	httpConfig              configs.HTTP
+	authConfig              configs.Auth
+	shutdownConfig          configs.Shutdown
+	logger                  *zap.Logger
 }
 
-func NewServer(grpcConfig configs.GRPC, httpConfig configs.HTTP, authConfig configs.Auth, logger *zap.Logger) Server {
+func NewServer(
+	downloadTaskFileHandler DownloadTaskFile,
+	jwksHandler JWKS,
+	oidcHandler OIDC,
+	healthHandler Health,
+	grpcConfig configs.GRPC,
+	httpConfig configs.HTTP,
+	authConfig configs.Auth,
+	shutdownConfig configs.Shutdown,
+	logger *zap.Logger,
+) Server {
 	return &server{
-		grpcConfig: grpcConfig,
-		httpConfig: httpConfig,
-		authConfig: authConfig,
-		logger:     logger,
+		downloadTaskFileHandler: downloadTaskFileHandler,
+		jwksHandler:             jwksHandler,
+		oidcHandler:             oidcHandler,
+		healthHandler:           healthHandler,
+		grpcConfig:              grpcConfig,
+		httpConfig:              httpConfig,
+		authConfig:              authConfig,
+		shutdownConfig:          shutdownConfig,
+		logger:                  logger,
 	}
 }
 func (s server) getGRPCGatewayHandler(ctx context.Context) (http.Handler, error) {
@@ -62,21 +85,93 @@ func (s server) getGRPCGatewayHandler(ctx context.Context) (http.Handler, error)
 	if err != nil {
 		return nil, err
 	}
+	err = go_load.RegisterAdminServiceHandlerFromEndpoint(
+		ctx,
+		grpcMux,
+		s.grpcConfig.Address,
+		[]grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		})
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, downloadTaskFilePathPattern, s.downloadTaskFileHandler.Handle)
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, jwksPathPattern, s.jwksHandler.Handle)
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, oidcLoginPathPattern, s.oidcHandler.HandleLogin)
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, oidcCallbackPathPattern, s.oidcHandler.HandleCallback)
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, livenessPathPattern, s.healthHandler.HandleLiveness)
+	if err != nil {
+		return nil, err
+	}
+	err = grpcMux.HandlePath(http.MethodGet, readinessPathPattern, s.healthHandler.HandleReadiness)
+	if err != nil {
+		return nil, err
+	}
 	return grpcMux, nil
 }
 func (s server) Start(ctx context.Context) error {
 	logger := utils.LoggerWithContext(ctx, s.logger)
 
-	grpcGatewayHandler, err := s.getGRPCGatewayHandler(ctx)
+	shutdownTimeout, err := s.shutdownConfig.GetTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse shutdown timeout")
+		return err
+	}
+	// The connections of the gateway to the gRPC server are closed along with gatewayCtx, so they must outlive ctx
+	// until the running requests are drained.
+	gatewayCtx, cancelGateway := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelGateway()
+	grpcGatewayHandler, err := s.getGRPCGatewayHandler(gatewayCtx)
 	if err != nil {
 		return err
 	}
-	httpServer := http.Server{
+	return listenAndServeUntilDone(ctx, "http", &http.Server{
 		Addr:              s.httpConfig.Address,
 		ReadHeaderTimeout: time.Minute,
 		Handler:           grpcGatewayHandler,
-	}
+	}, shutdownTimeout, logger)
+}
 
-	logger.With(zap.String("address", s.httpConfig.Address)).Info("starting http server")
-	return httpServer.ListenAndServe()
+// listenAndServeUntilDone serves until ctx is cancelled, then stops accepting requests and waits for the running ones
+// to finish, up to shutdownTimeout after which they are aborted.
+func listenAndServeUntilDone(
+	ctx context.Context,
+	serverName string,
+	httpServer *http.Server,
+	shutdownTimeout time.Duration,
+	logger *zap.Logger,
+) error {
+	logger.With(zap.String("address", httpServer.Addr)).Info("starting " + serverName + " server")
+	serveErrChannel := make(chan error, 1)
+	go func() {
+		serveErrChannel <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-serveErrChannel:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Info("stopping " + serverName + " server")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.With(zap.Error(err)).Warn(serverName + " server did not stop in time, aborting running requests")
+		_ = httpServer.Close()
+	}
+	if err := <-serveErrChannel; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
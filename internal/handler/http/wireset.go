@@ -3,5 +3,11 @@ package http
 import "github.com/google/wire"
 
 var WireSet = wire.NewSet(
+	NewDownloadTaskFile,
+	NewJWKS,
+	NewOIDC,
+	NewHealth,
 	NewServer,
+	NewHealthServer,
+	NewMetricsServer,
 )
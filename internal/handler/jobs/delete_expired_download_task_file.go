@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+)
+
+type DeleteExpiredDownloadTaskFile interface {
+	Run(context.Context) error
+}
+type deleteExpiredDownloadTaskFile struct {
+	downloadTaskLogic logic.DownloadTask
+}
+
+func NewDeleteExpiredDownloadTaskFile(downloadTaskLogic logic.DownloadTask) DeleteExpiredDownloadTaskFile {
+	return &deleteExpiredDownloadTaskFile{
+		downloadTaskLogic: downloadTaskLogic,
+	}
+}
+func (d deleteExpiredDownloadTaskFile) Run(ctx context.Context) error {
+	return d.downloadTaskLogic.DeleteExpiredDownloadTaskFile(ctx)
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+)
+
+type DeliverWebhooks interface {
+	Run(context.Context) error
+}
+type deliverWebhooks struct {
+	webhookLogic logic.Webhook
+}
+
+func NewDeliverWebhooks(webhookLogic logic.Webhook) DeliverWebhooks {
+	return &deliverWebhooks{
+		webhookLogic: webhookLogic,
+	}
+}
+func (d deliverWebhooks) Run(ctx context.Context) error {
+	return d.webhookLogic.DeliverPendingWebhookDeliveries(ctx)
+}
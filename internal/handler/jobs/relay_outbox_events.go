@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+)
+
+type RelayOutboxEvents interface {
+	Run(context.Context) error
+}
+type relayOutboxEvents struct {
+	outboxLogic logic.Outbox
+}
+
+func NewRelayOutboxEvents(outboxLogic logic.Outbox) RelayOutboxEvents {
+	return &relayOutboxEvents{
+		outboxLogic: outboxLogic,
+	}
+}
+func (r relayOutboxEvents) Run(ctx context.Context) error {
+	return r.outboxLogic.RelayPendingEvents(ctx)
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"GoLoad/internal/logic"
+	"context"
+)
+
+type RotateTokenSigningKey interface {
+	Run(context.Context) error
+}
+type rotateTokenSigningKey struct {
+	tokenLogic logic.Token
+}
+
+func NewRotateTokenSigningKey(tokenLogic logic.Token) RotateTokenSigningKey {
+	return &rotateTokenSigningKey{
+		tokenLogic: tokenLogic,
+	}
+}
+func (r rotateTokenSigningKey) Run(ctx context.Context) error {
+	return r.tokenLogic.RotateSigningKey(ctx)
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 )
 
+// UpdateDownloadingAndFailedDownloadTaskStatusToPending keeps the name of its config section, but only takes back the
+// downloading tasks whose worker lease has expired. Failed download tasks are requeued by administrators.
 type UpdateDownloadingAndFailedDownloadTaskStatusToPending interface {
 	Run(context.Context) error
 }
@@ -18,5 +20,5 @@ func NewUpdateDownloadingAndFailedDownloadTaskStatusToPending(downloadTaskLogic
 	}
 }
 func (u updateDownloadingAndFailedDownloadTaskStatusToPending) Run(ctx context.Context) error {
-	return u.downloadTaskLogic.UpdateDownloadingAndFailedDownloadTaskStatusToPending(ctx)
+	return u.downloadTaskLogic.UpdateExpiredDownloadingDownloadTaskStatusToPending(ctx)
 }
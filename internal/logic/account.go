@@ -1,12 +1,20 @@
 package logic
 
 import (
+	"GoLoad/internal/configs"
 	"GoLoad/internal/dataaccess/cache"
 	"GoLoad/internal/dataaccess/database"
 	"GoLoad/internal/generated/grpc/go_load"
 	"GoLoad/internal/utils"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"go.uber.org/zap"
@@ -25,42 +33,132 @@ type CreateAccountOutput struct {
 type CreateSessionParams struct {
 	AccountName string
 	Password    string
+	ClientIP    string
 }
 type CreateSessionOutput struct {
-	Account *go_load.Account
-	Token   string
+	Account      *go_load.Account
+	Token        string
+	RefreshToken string
 }
 
+// CreateSessionOfIdentityParams identifies a user authenticated by an external identity provider.
+type CreateSessionOfIdentityParams struct {
+	Issuer               string
+	Subject              string
+	PreferredAccountName string
+}
+type RefreshSessionParams struct {
+	RefreshToken string
+}
+type RefreshSessionOutput struct {
+	Account      *go_load.Account
+	Token        string
+	RefreshToken string
+}
+type ChangePasswordParams struct {
+	OldPassword string
+	NewPassword string
+}
+type CreatePasswordResetTokenParams struct {
+	AccountName string
+}
+type CreatePasswordResetTokenOutput struct {
+	ResetToken string
+	ExpireTime time.Time
+}
+type ResetPasswordParams struct {
+	ResetToken  string
+	NewPassword string
+}
+type DeleteAccountParams struct {
+	Password string
+}
+
+const (
+	randomTokenByteCount         = 32
+	defaultIdentityAccountName   = "user"
+	accountNameSuffixByteCount   = 3
+	maxAccountNameSuffixAttempts = 5
+)
+
+var (
+	errInvalidRefreshToken       = status.Error(codes.Unauthenticated, "invalid refresh token")
+	errIncorrectPassword         = status.Error(codes.Unauthenticated, "incorrect password")
+	errIncorrectLogin            = status.Error(codes.Unauthenticated, "incorrect account name or password")
+	errInvalidPasswordResetToken = status.Error(codes.InvalidArgument, "invalid password reset token")
+	errAccountDisabled           = status.Error(codes.PermissionDenied, "account is disabled")
+	errAccountPasswordNotSet     = status.Error(codes.FailedPrecondition, "account has no password, set one first")
+)
+
 type Account interface {
 	CreateAccount(ctx context.Context, params CreateAccountParams) (CreateAccountOutput, error)
 	CreateSession(ctx context.Context, params CreateSessionParams) (CreateSessionOutput, error)
+	// CreateSessionOfIdentity logs in a user authenticated by an identity provider, creating their account on first
+	// login.
+	CreateSessionOfIdentity(ctx context.Context, params CreateSessionOfIdentityParams) (CreateSessionOutput, error)
+	RefreshSession(ctx context.Context, params RefreshSessionParams) (RefreshSessionOutput, error)
+	DeleteSession(ctx context.Context) error
+	// ChangePassword revokes every other session of the account, keeping the one making the change. Accounts created
+	// through an identity provider set their first password with an empty OldPassword.
+	ChangePassword(ctx context.Context, params ChangePasswordParams) error
+	// CreatePasswordResetToken is meant for administrators, who hand the single-use token to the account owner.
+	CreatePasswordResetToken(ctx context.Context, params CreatePasswordResetTokenParams) (CreatePasswordResetTokenOutput, error)
+	ResetPassword(ctx context.Context, params ResetPasswordParams) error
+	DeleteAccount(ctx context.Context, params DeleteAccountParams) error
 }
 type account struct {
-	goquDatabase                *goqu.Database
-	takenAccountNameCache       cache.TakenAccountName
-	accountDataAccessor         database.AccountDataAccessor
-	accountPasswordDataAccessor database.AccountPasswordDataAccessor
-	hashLogic                   Hash
-	tokenLogic                  Token
-	logger                      *zap.Logger
+	goquDatabase                   *goqu.Database
+	takenAccountNameCache          cache.TakenAccountName
+	accountDataAccessor            database.AccountDataAccessor
+	accountPasswordDataAccessor    database.AccountPasswordDataAccessor
+	sessionDataAccessor            database.SessionDataAccessor
+	passwordResetTokenDataAccessor database.PasswordResetTokenDataAccessor
+	apiKeyDataAccessor             database.APIKeyDataAccessor
+	accountIdentityDataAccessor    database.AccountIdentityDataAccessor
+	hashLogic                      Hash
+	tokenLogic                     Token
+	downloadTaskLogic              DownloadTask
+	webhookLogic                   Webhook
+	workspaceLogic                 Workspace
+	passwordPolicyLogic            PasswordPolicy
+	loginRateLimiterLogic          LoginRateLimiter
+	authConfig                     configs.Auth
+	logger                         *zap.Logger
 }
 
 func NewAccount(goquDatabase *goqu.Database, takenAccountNameCache cache.TakenAccountName, accountDataAccessor database.AccountDataAccessor,
-	accountPasswordDataAccessor database.AccountPasswordDataAccessor, hashLogic Hash, tokenLogic Token, logger *zap.Logger) Account {
+	accountPasswordDataAccessor database.AccountPasswordDataAccessor, sessionDataAccessor database.SessionDataAccessor,
+	passwordResetTokenDataAccessor database.PasswordResetTokenDataAccessor, apiKeyDataAccessor database.APIKeyDataAccessor,
+	accountIdentityDataAccessor database.AccountIdentityDataAccessor,
+	hashLogic Hash, tokenLogic Token,
+	downloadTaskLogic DownloadTask, webhookLogic Webhook, workspaceLogic Workspace, passwordPolicyLogic PasswordPolicy, loginRateLimiterLogic LoginRateLimiter,
+	authConfig configs.Auth, logger *zap.Logger) Account {
 	return &account{
-		goquDatabase:                goquDatabase,
-		takenAccountNameCache:       takenAccountNameCache,
-		accountDataAccessor:         accountDataAccessor,
-		accountPasswordDataAccessor: accountPasswordDataAccessor,
-		hashLogic:                   hashLogic,
-		tokenLogic:                  tokenLogic,
-		logger:                      logger,
+		goquDatabase:                   goquDatabase,
+		takenAccountNameCache:          takenAccountNameCache,
+		accountDataAccessor:            accountDataAccessor,
+		accountPasswordDataAccessor:    accountPasswordDataAccessor,
+		sessionDataAccessor:            sessionDataAccessor,
+		passwordResetTokenDataAccessor: passwordResetTokenDataAccessor,
+		apiKeyDataAccessor:             apiKeyDataAccessor,
+		accountIdentityDataAccessor:    accountIdentityDataAccessor,
+		hashLogic:                      hashLogic,
+		tokenLogic:                     tokenLogic,
+		downloadTaskLogic:              downloadTaskLogic,
+		webhookLogic:                   webhookLogic,
+		workspaceLogic:                 workspaceLogic,
+		passwordPolicyLogic:            passwordPolicyLogic,
+		loginRateLimiterLogic:          loginRateLimiterLogic,
+		authConfig:                     authConfig,
+		logger:                         logger,
 	}
 }
 func (a account) databaseAccountToProtoAccount(account database.Account) *go_load.Account {
 	return &go_load.Account{
 		Id:          account.ID,
 		AccountName: account.AccountName,
+		Role:        account.Role,
+		Disabled:    account.Disabled,
 	}
 }
 
@@ -86,6 +184,9 @@ func (a *account) isAccountAccountNameTaken(ctx context.Context, accountName str
 }
 
 func (a *account) CreateAccount(ctx context.Context, params CreateAccountParams) (CreateAccountOutput, error) {
+	if err := a.passwordPolicyLogic.Validate(ctx, params.Password); err != nil {
+		return CreateAccountOutput{}, err
+	}
 	accountNameTaken, err := a.isAccountAccountNameTaken(ctx, params.AccountName)
 	if err != nil {
 		return CreateAccountOutput{}, status.Error(codes.Internal, "failed to check if account name is taken")
@@ -121,28 +222,476 @@ func (a *account) CreateAccount(ctx context.Context, params CreateAccountParams)
 		AccountName: params.AccountName,
 	}, nil
 }
+
+// recordFailedLogin returns the error reported to the client, which is the same whether the account name or the
+// password is wrong so that it cannot be used to find out which account names exist.
+func (a *account) recordFailedLogin(ctx context.Context, params CreateSessionParams, ofAccountID *uint64, reason string) error {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.String("account_name", params.AccountName))
+
+	if err := a.loginRateLimiterLogic.RecordFailedLogin(ctx, RecordFailedLoginParams{
+		AccountName: params.AccountName,
+		ClientIP:    params.ClientIP,
+		OfAccountID: ofAccountID,
+		Reason:      reason,
+	}); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to record failed login")
+	}
+	return errIncorrectLogin
+}
 func (a *account) CreateSession(ctx context.Context, params CreateSessionParams) (CreateSessionOutput, error) {
+	loginAttemptParams := LoginAttemptParams{
+		AccountName: params.AccountName,
+		ClientIP:    params.ClientIP,
+	}
+	if err := a.loginRateLimiterLogic.CheckLoginAllowed(ctx, loginAttemptParams); err != nil {
+		return CreateSessionOutput{}, err
+	}
 	existingAccount, err := a.accountDataAccessor.GetAccountByAccountName(ctx, params.AccountName)
 	if err != nil {
+		if errors.Is(err, database.ErrAccountNotFound) {
+			return CreateSessionOutput{}, a.recordFailedLogin(ctx, params, nil, "account_not_found")
+		}
 		return CreateSessionOutput{}, err
 	}
-	existingAccountPassword, err := a.accountPasswordDataAccessor.GetAccountPassword(ctx, existingAccount.ID)
+	existingAccountPassword, err := a.verifyAccountPassword(ctx, existingAccount.ID, params.Password)
 	if err != nil {
+		if errors.Is(err, errIncorrectPassword) {
+			return CreateSessionOutput{}, a.recordFailedLogin(ctx, params, &existingAccount.ID, "incorrect_password")
+		}
 		return CreateSessionOutput{}, err
 	}
-	isHashEqual, err := a.hashLogic.IsHashEqual(ctx, params.Password, existingAccountPassword.Hash)
+	// A disabled account is only told so once its password is verified, and its login does not count as successful.
+	if existingAccount.Disabled {
+		return CreateSessionOutput{}, errAccountDisabled
+	}
+	a.loginRateLimiterLogic.RecordSuccessfulLogin(ctx, loginAttemptParams)
+	a.rehashAccountPasswordIfNeeded(ctx, existingAccountPassword, params.Password)
+	return a.createSessionOfAccount(ctx, existingAccount)
+}
+func (a *account) createSessionOfAccount(ctx context.Context, existingAccount database.Account) (CreateSessionOutput, error) {
+	refreshToken, refreshTokenHash, err := a.generateRandomToken(ctx)
 	if err != nil {
 		return CreateSessionOutput{}, err
 	}
-	if !isHashEqual {
-		return CreateSessionOutput{}, status.Error(codes.Unauthenticated, "incorrect password")
+	refreshTokenExpireTime, err := a.getRefreshTokenExpireTime(ctx)
+	if err != nil {
+		return CreateSessionOutput{}, err
+	}
+	sessionID, err := a.sessionDataAccessor.CreateSession(ctx, database.Session{
+		OfAccountID:      existingAccount.ID,
+		RefreshTokenHash: refreshTokenHash,
+		ExpireTime:       refreshTokenExpireTime,
+	})
+	if err != nil {
+		return CreateSessionOutput{}, err
 	}
-	token, _, err := a.tokenLogic.GetToken(ctx, existingAccount.ID)
+	token, _, err := a.tokenLogic.GetToken(ctx, existingAccount.ID, sessionID)
 	if err != nil {
 		return CreateSessionOutput{}, err
 	}
 	return CreateSessionOutput{
-		Account: a.databaseAccountToProtoAccount(existingAccount),
-		Token:   token,
+		Account:      a.databaseAccountToProtoAccount(existingAccount),
+		Token:        token,
+		RefreshToken: refreshToken,
 	}, nil
 }
+
+// getAvailableAccountName returns preferredAccountName, or it followed by a random suffix if it is already taken.
+func (a *account) getAvailableAccountName(ctx context.Context, preferredAccountName string) (string, error) {
+	accountName := preferredAccountName
+	for i := 0; i < maxAccountNameSuffixAttempts; i++ {
+		accountNameTaken, err := a.isAccountAccountNameTaken(ctx, accountName)
+		if err != nil {
+			return "", err
+		}
+		if !accountNameTaken {
+			return accountName, nil
+		}
+		suffixBytes := make([]byte, accountNameSuffixByteCount)
+		if _, err = rand.Read(suffixBytes); err != nil {
+			return "", status.Error(codes.Internal, "failed to generate account name suffix")
+		}
+		accountName = fmt.Sprintf("%s-%s", preferredAccountName, hex.EncodeToString(suffixBytes))
+	}
+	return "", status.Error(codes.AlreadyExists, "failed to find an available account name")
+}
+
+// getOrCreateAccountOfIdentity provisions an account without a password the first time an identity logs in. Existing
+// accounts are never linked by name, since the identity provider does not prove ownership of a GoLoad account.
+func (a *account) getOrCreateAccountOfIdentity(ctx context.Context, params CreateSessionOfIdentityParams) (database.Account, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.String("issuer", params.Issuer))
+
+	accountIdentity, err := a.accountIdentityDataAccessor.GetAccountIdentityByIssuerAndSubject(ctx, params.Issuer, params.Subject)
+	if err == nil {
+		return a.accountDataAccessor.GetAccountByID(ctx, accountIdentity.OfAccountID)
+	}
+	if !errors.Is(err, database.ErrAccountIdentityNotFound) {
+		return database.Account{}, err
+	}
+	preferredAccountName := params.PreferredAccountName
+	if preferredAccountName == "" {
+		preferredAccountName = defaultIdentityAccountName
+	}
+	accountName, err := a.getAvailableAccountName(ctx, preferredAccountName)
+	if err != nil {
+		return database.Account{}, err
+	}
+	newAccount := database.Account{
+		AccountName: accountName,
+		Role:        go_load.AccountRole_User,
+	}
+	txErr := a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		var createAccountErr error
+		newAccount.ID, createAccountErr = a.accountDataAccessor.WithDatabase(td).CreateAccount(ctx, newAccount)
+		if createAccountErr != nil {
+			return createAccountErr
+		}
+		_, createAccountIdentityErr := a.accountIdentityDataAccessor.WithDatabase(td).CreateAccountIdentity(ctx, database.AccountIdentity{
+			OfAccountID: newAccount.ID,
+			Issuer:      params.Issuer,
+			Subject:     params.Subject,
+			CreatedTime: time.Now(),
+		})
+		return createAccountIdentityErr
+	})
+	if txErr != nil {
+		return database.Account{}, txErr
+	}
+	logger.With(zap.Uint64("account_id", newAccount.ID)).Info("created account for new identity")
+	return newAccount, nil
+}
+func (a *account) CreateSessionOfIdentity(ctx context.Context, params CreateSessionOfIdentityParams) (CreateSessionOutput, error) {
+	existingAccount, err := a.getOrCreateAccountOfIdentity(ctx, params)
+	if err != nil {
+		return CreateSessionOutput{}, err
+	}
+	if existingAccount.Disabled {
+		return CreateSessionOutput{}, errAccountDisabled
+	}
+	return a.createSessionOfAccount(ctx, existingAccount)
+}
+func (a *account) verifyAccountPassword(ctx context.Context, accountID uint64, password string) (database.AccountPassword, error) {
+	existingAccountPassword, err := a.accountPasswordDataAccessor.GetAccountPassword(ctx, accountID)
+	if err != nil {
+		// Accounts created through an identity provider have no password.
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.AccountPassword{}, errIncorrectPassword
+		}
+		return database.AccountPassword{}, err
+	}
+	isHashEqual, err := a.hashLogic.IsHashEqual(ctx, password, existingAccountPassword.Hash)
+	if err != nil {
+		return database.AccountPassword{}, err
+	}
+	if !isHashEqual {
+		return database.AccountPassword{}, errIncorrectPassword
+	}
+	return existingAccountPassword, nil
+}
+
+// hasAccountPassword is false for accounts created through an identity provider until they set a password with
+// ChangePassword.
+func (a *account) hasAccountPassword(ctx context.Context, accountID uint64) (bool, error) {
+	if _, err := a.accountPasswordDataAccessor.GetAccountPassword(ctx, accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// setAccountPassword creates the password of accounts that do not have one yet instead of updating it.
+func setAccountPassword(
+	ctx context.Context,
+	accountPasswordDataAccessor database.AccountPasswordDataAccessor,
+	accountPassword database.AccountPassword,
+) error {
+	if _, err := accountPasswordDataAccessor.GetAccountPassword(ctx, accountPassword.OfAccountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return accountPasswordDataAccessor.CreateAccountPassword(ctx, accountPassword)
+		}
+		return err
+	}
+	return accountPasswordDataAccessor.UpdateAccountPassword(ctx, accountPassword)
+}
+
+// rehashAccountPasswordIfNeeded moves the stored hash to the current hash settings while the plaintext password is at
+// hand. Failing to do so does not fail the login, since the old hash is still valid.
+func (a *account) rehashAccountPasswordIfNeeded(ctx context.Context, accountPassword database.AccountPassword, password string) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountPassword.OfAccountID))
+
+	if !a.hashLogic.NeedsRehash(ctx, accountPassword.Hash) {
+		return
+	}
+	hashedPassword, err := a.hashLogic.Hash(ctx, password)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to rehash account password")
+		return
+	}
+	accountPassword.Hash = hashedPassword
+	if err = a.accountPasswordDataAccessor.UpdateAccountPassword(ctx, accountPassword); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to update rehashed account password")
+		return
+	}
+	logger.Info("rehashed account password")
+}
+func (a *account) generateRandomToken(ctx context.Context) (string, string, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	refreshTokenBytes := make([]byte, randomTokenByteCount)
+	if _, err := rand.Read(refreshTokenBytes); err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate refresh token")
+		return "", "", status.Error(codes.Internal, "failed to generate refresh token")
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(refreshTokenBytes)
+	return refreshToken, hashRandomToken(refreshToken), nil
+}
+
+// hashRandomToken uses a plain SHA-256 rather than the password hash, since refresh tokens, password reset tokens and
+// API keys are random and need to be looked up by their hash.
+func hashRandomToken(randomToken string) string {
+	randomTokenHash := sha256.Sum256([]byte(randomToken))
+	return hex.EncodeToString(randomTokenHash[:])
+}
+func (a *account) getRefreshTokenExpireTime(ctx context.Context) (time.Time, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	refreshTokenExpiresIn, err := a.authConfig.Token.GetRefreshTokenExpiresInDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse refresh_token_expires_in")
+		return time.Time{}, status.Error(codes.Internal, "failed to parse refresh_token_expires_in")
+	}
+	return time.Now().Add(refreshTokenExpiresIn), nil
+}
+
+// RefreshSession rotates the refresh token of the session, so each refresh token can only be used once.
+func (a *account) RefreshSession(ctx context.Context, params RefreshSessionParams) (RefreshSessionOutput, error) {
+	refreshToken, refreshTokenHash, err := a.generateRandomToken(ctx)
+	if err != nil {
+		return RefreshSessionOutput{}, err
+	}
+	refreshTokenExpireTime, err := a.getRefreshTokenExpireTime(ctx)
+	if err != nil {
+		return RefreshSessionOutput{}, err
+	}
+	output := RefreshSessionOutput{
+		RefreshToken: refreshToken,
+	}
+	txErr := a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		session, getSessionErr := a.sessionDataAccessor.WithDatabase(td).
+			GetSessionByRefreshTokenHashWithXLock(ctx, hashRandomToken(params.RefreshToken))
+		if getSessionErr != nil {
+			if errors.Is(getSessionErr, database.ErrSessionNotFound) {
+				return errInvalidRefreshToken
+			}
+			return getSessionErr
+		}
+		if session.Revoked || time.Now().After(session.ExpireTime) {
+			return errInvalidRefreshToken
+		}
+		session.RefreshTokenHash = refreshTokenHash
+		session.ExpireTime = refreshTokenExpireTime
+		if updateSessionErr := a.sessionDataAccessor.WithDatabase(td).UpdateSession(ctx, session); updateSessionErr != nil {
+			return updateSessionErr
+		}
+		existingAccount, getAccountErr := a.accountDataAccessor.WithDatabase(td).GetAccountByID(ctx, session.OfAccountID)
+		if getAccountErr != nil {
+			return getAccountErr
+		}
+		if existingAccount.Disabled {
+			return errAccountDisabled
+		}
+		output.Account = a.databaseAccountToProtoAccount(existingAccount)
+		token, _, getTokenErr := a.tokenLogic.GetToken(ctx, session.OfAccountID, session.ID)
+		if getTokenErr != nil {
+			return getTokenErr
+		}
+		output.Token = token
+		return nil
+	})
+	if txErr != nil {
+		return RefreshSessionOutput{}, txErr
+	}
+	return output, nil
+}
+func (a *account) DeleteSession(ctx context.Context) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	return a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		return a.tokenLogic.WithDatabase(td).RevokeSession(ctx, principal.SessionID)
+	})
+}
+func (a *account) ChangePassword(ctx context.Context, params ChangePasswordParams) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	hasPassword, err := a.hasAccountPassword(ctx, principal.AccountID)
+	if err != nil {
+		return err
+	}
+	if hasPassword {
+		if _, err = a.verifyAccountPassword(ctx, principal.AccountID, params.OldPassword); err != nil {
+			return err
+		}
+	} else if params.OldPassword != "" {
+		return errIncorrectPassword
+	}
+	if err = a.passwordPolicyLogic.Validate(ctx, params.NewPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := a.hashLogic.Hash(ctx, params.NewPassword)
+	if err != nil {
+		return err
+	}
+	return a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		if err := setAccountPassword(ctx, a.accountPasswordDataAccessor.WithDatabase(td), database.AccountPassword{
+			OfAccountID: principal.AccountID,
+			Hash:        hashedPassword,
+		}); err != nil {
+			return err
+		}
+		return a.tokenLogic.WithDatabase(td).RevokeSessionsOfAccount(ctx, principal.AccountID, principal.SessionID)
+	})
+}
+func (a *account) CreatePasswordResetToken(
+	ctx context.Context,
+	params CreatePasswordResetTokenParams,
+) (CreatePasswordResetTokenOutput, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).With(zap.String("account_name", params.AccountName))
+
+	existingAccount, err := a.accountDataAccessor.GetAccountByAccountName(ctx, params.AccountName)
+	if err != nil {
+		return CreatePasswordResetTokenOutput{}, err
+	}
+	expiresIn, err := a.authConfig.PasswordReset.GetExpiresInDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse password reset expires_in")
+		return CreatePasswordResetTokenOutput{}, status.Error(codes.Internal, "failed to parse password reset expires_in")
+	}
+	resetToken, resetTokenHash, err := a.generateRandomToken(ctx)
+	if err != nil {
+		return CreatePasswordResetTokenOutput{}, err
+	}
+	expireTime := time.Now().Add(expiresIn)
+	if _, err = a.passwordResetTokenDataAccessor.CreatePasswordResetToken(ctx, database.PasswordResetToken{
+		OfAccountID: existingAccount.ID,
+		TokenHash:   resetTokenHash,
+		ExpireTime:  expireTime,
+	}); err != nil {
+		return CreatePasswordResetTokenOutput{}, err
+	}
+	logger.Info("created password reset token")
+	return CreatePasswordResetTokenOutput{
+		ResetToken: resetToken,
+		ExpireTime: expireTime,
+	}, nil
+}
+func (a *account) ResetPassword(ctx context.Context, params ResetPasswordParams) error {
+	if err := a.passwordPolicyLogic.Validate(ctx, params.NewPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := a.hashLogic.Hash(ctx, params.NewPassword)
+	if err != nil {
+		return err
+	}
+	return a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		passwordResetToken, getPasswordResetTokenErr := a.passwordResetTokenDataAccessor.WithDatabase(td).
+			GetPasswordResetTokenByTokenHashWithXLock(ctx, hashRandomToken(params.ResetToken))
+		if getPasswordResetTokenErr != nil {
+			if errors.Is(getPasswordResetTokenErr, database.ErrPasswordResetTokenNotFound) {
+				return errInvalidPasswordResetToken
+			}
+			return getPasswordResetTokenErr
+		}
+		if passwordResetToken.Used || time.Now().After(passwordResetToken.ExpireTime) {
+			return errInvalidPasswordResetToken
+		}
+		passwordResetToken.Used = true
+		if err := a.passwordResetTokenDataAccessor.WithDatabase(td).
+			UpdatePasswordResetToken(ctx, passwordResetToken); err != nil {
+			return err
+		}
+		if err := setAccountPassword(ctx, a.accountPasswordDataAccessor.WithDatabase(td), database.AccountPassword{
+			OfAccountID: passwordResetToken.OfAccountID,
+			Hash:        hashedPassword,
+		}); err != nil {
+			return err
+		}
+		return a.tokenLogic.WithDatabase(td).RevokeSessionsOfAccount(ctx, passwordResetToken.OfAccountID, 0)
+	})
+}
+
+// DeleteAccount deletes the download tasks and their files before the account itself, so that a failure part way
+// leaves an account the owner can still log in to and delete again.
+func (a *account) DeleteAccount(ctx context.Context, params DeleteAccountParams) error {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	hasPassword, err := a.hasAccountPassword(ctx, principal.AccountID)
+	if err != nil {
+		return err
+	}
+	if !hasPassword {
+		return errAccountPasswordNotSet
+	}
+	if _, err = a.verifyAccountPassword(ctx, principal.AccountID, params.Password); err != nil {
+		return err
+	}
+	existingAccount, err := a.accountDataAccessor.GetAccountByID(ctx, principal.AccountID)
+	if err != nil {
+		return err
+	}
+	// Leaving the workspaces first refuses to delete the last owner of a workspace before anything is deleted.
+	if err = a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		return a.workspaceLogic.WithDatabase(td).LeaveWorkspaceListOfAccount(ctx, principal.AccountID)
+	}); err != nil {
+		return err
+	}
+	if err = a.downloadTaskLogic.DeleteDownloadTaskListOfAccount(ctx, principal.AccountID); err != nil {
+		return err
+	}
+	if err = a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		// Sessions are revoked before their rows are deleted so that the revocation is also recorded in the cache.
+		if err := a.tokenLogic.WithDatabase(td).RevokeSessionsOfAccount(ctx, principal.AccountID, 0); err != nil {
+			return err
+		}
+		if err := a.sessionDataAccessor.WithDatabase(td).DeleteSessionsOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		if err := a.passwordResetTokenDataAccessor.WithDatabase(td).
+			DeletePasswordResetTokensOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		if err := a.apiKeyDataAccessor.WithDatabase(td).DeleteAPIKeysOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		if err := a.accountIdentityDataAccessor.WithDatabase(td).
+			DeleteAccountIdentitiesOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		// The account may have been added to a workspace since it left them.
+		if err := a.workspaceLogic.WithDatabase(td).LeaveWorkspaceListOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		if err := a.webhookLogic.WithDatabase(td).DeleteWebhookListOfAccount(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		if err := a.accountPasswordDataAccessor.WithDatabase(td).DeleteAccountPassword(ctx, principal.AccountID); err != nil {
+			return err
+		}
+		return a.accountDataAccessor.WithDatabase(td).DeleteAccount(ctx, principal.AccountID)
+	}); err != nil {
+		return err
+	}
+	if err = a.takenAccountNameCache.Remove(ctx, existingAccount.AccountName); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to remove account name from taken set in cache")
+	}
+	return nil
+}
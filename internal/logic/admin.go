@@ -0,0 +1,173 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type GetAccountListParams struct {
+	Offset uint64
+	Limit  uint64
+}
+type GetAccountListOutput struct {
+	TotalAccountCount uint64
+	AccountList       []*go_load.Account
+}
+type UpdateAccountRoleParams struct {
+	AccountID uint64
+	Role      go_load.AccountRole
+}
+type UpdateAccountRoleOfAccountNameParams struct {
+	AccountName string
+	Role        go_load.AccountRole
+}
+type UpdateAccountRoleOutput struct {
+	Account *go_load.Account
+}
+
+// Admin manages accounts on behalf of administrators. Callers are expected to have checked the Admin role.
+type Admin interface {
+	GetAccountList(ctx context.Context, params GetAccountListParams) (GetAccountListOutput, error)
+	UpdateAccountRole(ctx context.Context, params UpdateAccountRoleParams) (UpdateAccountRoleOutput, error)
+	// UpdateAccountRoleOfAccountName is used from the command line to bootstrap the first administrator.
+	UpdateAccountRoleOfAccountName(ctx context.Context, params UpdateAccountRoleOfAccountNameParams) (UpdateAccountRoleOutput, error)
+	// DisableAccount also revokes every session of the account.
+	DisableAccount(ctx context.Context, accountID uint64) error
+	EnableAccount(ctx context.Context, accountID uint64) error
+	// CreatePasswordResetToken issues a reset token the administrator hands to the owner of the account.
+	CreatePasswordResetToken(ctx context.Context, accountID uint64) (CreatePasswordResetTokenOutput, error)
+}
+type admin struct {
+	goquDatabase        *goqu.Database
+	accountDataAccessor database.AccountDataAccessor
+	accountLogic        Account
+	tokenLogic          Token
+	logger              *zap.Logger
+}
+
+func NewAdmin(
+	goquDatabase *goqu.Database,
+	accountDataAccessor database.AccountDataAccessor,
+	accountLogic Account,
+	tokenLogic Token,
+	logger *zap.Logger,
+) Admin {
+	return &admin{
+		goquDatabase:        goquDatabase,
+		accountDataAccessor: accountDataAccessor,
+		accountLogic:        accountLogic,
+		tokenLogic:          tokenLogic,
+		logger:              logger,
+	}
+}
+func (a admin) databaseAccountToProtoAccount(account database.Account) *go_load.Account {
+	return &go_load.Account{
+		Id:          account.ID,
+		AccountName: account.AccountName,
+		Role:        account.Role,
+		Disabled:    account.Disabled,
+	}
+}
+func (a admin) GetAccountList(ctx context.Context, params GetAccountListParams) (GetAccountListOutput, error) {
+	totalAccountCount, err := a.accountDataAccessor.GetAccountCount(ctx)
+	if err != nil {
+		return GetAccountListOutput{}, err
+	}
+	accountList, err := a.accountDataAccessor.GetAccountList(ctx, params.Offset, params.Limit)
+	if err != nil {
+		return GetAccountListOutput{}, err
+	}
+	return GetAccountListOutput{
+		TotalAccountCount: totalAccountCount,
+		AccountList: lo.Map(accountList, func(item database.Account, _ int) *go_load.Account {
+			return a.databaseAccountToProtoAccount(item)
+		}),
+	}, nil
+}
+func (a admin) UpdateAccountRole(ctx context.Context, params UpdateAccountRoleParams) (UpdateAccountRoleOutput, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger).
+		With(zap.Uint64("account_id", params.AccountID)).
+		With(zap.String("role", params.Role.String()))
+
+	if _, ok := go_load.AccountRole_name[int32(params.Role)]; !ok || params.Role == go_load.AccountRole_UndefinedRole {
+		return UpdateAccountRoleOutput{}, status.Error(codes.InvalidArgument, "invalid account role")
+	}
+	output := UpdateAccountRoleOutput{}
+	txErr := a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		account, err := a.accountDataAccessor.WithDatabase(td).GetAccountByID(ctx, params.AccountID)
+		if err != nil {
+			return err
+		}
+		account.Role = params.Role
+		if err = a.accountDataAccessor.WithDatabase(td).UpdateAccount(ctx, account); err != nil {
+			return err
+		}
+		output.Account = a.databaseAccountToProtoAccount(account)
+		return nil
+	})
+	if txErr != nil {
+		return UpdateAccountRoleOutput{}, txErr
+	}
+	logger.Info("updated account role")
+	return output, nil
+}
+func (a admin) UpdateAccountRoleOfAccountName(
+	ctx context.Context,
+	params UpdateAccountRoleOfAccountNameParams,
+) (UpdateAccountRoleOutput, error) {
+	account, err := a.accountDataAccessor.GetAccountByAccountName(ctx, params.AccountName)
+	if err != nil {
+		return UpdateAccountRoleOutput{}, err
+	}
+	return a.UpdateAccountRole(ctx, UpdateAccountRoleParams{
+		AccountID: account.ID,
+		Role:      params.Role,
+	})
+}
+func (a admin) setAccountDisabled(ctx context.Context, accountID uint64, disabled bool) error {
+	return a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		account, err := a.accountDataAccessor.WithDatabase(td).GetAccountByID(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		account.Disabled = disabled
+		if err = a.accountDataAccessor.WithDatabase(td).UpdateAccount(ctx, account); err != nil {
+			return err
+		}
+		if !disabled {
+			return nil
+		}
+		return a.tokenLogic.WithDatabase(td).RevokeSessionsOfAccount(ctx, accountID, 0)
+	})
+}
+func (a admin) DisableAccount(ctx context.Context, accountID uint64) error {
+	if err := a.setAccountDisabled(ctx, accountID, true); err != nil {
+		return err
+	}
+	utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountID)).Info("disabled account")
+	return nil
+}
+func (a admin) EnableAccount(ctx context.Context, accountID uint64) error {
+	if err := a.setAccountDisabled(ctx, accountID, false); err != nil {
+		return err
+	}
+	utils.LoggerWithContext(ctx, a.logger).With(zap.Uint64("account_id", accountID)).Info("enabled account")
+	return nil
+}
+func (a admin) CreatePasswordResetToken(ctx context.Context, accountID uint64) (CreatePasswordResetTokenOutput, error) {
+	account, err := a.accountDataAccessor.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return CreatePasswordResetTokenOutput{}, err
+	}
+	return a.accountLogic.CreatePasswordResetToken(ctx, CreatePasswordResetTokenParams{
+		AccountName: account.AccountName,
+	})
+}
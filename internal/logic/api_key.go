@@ -0,0 +1,191 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// apiKeyPrefix tells API keys apart from session tokens, which are JWTs.
+	apiKeyPrefix = "gl_"
+	// apiKeyDisplayPrefixLength is how much of the key is kept to be shown in API key lists.
+	apiKeyDisplayPrefixLength = len(apiKeyPrefix) + 8
+	apiKeyScopeSeparator      = ","
+)
+
+type CreateAPIKeyParams struct {
+	Name   string
+	Scopes []go_load.APIKeyScope
+	// TTL is 0 for keys that never expire.
+	TTL time.Duration
+}
+type CreateAPIKeyOutput struct {
+	APIKey *go_load.APIKey
+	Key    string
+}
+type GetAPIKeyListOutput struct {
+	APIKeyList []*go_load.APIKey
+}
+type RevokeAPIKeyParams struct {
+	APIKeyID uint64
+}
+
+// APIKey manages the API keys of an account. Only sessions can manage API keys, so a leaked key cannot be used to
+// create more.
+type APIKey interface {
+	// CreateAPIKey returns the key itself only once; only its hash is stored.
+	CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (CreateAPIKeyOutput, error)
+	GetAPIKeyList(ctx context.Context) (GetAPIKeyListOutput, error)
+	RevokeAPIKey(ctx context.Context, params RevokeAPIKeyParams) error
+}
+type apiKey struct {
+	goquDatabase       *goqu.Database
+	apiKeyDataAccessor database.APIKeyDataAccessor
+	logger             *zap.Logger
+}
+
+func NewAPIKey(goquDatabase *goqu.Database, apiKeyDataAccessor database.APIKeyDataAccessor, logger *zap.Logger) APIKey {
+	return &apiKey{
+		goquDatabase:       goquDatabase,
+		apiKeyDataAccessor: apiKeyDataAccessor,
+		logger:             logger,
+	}
+}
+func formatAPIKeyScopes(scopes []go_load.APIKeyScope) string {
+	scopeNameList := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scopeNameList = append(scopeNameList, scope.String())
+	}
+	return strings.Join(scopeNameList, apiKeyScopeSeparator)
+}
+func parseAPIKeyScopes(scopes string) []go_load.APIKeyScope {
+	if scopes == "" {
+		return nil
+	}
+	scopeList := make([]go_load.APIKeyScope, 0)
+	for _, scopeName := range strings.Split(scopes, apiKeyScopeSeparator) {
+		if scopeValue, ok := go_load.APIKeyScope_value[scopeName]; ok {
+			scopeList = append(scopeList, go_load.APIKeyScope(scopeValue))
+		}
+	}
+	return scopeList
+}
+func (a apiKey) databaseAPIKeyToProtoAPIKey(apiKey database.APIKey) *go_load.APIKey {
+	protoAPIKey := &go_load.APIKey{
+		Id:          apiKey.ID,
+		Name:        apiKey.Name,
+		KeyPrefix:   apiKey.KeyPrefix,
+		Scopes:      parseAPIKeyScopes(apiKey.Scopes),
+		CreatedTime: apiKey.CreatedTime.Unix(),
+		Revoked:     apiKey.Revoked,
+	}
+	if apiKey.ExpireTime != nil {
+		protoAPIKey.ExpireTime = apiKey.ExpireTime.Unix()
+	}
+	return protoAPIKey
+}
+func (a apiKey) validateAPIKeyScopes(scopes []go_load.APIKeyScope) ([]go_load.APIKeyScope, error) {
+	uniqueScopeList := make([]go_load.APIKeyScope, 0, len(scopes))
+	scopeSet := make(map[go_load.APIKeyScope]struct{})
+	for _, scope := range scopes {
+		if _, ok := go_load.APIKeyScope_name[int32(scope)]; !ok || scope == go_load.APIKeyScope_UndefinedScope {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid api key scope %d", scope)
+		}
+		if _, ok := scopeSet[scope]; ok {
+			continue
+		}
+		scopeSet[scope] = struct{}{}
+		uniqueScopeList = append(uniqueScopeList, scope)
+	}
+	return uniqueScopeList, nil
+}
+func (a apiKey) CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (CreateAPIKeyOutput, error) {
+	logger := utils.LoggerWithContext(ctx, a.logger)
+
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return CreateAPIKeyOutput{}, err
+	}
+	if params.Name == "" {
+		return CreateAPIKeyOutput{}, status.Error(codes.InvalidArgument, "api key name is required")
+	}
+	scopes, err := a.validateAPIKeyScopes(params.Scopes)
+	if err != nil {
+		return CreateAPIKeyOutput{}, err
+	}
+	keyBytes := make([]byte, randomTokenByteCount)
+	if _, err = rand.Read(keyBytes); err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate api key")
+		return CreateAPIKeyOutput{}, status.Error(codes.Internal, "failed to generate api key")
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(keyBytes)
+	databaseAPIKey := database.APIKey{
+		OfAccountID: principal.AccountID,
+		Name:        params.Name,
+		KeyPrefix:   key[:apiKeyDisplayPrefixLength],
+		KeyHash:     hashRandomToken(key),
+		Scopes:      formatAPIKeyScopes(scopes),
+		CreatedTime: time.Now(),
+	}
+	if params.TTL > 0 {
+		expireTime := databaseAPIKey.CreatedTime.Add(params.TTL)
+		databaseAPIKey.ExpireTime = &expireTime
+	}
+	databaseAPIKey.ID, err = a.apiKeyDataAccessor.CreateAPIKey(ctx, databaseAPIKey)
+	if err != nil {
+		return CreateAPIKeyOutput{}, err
+	}
+	logger.
+		With(zap.Uint64("account_id", principal.AccountID)).
+		With(zap.Uint64("api_key_id", databaseAPIKey.ID)).
+		Info("created api key")
+	return CreateAPIKeyOutput{
+		APIKey: a.databaseAPIKeyToProtoAPIKey(databaseAPIKey),
+		Key:    key,
+	}, nil
+}
+func (a apiKey) GetAPIKeyList(ctx context.Context) (GetAPIKeyListOutput, error) {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return GetAPIKeyListOutput{}, err
+	}
+	apiKeyList, err := a.apiKeyDataAccessor.GetAPIKeyListOfAccount(ctx, principal.AccountID)
+	if err != nil {
+		return GetAPIKeyListOutput{}, err
+	}
+	output := GetAPIKeyListOutput{
+		APIKeyList: make([]*go_load.APIKey, 0, len(apiKeyList)),
+	}
+	for _, apiKey := range apiKeyList {
+		output.APIKeyList = append(output.APIKeyList, a.databaseAPIKeyToProtoAPIKey(apiKey))
+	}
+	return output, nil
+}
+func (a apiKey) RevokeAPIKey(ctx context.Context, params RevokeAPIKeyParams) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	return a.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		apiKey, getAPIKeyErr := a.apiKeyDataAccessor.WithDatabase(td).GetAPIKeyWithXLock(ctx, params.APIKeyID)
+		if getAPIKeyErr != nil {
+			return getAPIKeyErr
+		}
+		if apiKey.OfAccountID != principal.AccountID {
+			return status.Error(codes.PermissionDenied, "trying to revoke an api key the account does not own")
+		}
+		apiKey.Revoked = true
+		return a.apiKeyDataAccessor.WithDatabase(td).UpdateAPIKey(ctx, apiKey)
+	})
+}
@@ -0,0 +1,66 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/generated/grpc/go_load"
+	"context"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errMissingAuthToken = status.Error(codes.Unauthenticated, "missing auth token")
+	errRoleNotAllowed   = status.Error(codes.PermissionDenied, "account role is not allowed to perform this operation")
+)
+
+// AllAccountRoleList allows every account that is not disabled.
+var AllAccountRoleList = []go_load.AccountRole{
+	go_load.AccountRole_Admin,
+	go_load.AccountRole_User,
+	go_load.AccountRole_ReadOnlyUser,
+}
+
+// Authorization authenticates a token and checks the role of the account behind it. Roles are read from the database on
+// every check, so role changes and disabling an account take effect immediately, including for API keys.
+type Authorization interface {
+	Authorize(ctx context.Context, token string, allowedRoleList []go_load.AccountRole) (Principal, error)
+}
+type authorization struct {
+	tokenLogic          Token
+	accountDataAccessor database.AccountDataAccessor
+	logger              *zap.Logger
+}
+
+func NewAuthorization(tokenLogic Token, accountDataAccessor database.AccountDataAccessor, logger *zap.Logger) Authorization {
+	return &authorization{
+		tokenLogic:          tokenLogic,
+		accountDataAccessor: accountDataAccessor,
+		logger:              logger,
+	}
+}
+func (a authorization) Authorize(ctx context.Context, token string, allowedRoleList []go_load.AccountRole) (Principal, error) {
+	if token == "" {
+		return Principal{}, errMissingAuthToken
+	}
+	claims, err := a.tokenLogic.GetTokenClaims(ctx, token)
+	if err != nil {
+		return Principal{}, err
+	}
+	account, err := a.accountDataAccessor.GetAccountByID(ctx, claims.AccountID)
+	if err != nil {
+		return Principal{}, err
+	}
+	if account.Disabled {
+		return Principal{}, errAccountDisabled
+	}
+	if !lo.Contains(allowedRoleList, account.Role) {
+		return Principal{}, errRoleNotAllowed
+	}
+	return Principal{
+		TokenClaims: claims,
+		AccountRole: account.Role,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/mq/consumer"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type GetDeadLetterMessageListParams struct {
+	QueueName string
+	Limit     uint64
+}
+type GetDeadLetterMessageListOutput struct {
+	DeadLetterMessageList []consumer.DeadLetterMessage
+}
+type ReplayDeadLetterMessageParams struct {
+	QueueName string
+	Partition int32
+	Offset    int64
+}
+
+// DeadLetter lets operators inspect the messages that failed to be handled and replay them once the cause is fixed.
+// Replayed messages are not removed from the dead letter queue.
+type DeadLetter interface {
+	GetDeadLetterMessageList(ctx context.Context, params GetDeadLetterMessageListParams) (GetDeadLetterMessageListOutput, error)
+	ReplayDeadLetterMessage(ctx context.Context, params ReplayDeadLetterMessageParams) error
+}
+type deadLetter struct {
+	deadLetterReader consumer.DeadLetterReader
+	mqClient         producer.Client
+	logger           *zap.Logger
+}
+
+func NewDeadLetter(deadLetterReader consumer.DeadLetterReader, mqClient producer.Client, logger *zap.Logger) DeadLetter {
+	return &deadLetter{
+		deadLetterReader: deadLetterReader,
+		mqClient:         mqClient,
+		logger:           logger,
+	}
+}
+func (d deadLetter) GetDeadLetterMessageList(
+	ctx context.Context,
+	params GetDeadLetterMessageListParams,
+) (GetDeadLetterMessageListOutput, error) {
+	deadLetterMessageList, err := d.deadLetterReader.GetDeadLetterMessageList(ctx, params.QueueName, params.Limit)
+	if err != nil {
+		return GetDeadLetterMessageListOutput{}, err
+	}
+	return GetDeadLetterMessageListOutput{
+		DeadLetterMessageList: deadLetterMessageList,
+	}, nil
+}
+func (d deadLetter) ReplayDeadLetterMessage(ctx context.Context, params ReplayDeadLetterMessageParams) error {
+	logger := utils.LoggerWithContext(ctx, d.logger).
+		With(zap.String("queue_name", params.QueueName)).
+		With(zap.Int32("partition", params.Partition)).
+		With(zap.Int64("offset", params.Offset))
+
+	deadLetterMessage, err := d.deadLetterReader.GetDeadLetterMessage(ctx, params.QueueName, params.Partition, params.Offset)
+	if err != nil {
+		return err
+	}
+	if deadLetterMessage.QueueName != params.QueueName {
+		logger.With(zap.String("message_queue_name", deadLetterMessage.QueueName)).
+			Error("dead letter message does not come from its queue")
+		return status.Error(codes.FailedPrecondition, "dead letter message does not come from its queue")
+	}
+	if err = d.mqClient.Produce(ctx, deadLetterMessage.QueueName, deadLetterMessage.Payload); err != nil {
+		return err
+	}
+	logger.Info("replayed dead letter message")
+	return nil
+}
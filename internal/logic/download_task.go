@@ -8,12 +8,17 @@ import (
 	"GoLoad/internal/generated/grpc/go_load"
 	"GoLoad/internal/utils"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/gammazero/workerpool"
+	"github.com/google/uuid"
 	"github.com/samber/lo"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -22,27 +27,39 @@ import (
 
 const (
 	downloadTaskMetadataFieldNameFileName = "file-name"
+	downloadTaskMetadataFieldNameFileSize = "file-size"
+	downloadTaskMetadataFieldNameChecksum = "checksum"
+	downloadTaskFileNameFormat            = "download_file_%d"
+)
+
+var (
+	errDownloadTaskFileExpired = status.Error(codes.NotFound, "download task file has expired")
+	// downloadProgressMilestonePercentList are the progresses progress_milestone lifecycle events are published at.
+	downloadProgressMilestonePercentList = []uint64{25, 50, 75}
 )
 
 type CreateDownloadTaskParams struct {
-	Token        string
 	DownloadType go_load.DownloadType
 	URL          string
+	// TTL overrides the retention configured for the account when it is not 0.
+	TTL time.Duration
+	// WorkspaceID makes the workspace own the download task when it is not 0.
+	WorkspaceID uint64
 }
 type CreateDownloadTaskOutput struct {
 	DownloadTask *go_load.DownloadTask
 }
 type GetDownloadTaskListParams struct {
-	Token  string
 	Offset uint64
 	Limit  uint64
+	// WorkspaceID lists the download tasks of the workspace instead of the account when it is not 0.
+	WorkspaceID uint64
 }
 type GetDownloadTaskListOutput struct {
 	TotalDownloadTaskCount uint64
 	DownloadTaskList       []*go_load.DownloadTask
 }
 type UpdateDownloadTaskParams struct {
-	Token          string
 	DownloadTaskID uint64
 	URL            string
 }
@@ -50,12 +67,42 @@ type UpdateDownloadTaskOutput struct {
 	DownloadTask *go_load.DownloadTask
 }
 type DeleteDownloadTaskParams struct {
-	Token          string
 	DownloadTaskID uint64
 }
 type GetDownloadTaskFileParams struct {
-	Token          string
 	DownloadTaskID uint64
+	Offset         uint64
+	Length         uint64
+}
+type GetDownloadTaskFileOutput struct {
+	Reader   io.ReadSeekCloser
+	FileName string
+	FileSize uint64
+	// FileSizeKnown is false for files downloaded before their size was recorded, whose Reader cannot seek relative to
+	// the end.
+	FileSizeKnown bool
+	ContentType   string
+	Checksum      string
+	FinalURL      string
+}
+type GetDownloadTaskFileURLParams struct {
+	DownloadTaskID uint64
+}
+type GetDownloadTaskFileURLOutput struct {
+	URL        string
+	ExpireTime time.Time
+}
+type GetAllDownloadTaskListParams struct {
+	Offset uint64
+	Limit  uint64
+}
+type RequeueDownloadTaskOutput struct {
+	DownloadTask *go_load.DownloadTask
+}
+type GetDownloadTaskFileWithSignatureParams struct {
+	DownloadTaskID uint64
+	ExpireTime     int64
+	Signature      string
 }
 
 type DownloadTask interface {
@@ -65,50 +112,192 @@ type DownloadTask interface {
 	DeleteDownloadTask(context.Context, DeleteDownloadTaskParams) error
 	ExecuteAllPendingDownloadTask(context.Context) error
 	ExecuteDownloadTask(context.Context, uint64) error
-	GetDownloadTaskFile(context.Context, GetDownloadTaskFileParams) (io.ReadCloser, error)
-	UpdateDownloadingAndFailedDownloadTaskStatusToPending(context.Context) error
+	GetDownloadTaskFile(context.Context, GetDownloadTaskFileParams) (GetDownloadTaskFileOutput, error)
+	GetDownloadTaskFileURL(context.Context, GetDownloadTaskFileURLParams) (GetDownloadTaskFileURLOutput, error)
+	GetDownloadTaskFileWithSignature(context.Context, GetDownloadTaskFileWithSignatureParams) (GetDownloadTaskFileOutput, error)
+	// UpdateExpiredDownloadingDownloadTaskStatusToPending leaves failed download tasks alone, they are only retried
+	// once an administrator requeues them.
+	UpdateExpiredDownloadingDownloadTaskStatusToPending(context.Context) error
+	DeleteExpiredDownloadTaskFile(context.Context) error
+	DeleteDownloadTaskListOfAccount(ctx context.Context, accountID uint64) error
+	// GetAllDownloadTaskList, RequeueDownloadTask and DeleteAnyDownloadTask are for administrators and do not check
+	// which account owns the download tasks.
+	GetAllDownloadTaskList(ctx context.Context, params GetAllDownloadTaskListParams) (GetDownloadTaskListOutput, error)
+	RequeueDownloadTask(ctx context.Context, downloadTaskID uint64) (RequeueDownloadTaskOutput, error)
+	DeleteAnyDownloadTask(ctx context.Context, downloadTaskID uint64) error
 }
 type downloadTask struct {
-	tokenLogic                  Token
 	accountDataAccessor         database.AccountDataAccessor
 	downloadTaskDataAccessor    database.DownloadTaskDataAccessor
-	downloadTaskCreatedProducer producer.DownloadTaskCreatedProducer
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor
+	outboxLogic                 Outbox
+	webhookLogic                Webhook
 	goquDatabase                *goqu.Database
 	fileClient                  file.Client
+	fileURLSigner               FileURLSigner
 	cronConfig                  configs.Cron
+	downloadConfig              configs.Download
+	retentionConfig             configs.Retention
+	workerID                    string
+	leaseTTL                    time.Duration
+	heartbeatInterval           time.Duration
 	logger                      *zap.Logger
 }
 
-func NewDownloadTask(tokenLogic Token, accountDataAccessor database.AccountDataAccessor, downloadTaskDataAccessor database.DownloadTaskDataAccessor,
-	downloadTaskCreatedProducer producer.DownloadTaskCreatedProducer, goquDatabase *goqu.Database, fileClient file.Client,
-	cronConfig configs.Cron, logger *zap.Logger) DownloadTask {
+func NewDownloadTask(accountDataAccessor database.AccountDataAccessor, downloadTaskDataAccessor database.DownloadTaskDataAccessor,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor, outboxLogic Outbox, webhookLogic Webhook,
+	goquDatabase *goqu.Database, fileClient file.Client, fileURLSigner FileURLSigner, cronConfig configs.Cron,
+	downloadConfig configs.Download, retentionConfig configs.Retention, workerConfig configs.Worker, logger *zap.Logger,
+) (DownloadTask, error) {
+	leaseTTL, err := workerConfig.GetLeaseTTLDuration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse worker lease_ttl: %w", err)
+	}
+	heartbeatInterval, err := workerConfig.GetHeartbeatIntervalDuration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse worker heartbeat_interval: %w", err)
+	}
+	// A heartbeat must land before the lease it renews expires, or live downloads would be taken back.
+	if heartbeatInterval <= 0 || heartbeatInterval >= leaseTTL {
+		return nil, fmt.Errorf(
+			"worker heartbeat_interval %s must be positive and shorter than lease_ttl %s", heartbeatInterval, leaseTTL,
+		)
+	}
 	return &downloadTask{
-		tokenLogic:                  tokenLogic,
 		accountDataAccessor:         accountDataAccessor,
 		downloadTaskDataAccessor:    downloadTaskDataAccessor,
-		downloadTaskCreatedProducer: downloadTaskCreatedProducer,
+		workspaceMemberDataAccessor: workspaceMemberDataAccessor,
+		outboxLogic:                 outboxLogic,
+		webhookLogic:                webhookLogic,
 		goquDatabase:                goquDatabase,
 		fileClient:                  fileClient,
+		fileURLSigner:               fileURLSigner,
 		cronConfig:                  cronConfig,
+		downloadConfig:              downloadConfig,
+		retentionConfig:             retentionConfig,
+		workerID:                    workerConfig.GetID(),
+		leaseTTL:                    leaseTTL,
+		heartbeatInterval:           heartbeatInterval,
 		logger:                      logger,
-	}
+	}, nil
+}
+
+// downloadTaskFileInfo separates the path the file is stored under from the original file name shown to users.
+type downloadTaskFileInfo struct {
+	filePath        string
+	fileName        string
+	fileSize        uint64
+	fileSizeKnown   bool
+	contentType     string
+	checksum        string
+	finalURL        string
+	responseHeaders map[string]string
+}
+type fileSizeCounter struct {
+	fileSize uint64
+}
+
+func (f *fileSizeCounter) Write(p []byte) (int, error) {
+	f.fileSize += uint64(len(p))
+	return len(p), nil
 }
 
 func (d downloadTask) databaseDownloadTaskToProtoDownloadTask(downloadTask database.DownloadTask, account database.Account) *go_load.DownloadTask {
+	var expireTime int64
+	if downloadTask.ExpireTime != nil {
+		expireTime = downloadTask.ExpireTime.Unix()
+	}
+	// Tasks that have not finished downloading do not have file information yet.
+	fileInfo, _ := d.getDownloadTaskFileInfo(downloadTask)
 	return &go_load.DownloadTask{
 		Id: downloadTask.ID,
 		OfAccount: &go_load.Account{
 			Id:          account.ID,
 			AccountName: account.AccountName,
+			Role:        account.Role,
+			Disabled:    account.Disabled,
 		},
-		DownloadType:   downloadTask.DownloadType,
-		Url:            downloadTask.URL,
-		DownloadStatus: downloadTask.DownloadStatus,
+		DownloadType:    downloadTask.DownloadType,
+		Url:             downloadTask.URL,
+		DownloadStatus:  downloadTask.DownloadStatus,
+		ExpireTime:      expireTime,
+		FileName:        fileInfo.fileName,
+		FileSize:        fileInfo.fileSize,
+		ContentType:     fileInfo.contentType,
+		FinalUrl:        fileInfo.finalURL,
+		ResponseHeaders: fileInfo.responseHeaders,
+		OfWorkspaceId:   lo.FromPtr(downloadTask.OfWorkspaceID),
+	}
+}
+func (d downloadTask) databaseDownloadTaskListToProtoDownloadTaskList(
+	ctx context.Context,
+	downloadTaskList []database.DownloadTask,
+) ([]*go_load.DownloadTask, error) {
+	accountList, err := d.accountDataAccessor.GetAccountListByIDList(ctx, lo.Uniq(lo.Map(
+		downloadTaskList, func(item database.DownloadTask, _ int) uint64 { return item.OfAccountID },
+	)))
+	if err != nil {
+		return nil, err
+	}
+	accountMap := lo.KeyBy(accountList, func(item database.Account) uint64 { return item.ID })
+	return lo.Map(downloadTaskList, func(item database.DownloadTask, _ int) *go_load.DownloadTask {
+		return d.databaseDownloadTaskToProtoDownloadTask(item, accountMap[item.OfAccountID])
+	}), nil
+}
+
+// canAccessDownloadTask allows the account owning the download task, or the members of the workspace owning it with one
+// of the allowed roles.
+func canAccessDownloadTask(
+	ctx context.Context,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor,
+	accountID uint64,
+	downloadTask database.DownloadTask,
+	allowedWorkspaceRoleList []go_load.WorkspaceRole,
+) (bool, error) {
+	if downloadTask.OfWorkspaceID == nil {
+		return downloadTask.OfAccountID == accountID, nil
+	}
+	role, err := getWorkspaceRoleOfAccount(ctx, workspaceMemberDataAccessor, *downloadTask.OfWorkspaceID, accountID)
+	if err != nil {
+		return false, err
 	}
+	return lo.Contains(allowedWorkspaceRoleList, role), nil
 }
+func (d downloadTask) newDownloadTaskLifecycleEvent(
+	eventType producer.DownloadTaskLifecycleEventType,
+	downloadTask database.DownloadTask,
+) producer.DownloadTaskLifecycleEvent {
+	return producer.DownloadTaskLifecycleEvent{
+		SchemaVersion:  producer.DownloadTaskLifecycleEventSchemaVersion,
+		EventID:        uuid.NewString(),
+		EventType:      eventType,
+		EventTime:      time.Now(),
+		DownloadTaskID: downloadTask.ID,
+		OfAccountID:    downloadTask.OfAccountID,
+		OfWorkspaceID:  downloadTask.OfWorkspaceID,
+		URL:            downloadTask.URL,
+	}
+}
+
+func (d downloadTask) getDownloadTaskExpireTime(ctx context.Context, account database.Account, ttl time.Duration) (*time.Time, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.String("account_name", account.AccountName))
 
+	if ttl == 0 {
+		var err error
+		ttl, err = d.retentionConfig.GetTTLOfAccount(account.AccountName)
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to parse retention ttl")
+			return nil, status.Error(codes.Internal, "failed to parse retention ttl")
+		}
+	}
+	if ttl == 0 {
+		return nil, nil
+	}
+	expireTime := time.Now().Add(ttl)
+	return &expireTime, nil
+}
 func (d downloadTask) CreateDownloadTask(ctx context.Context, params CreateDownloadTaskParams) (CreateDownloadTaskOutput, error) {
-	accountID, _, err := d.tokenLogic.GetAccountIDAndExpireTime(ctx, params.Token)
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_CreateTasks)
 	if err != nil {
 		return CreateDownloadTaskOutput{}, err
 	}
@@ -116,14 +305,29 @@ func (d downloadTask) CreateDownloadTask(ctx context.Context, params CreateDownl
 	if err != nil {
 		return CreateDownloadTaskOutput{}, err
 	}
+	var workspaceID *uint64
+	if params.WorkspaceID != 0 {
+		if err = checkWorkspaceRoleOfAccount(
+			ctx, d.workspaceMemberDataAccessor, params.WorkspaceID, accountID, editorWorkspaceRoleList,
+		); err != nil {
+			return CreateDownloadTaskOutput{}, err
+		}
+		workspaceID = &params.WorkspaceID
+	}
+	expireTime, err := d.getDownloadTaskExpireTime(ctx, account, params.TTL)
+	if err != nil {
+		return CreateDownloadTaskOutput{}, err
+	}
 	downloadTask := database.DownloadTask{
 		OfAccountID:    accountID,
+		OfWorkspaceID:  workspaceID,
 		DownloadType:   params.DownloadType,
 		URL:            params.URL,
 		DownloadStatus: go_load.DownloadStatus_Pending,
 		Metadata: database.JSON{
 			Data: make(map[string]any),
 		},
+		ExpireTime: expireTime,
 	}
 	txErr := d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
 		downloadTaskID, createDownloadTaskErr := d.downloadTaskDataAccessor.
@@ -133,13 +337,13 @@ func (d downloadTask) CreateDownloadTask(ctx context.Context, params CreateDownl
 			return createDownloadTaskErr
 		}
 		downloadTask.ID = downloadTaskID
-		produceErr := d.downloadTaskCreatedProducer.Produce(ctx, producer.DownloadTaskCreated{
+		if enqueueErr := d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskCreated(ctx, producer.DownloadTaskCreated{
 			ID: downloadTaskID,
-		})
-		if produceErr != nil {
-			return produceErr
+		}); enqueueErr != nil {
+			return enqueueErr
 		}
-		return nil
+		return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(
+			ctx, d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeCreated, downloadTask))
 	})
 	if txErr != nil {
 		return CreateDownloadTaskOutput{}, txErr
@@ -149,10 +353,13 @@ func (d downloadTask) CreateDownloadTask(ctx context.Context, params CreateDownl
 	}, nil
 }
 func (d downloadTask) GetDownloadTaskList(ctx context.Context, params GetDownloadTaskListParams) (GetDownloadTaskListOutput, error) {
-	accountID, _, err := d.tokenLogic.GetAccountIDAndExpireTime(ctx, params.Token)
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_ReadOnly)
 	if err != nil {
 		return GetDownloadTaskListOutput{}, err
 	}
+	if params.WorkspaceID != 0 {
+		return d.getDownloadTaskListOfWorkspace(ctx, accountID, params)
+	}
 	account, err := d.accountDataAccessor.GetAccountByID(ctx, accountID)
 	if err != nil {
 		return GetDownloadTaskListOutput{}, err
@@ -172,12 +379,35 @@ func (d downloadTask) GetDownloadTaskList(ctx context.Context, params GetDownloa
 		}),
 	}, nil
 }
-func (d downloadTask) UpdateDownloadTask(ctx context.Context, params UpdateDownloadTaskParams) (UpdateDownloadTaskOutput, error) {
-	accountID, _, err := d.tokenLogic.GetAccountIDAndExpireTime(ctx, params.Token)
+func (d downloadTask) getDownloadTaskListOfWorkspace(
+	ctx context.Context,
+	accountID uint64,
+	params GetDownloadTaskListParams,
+) (GetDownloadTaskListOutput, error) {
+	if err := checkWorkspaceRoleOfAccount(
+		ctx, d.workspaceMemberDataAccessor, params.WorkspaceID, accountID, allWorkspaceRoleList,
+	); err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	totalDownloadTaskCount, err := d.downloadTaskDataAccessor.GetDownloadTaskCountOfWorkspace(ctx, params.WorkspaceID)
 	if err != nil {
-		return UpdateDownloadTaskOutput{}, err
+		return GetDownloadTaskListOutput{}, err
 	}
-	account, err := d.accountDataAccessor.GetAccountByID(ctx, accountID)
+	downloadTaskList, err := d.downloadTaskDataAccessor.GetDownloadTaskListOfWorkspace(ctx, params.WorkspaceID, params.Offset, params.Limit)
+	if err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	protoDownloadTaskList, err := d.databaseDownloadTaskListToProtoDownloadTaskList(ctx, downloadTaskList)
+	if err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	return GetDownloadTaskListOutput{
+		TotalDownloadTaskCount: totalDownloadTaskCount,
+		DownloadTaskList:       protoDownloadTaskList,
+	}, nil
+}
+func (d downloadTask) UpdateDownloadTask(ctx context.Context, params UpdateDownloadTaskParams) (UpdateDownloadTaskOutput, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_CreateTasks)
 	if err != nil {
 		return UpdateDownloadTaskOutput{}, err
 	}
@@ -187,9 +417,18 @@ func (d downloadTask) UpdateDownloadTask(ctx context.Context, params UpdateDownl
 		if getDownloadTaskWithXLockErr != nil {
 			return getDownloadTaskWithXLockErr
 		}
-		if downloadTask.OfAccountID != accountID {
+		canAccess, canAccessErr := canAccessDownloadTask(
+			ctx, d.workspaceMemberDataAccessor.WithDatabase(td), accountID, downloadTask, editorWorkspaceRoleList)
+		if canAccessErr != nil {
+			return canAccessErr
+		}
+		if !canAccess {
 			return status.Error(codes.PermissionDenied, "trying to update a download task the account does not own")
 		}
+		account, getAccountErr := d.accountDataAccessor.WithDatabase(td).GetAccountByID(ctx, downloadTask.OfAccountID)
+		if getAccountErr != nil {
+			return getAccountErr
+		}
 		downloadTask.URL = params.URL
 		output.DownloadTask = d.databaseDownloadTaskToProtoDownloadTask(downloadTask, account)
 		return d.downloadTaskDataAccessor.WithDatabase(td).UpdateDownloadTask(ctx, downloadTask)
@@ -200,7 +439,7 @@ func (d downloadTask) UpdateDownloadTask(ctx context.Context, params UpdateDownl
 	return output, nil
 }
 func (d downloadTask) DeleteDownloadTask(ctx context.Context, params DeleteDownloadTaskParams) error {
-	accountID, _, err := d.tokenLogic.GetAccountIDAndExpireTime(ctx, params.Token)
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_CreateTasks)
 	if err != nil {
 		return err
 	}
@@ -210,10 +449,24 @@ func (d downloadTask) DeleteDownloadTask(ctx context.Context, params DeleteDownl
 		if getDownloadTaskWithXLockErr != nil {
 			return getDownloadTaskWithXLockErr
 		}
-		if downloadTask.OfAccountID != accountID {
+		canAccess, canAccessErr := canAccessDownloadTask(
+			ctx, d.workspaceMemberDataAccessor.WithDatabase(td), accountID, downloadTask, editorWorkspaceRoleList)
+		if canAccessErr != nil {
+			return canAccessErr
+		}
+		if !canAccess {
 			return status.Error(codes.PermissionDenied, "trying to delete a download task the account does not own")
 		}
-		return d.downloadTaskDataAccessor.WithDatabase(td).DeleteDownloadTask(ctx, params.DownloadTaskID)
+		if deleteErr := d.webhookLogic.WithDatabase(td).
+			DeleteWebhookListOfDownloadTask(ctx, params.DownloadTaskID); deleteErr != nil {
+			return deleteErr
+		}
+		if deleteErr := d.downloadTaskDataAccessor.WithDatabase(td).
+			DeleteDownloadTask(ctx, params.DownloadTaskID); deleteErr != nil {
+			return deleteErr
+		}
+		return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(
+			ctx, d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeDeleted, downloadTask))
 	})
 }
 
@@ -236,6 +489,9 @@ func (d downloadTask) ExecuteAllPendingDownloadTask(ctx context.Context) error {
 	workerPool := workerpool.New(d.cronConfig.ExecuteAllPendingDownloadTask.ConcurrencyLimit)
 	for _, id := range pendingDownloadTaskIDList {
 		workerPool.Submit(func() {
+			if ctx.Err() != nil {
+				return
+			}
 			if executeDownloadTaskErr := d.ExecuteDownloadTask(ctx, id); executeDownloadTaskErr != nil {
 				logger.
 					With(zap.Uint64("download_task_id", id)).
@@ -248,6 +504,7 @@ func (d downloadTask) ExecuteAllPendingDownloadTask(ctx context.Context) error {
 	return nil
 }
 
+// updateDownloadTaskStatusFromPendingToDownloading takes the lease of the download task for this worker.
 func (d downloadTask) updateDownloadTaskStatusFromPendingToDownloading(ctx context.Context, id uint64) (bool, database.DownloadTask, error) {
 	var (
 		logger       = utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
@@ -270,34 +527,174 @@ func (d downloadTask) updateDownloadTaskStatusFromPendingToDownloading(ctx conte
 			updated = false
 			return nil
 		}
+		now := time.Now()
+		leaseExpireTime := now.Add(d.leaseTTL)
 		downloadTask.DownloadStatus = go_load.DownloadStatus_Downloading
+		downloadTask.WorkerID = &d.workerID
+		downloadTask.LeaseExpireTime = &leaseExpireTime
+		downloadTask.HeartbeatTime = &now
 		err = d.downloadTaskDataAccessor.WithDatabase(td).UpdateDownloadTask(ctx, downloadTask)
 		if err != nil {
 			logger.With(zap.Error(err)).Error("failed to update download task")
 			return err
 		}
+		err = d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(
+			ctx, d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeStarted, downloadTask))
+		if err != nil {
+			return err
+		}
 		updated = true
 		return nil
 	})
 	if txErr != nil {
-		return false, database.DownloadTask{}, err
+		return false, database.DownloadTask{}, txErr
 	}
 	return updated, downloadTask, nil
 }
 
+// updateLeasedDownloadTask finishes the download task if this worker still holds its lease, releasing the lease, and
+// publishes event if it is not nil. It reports false if the lease was lost, in which case the download task is left to
+// the worker that took it over.
+func (d downloadTask) updateLeasedDownloadTask(
+	ctx context.Context,
+	downloadTask database.DownloadTask,
+	event *producer.DownloadTaskLifecycleEvent,
+) (bool, error) {
+	updated := false
+	txErr := d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		leasedDownloadTask, err := d.downloadTaskDataAccessor.WithDatabase(td).GetDownloadTaskWithXLock(ctx, downloadTask.ID)
+		if err != nil {
+			return err
+		}
+		if leasedDownloadTask.DownloadStatus != go_load.DownloadStatus_Downloading ||
+			lo.FromPtr(leasedDownloadTask.WorkerID) != d.workerID {
+			return nil
+		}
+		downloadTask.WorkerID = nil
+		downloadTask.LeaseExpireTime = nil
+		downloadTask.HeartbeatTime = nil
+		if err = d.downloadTaskDataAccessor.WithDatabase(td).UpdateDownloadTask(ctx, downloadTask); err != nil {
+			return err
+		}
+		updated = true
+		if event == nil {
+			return nil
+		}
+		return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(ctx, *event)
+	})
+	if txErr != nil {
+		return false, txErr
+	}
+	return updated, nil
+}
+
+// renewDownloadTaskLease renews the lease of the download task every heartbeat interval until the returned function is
+// called, cancelling the download if the lease is lost. The returned function reports whether the lease was lost.
+func (d downloadTask) renewDownloadTaskLease(
+	ctx context.Context,
+	id uint64,
+	cancelDownload context.CancelFunc,
+) func() bool {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
+
+	stopChannel := make(chan struct{})
+	stoppedChannel := make(chan struct{})
+	leaseLost := false
+	go func() {
+		defer close(stoppedChannel)
+		ticker := time.NewTicker(d.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopChannel:
+				return
+			case <-ticker.C:
+			}
+			renewed, renewErr := d.downloadTaskDataAccessor.RenewDownloadTaskLease(ctx, id, d.workerID, time.Now().Add(d.leaseTTL))
+			if renewErr != nil {
+				logger.With(zap.Error(renewErr)).Warn("failed to renew download task lease, will retry")
+				continue
+			}
+			if !renewed {
+				logger.Error("download task lease lost, cancelling download")
+				leaseLost = true
+				cancelDownload()
+				return
+			}
+		}
+	}()
+	return func() bool {
+		close(stopChannel)
+		<-stoppedChannel
+		return leaseLost
+	}
+}
+
 func (d downloadTask) updateDownloadTaskStatusToFailed(ctx context.Context, downloadTask database.DownloadTask) {
 	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", downloadTask.ID))
 
 	downloadTask.DownloadStatus = go_load.DownloadStatus_Failed
-	updateDownloadTaskErr := d.downloadTaskDataAccessor.UpdateDownloadTask(ctx, downloadTask)
-	if updateDownloadTaskErr != nil {
-		logger.With(zap.Error(updateDownloadTaskErr)).Warn("failed to update download task status to failed")
+	failedEvent := d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeFailed, downloadTask)
+	updated, err := d.updateLeasedDownloadTask(ctx, downloadTask, &failedEvent)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to update download task status to failed")
+		return
+	}
+	if !updated {
+		logger.Warn("download task lease lost, will not update download task status to failed")
 	}
 }
 
+// updateDownloadTaskStatusToPending returns an interrupted download task to the pending download tasks, so that it is
+// downloaded again from the start by the next worker executing it. Nothing is published, since the download task
+// neither finished nor failed.
+func (d downloadTask) updateDownloadTaskStatusToPending(ctx context.Context, downloadTask database.DownloadTask) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", downloadTask.ID))
+
+	downloadTask.DownloadStatus = go_load.DownloadStatus_Pending
+	updated, err := d.updateLeasedDownloadTask(ctx, downloadTask, nil)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to update download task status to pending, leaving it until its lease expires")
+		return
+	}
+	if !updated {
+		logger.Warn("download task lease lost, will not update download task status to pending")
+		return
+	}
+	logger.Info("download task interrupted, returned it to pending")
+}
+
+// newDownloadProgressFunc publishes a progress_milestone lifecycle event for each milestone the download reaches, if the
+// size of the file is known. Failing to publish one does not fail the download.
+func (d downloadTask) newDownloadProgressFunc(ctx context.Context, downloadTask database.DownloadTask) DownloadProgressFunc {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", downloadTask.ID))
+
+	nextMilestoneIndex := 0
+	return func(downloadedSize uint64, totalSize int64) {
+		if totalSize <= 0 {
+			return
+		}
+		progressPercent := downloadedSize * 100 / uint64(totalSize)
+		for nextMilestoneIndex < len(downloadProgressMilestonePercentList) &&
+			progressPercent >= downloadProgressMilestonePercentList[nextMilestoneIndex] {
+			event := d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeProgressMilestone, downloadTask)
+			event.ProgressPercent = downloadProgressMilestonePercentList[nextMilestoneIndex]
+			event.DownloadedSize = downloadedSize
+			nextMilestoneIndex++
+			if err := d.outboxLogic.EnqueueDownloadTaskLifecycleEvent(ctx, event); err != nil {
+				logger.With(zap.Error(err)).Warn("failed to publish download progress milestone")
+			}
+		}
+	}
+}
+
+// ExecuteDownloadTask interrupts the download when ctx is cancelled, returning the download task to pending.
 func (d downloadTask) ExecuteDownloadTask(ctx context.Context, id uint64) error {
 	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	updated, downloadTask, err := d.updateDownloadTaskStatusFromPendingToDownloading(ctx, id)
 	if err != nil {
 		return err
@@ -305,6 +702,10 @@ func (d downloadTask) ExecuteDownloadTask(ctx context.Context, id uint64) error
 	if !updated {
 		return nil
 	}
+	downloadCtx, cancelDownload := context.WithCancel(ctx)
+	defer cancelDownload()
+	// The download task must still be updated once the download is interrupted.
+	ctx = context.WithoutCancel(ctx)
 	var downloader Downloader
 	//nolint:exhaustive // No need to check unsupported download type
 	switch downloadTask.DownloadType {
@@ -315,58 +716,420 @@ func (d downloadTask) ExecuteDownloadTask(ctx context.Context, id uint64) error
 		d.updateDownloadTaskStatusToFailed(ctx, downloadTask)
 		return nil
 	}
-	fileName := fmt.Sprintf("download_file_%d", id)
+	fileName := fmt.Sprintf(downloadTaskFileNameFormat, id)
 	fileWriteCloser, err := d.fileClient.Write(ctx, fileName)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to get download file writer")
 		d.updateDownloadTaskStatusToFailed(ctx, downloadTask)
 		return err
 	}
-	defer fileWriteCloser.Close()
-	metadata, err := downloader.Download(ctx, fileWriteCloser)
+	// A downloaded file is closed while the lease is still renewed, so it is only closed here if the download did not end.
+	fileClosed := false
+	defer func() {
+		if !fileClosed {
+			_ = fileWriteCloser.Close()
+		}
+	}()
+	stopRenewingDownloadTaskLease := d.renewDownloadTaskLease(ctx, id, cancelDownload)
+	checksumHash := sha256.New()
+	fileSizeCounter := new(fileSizeCounter)
+	metadata, err := downloader.Download(
+		downloadCtx,
+		io.MultiWriter(fileWriteCloser, checksumHash, fileSizeCounter),
+		d.newDownloadProgressFunc(ctx, downloadTask),
+	)
+	if err == nil {
+		// Closing may still write the end of the file, so the download task only succeeds once it did.
+		fileClosed = true
+		if closeErr := fileWriteCloser.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close download file: %w", closeErr)
+		}
+	}
+	if stopRenewingDownloadTaskLease() {
+		logger.Warn("download task lease lost, leaving download task to the worker that took it over")
+		return nil
+	}
+	if err != nil && downloadCtx.Err() != nil {
+		d.updateDownloadTaskStatusToPending(ctx, downloadTask)
+		return nil
+	}
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to download")
 		d.updateDownloadTaskStatusToFailed(ctx, downloadTask)
 		return err
 	}
 	metadata[downloadTaskMetadataFieldNameFileName] = fileName
+	metadata[downloadTaskMetadataFieldNameFileSize] = fileSizeCounter.fileSize
+	metadata[downloadTaskMetadataFieldNameChecksum] = hex.EncodeToString(checksumHash.Sum(nil))
 	downloadTask.DownloadStatus = go_load.DownloadStatus_Success
 	downloadTask.Metadata = database.JSON{
 		Data: metadata,
 	}
-	err = d.downloadTaskDataAccessor.UpdateDownloadTask(ctx, downloadTask)
+	succeededEvent := d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeSucceeded, downloadTask)
+	succeededEvent.DownloadedSize = fileSizeCounter.fileSize
+	succeededEvent.FileName = fileName
+	if originalFileName, _ := metadata[HTTPMetadataKeyOriginalFileName].(string); originalFileName != "" {
+		succeededEvent.FileName = originalFileName
+	}
+	succeededEvent.ContentType, _ = metadata[HTTPMetadataKeyContentType].(string)
+	succeededEvent.Checksum, _ = metadata[downloadTaskMetadataFieldNameChecksum].(string)
+	updated, err = d.updateLeasedDownloadTask(ctx, downloadTask, &succeededEvent)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to update download task status to success")
 		return err
 	}
+	if !updated {
+		logger.Warn("download task lease lost, leaving download task to the worker that took it over")
+		return nil
+	}
 	logger.Info("download task executed successfully")
 	return nil
 }
-func (d downloadTask) GetDownloadTaskFile(ctx context.Context, params GetDownloadTaskFileParams) (io.ReadCloser, error) {
-	accountID, _, err := d.tokenLogic.GetAccountIDAndExpireTime(ctx, params.Token)
+func (d downloadTask) isDownloadTaskExpired(downloadTask database.DownloadTask) bool {
+	return downloadTask.DownloadStatus == go_load.DownloadStatus_Expired ||
+		(downloadTask.ExpireTime != nil && !time.Now().Before(*downloadTask.ExpireTime))
+}
+func (d downloadTask) getSuccessfulDownloadTaskOfAccount(ctx context.Context, downloadTaskID uint64) (database.DownloadTask, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_FetchFiles)
 	if err != nil {
-		return nil, err
+		return database.DownloadTask{}, err
 	}
-	downloadTask, err := d.downloadTaskDataAccessor.GetDownloadTask(ctx, params.DownloadTaskID)
+	downloadTask, err := d.downloadTaskDataAccessor.GetDownloadTask(ctx, downloadTaskID)
 	if err != nil {
-		return nil, err
+		return database.DownloadTask{}, err
 	}
-	if downloadTask.OfAccountID != accountID {
-		return nil, status.Error(codes.PermissionDenied, "trying to get file of a download task the account does not own")
+	canAccess, err := canAccessDownloadTask(ctx, d.workspaceMemberDataAccessor, accountID, downloadTask, allWorkspaceRoleList)
+	if err != nil {
+		return database.DownloadTask{}, err
+	}
+	if !canAccess {
+		return database.DownloadTask{}, status.Error(codes.PermissionDenied, "trying to get file of a download task the account does not own")
+	}
+	if d.isDownloadTaskExpired(downloadTask) {
+		return database.DownloadTask{}, errDownloadTaskFileExpired
 	}
 	if downloadTask.DownloadStatus != go_load.DownloadStatus_Success {
-		return nil, status.Error(codes.InvalidArgument, "download task does not have status of success")
+		return database.DownloadTask{}, status.Error(codes.InvalidArgument, "download task does not have status of success")
 	}
+	return downloadTask, nil
+}
+
+// getDownloadTaskFileInfo reads the file information recorded in the metadata of a download task. Metadata read back
+// from the database is JSON-decoded, so numbers come back as float64 and objects as map[string]any.
+func (d downloadTask) getDownloadTaskFileInfo(downloadTask database.DownloadTask) (downloadTaskFileInfo, error) {
 	downloadTaskMetadata, ok := downloadTask.Metadata.Data.(map[string]any)
 	if !ok {
-		return nil, status.Error(codes.Internal, "download task metadata is not a map[string]any")
+		return downloadTaskFileInfo{}, status.Error(codes.Internal, "download task metadata is not a map[string]any")
 	}
-	fileName, ok := downloadTaskMetadata[downloadTaskMetadataFieldNameFileName]
+	filePath, ok := downloadTaskMetadata[downloadTaskMetadataFieldNameFileName].(string)
 	if !ok {
-		return nil, status.Error(codes.Internal, "download task metadata does not contain file name")
+		return downloadTaskFileInfo{}, status.Error(codes.Internal, "download task metadata does not contain file name")
+	}
+	fileInfo := downloadTaskFileInfo{
+		filePath: filePath,
+		fileName: filePath,
+	}
+	if originalFileName, _ := downloadTaskMetadata[HTTPMetadataKeyOriginalFileName].(string); originalFileName != "" {
+		fileInfo.fileName = originalFileName
+	}
+	fileInfo.contentType, _ = downloadTaskMetadata[HTTPMetadataKeyContentType].(string)
+	fileInfo.checksum, _ = downloadTaskMetadata[downloadTaskMetadataFieldNameChecksum].(string)
+	fileInfo.finalURL, _ = downloadTaskMetadata[HTTPMetadataKeyFinalURL].(string)
+	switch fileSize := downloadTaskMetadata[downloadTaskMetadataFieldNameFileSize].(type) {
+	case float64:
+		fileInfo.fileSize, fileInfo.fileSizeKnown = uint64(fileSize), true
+	case uint64:
+		fileInfo.fileSize, fileInfo.fileSizeKnown = fileSize, true
+	}
+	switch responseHeaders := downloadTaskMetadata[HTTPMetadataKeyResponseHeaders].(type) {
+	case map[string]string:
+		fileInfo.responseHeaders = responseHeaders
+	case map[string]any:
+		fileInfo.responseHeaders = make(map[string]string, len(responseHeaders))
+		for key, value := range responseHeaders {
+			if stringValue, isString := value.(string); isString {
+				fileInfo.responseHeaders[key] = stringValue
+			}
+		}
+	}
+	return fileInfo, nil
+}
+func (d downloadTask) openDownloadTaskFile(
+	ctx context.Context,
+	downloadTask database.DownloadTask,
+	offset uint64,
+	length uint64,
+) (GetDownloadTaskFileOutput, error) {
+	fileInfo, err := d.getDownloadTaskFileInfo(downloadTask)
+	if err != nil {
+		return GetDownloadTaskFileOutput{}, err
+	}
+	output := GetDownloadTaskFileOutput{
+		FileName:      fileInfo.fileName,
+		FileSize:      fileInfo.fileSize,
+		FileSizeKnown: fileInfo.fileSizeKnown,
+		ContentType:   fileInfo.contentType,
+		Checksum:      fileInfo.checksum,
+		FinalURL:      fileInfo.finalURL,
+	}
+	if !fileInfo.fileSizeKnown {
+		sectionLength := int64(-1)
+		if length > 0 {
+			sectionLength = int64(length)
+		}
+		output.Reader = file.NewRangeReadSeekCloser(ctx, d.fileClient, fileInfo.filePath, int64(offset), sectionLength)
+		return output, nil
+	}
+	if offset > fileInfo.fileSize {
+		return GetDownloadTaskFileOutput{}, status.Error(codes.OutOfRange, "offset is beyond the end of the file")
+	}
+	if remainingLength := fileInfo.fileSize - offset; length == 0 || length > remainingLength {
+		length = remainingLength
+	}
+	output.Reader = file.NewRangeReadSeekCloser(ctx, d.fileClient, fileInfo.filePath, int64(offset), int64(length))
+	return output, nil
+}
+func (d downloadTask) GetDownloadTaskFile(ctx context.Context, params GetDownloadTaskFileParams) (GetDownloadTaskFileOutput, error) {
+	downloadTask, err := d.getSuccessfulDownloadTaskOfAccount(ctx, params.DownloadTaskID)
+	if err != nil {
+		return GetDownloadTaskFileOutput{}, err
+	}
+	return d.openDownloadTaskFile(ctx, downloadTask, params.Offset, params.Length)
+}
+func (d downloadTask) GetDownloadTaskFileURL(ctx context.Context, params GetDownloadTaskFileURLParams) (GetDownloadTaskFileURLOutput, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("download_task_id", params.DownloadTaskID))
+
+	downloadTask, err := d.getSuccessfulDownloadTaskOfAccount(ctx, params.DownloadTaskID)
+	if err != nil {
+		return GetDownloadTaskFileURLOutput{}, err
+	}
+	fileInfo, err := d.getDownloadTaskFileInfo(downloadTask)
+	if err != nil {
+		return GetDownloadTaskFileURLOutput{}, err
+	}
+	expiresIn, err := d.downloadConfig.FileURL.GetExpiresInDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse file url expires_in")
+		return GetDownloadTaskFileURLOutput{}, status.Error(codes.Internal, "failed to parse file url expires_in")
+	}
+	expireTime := time.Now().Add(expiresIn)
+	presignedURL, err := d.fileClient.GetPresignedURL(ctx, fileInfo.filePath, file.PresignedURLOptions{
+		ExpiresIn:          expiresIn,
+		ContentType:        fileInfo.contentType,
+		ContentDisposition: mime.FormatMediaType("attachment", map[string]string{"filename": fileInfo.fileName}),
+	})
+	if err == nil {
+		return GetDownloadTaskFileURLOutput{
+			URL:        presignedURL,
+			ExpireTime: expireTime,
+		}, nil
+	}
+	if !errors.Is(err, file.ErrPresignedURLNotSupported) {
+		return GetDownloadTaskFileURLOutput{}, err
+	}
+	return GetDownloadTaskFileURLOutput{
+		URL:        d.fileURLSigner.Sign(ctx, params.DownloadTaskID, expireTime),
+		ExpireTime: expireTime,
+	}, nil
+}
+func (d downloadTask) GetDownloadTaskFileWithSignature(
+	ctx context.Context,
+	params GetDownloadTaskFileWithSignatureParams,
+) (GetDownloadTaskFileOutput, error) {
+	if err := d.fileURLSigner.Verify(ctx, params.DownloadTaskID, params.ExpireTime, params.Signature); err != nil {
+		return GetDownloadTaskFileOutput{}, err
+	}
+	downloadTask, err := d.downloadTaskDataAccessor.GetDownloadTask(ctx, params.DownloadTaskID)
+	if err != nil {
+		return GetDownloadTaskFileOutput{}, err
 	}
-	return d.fileClient.Read(ctx, fileName.(string))
+	if d.isDownloadTaskExpired(downloadTask) {
+		return GetDownloadTaskFileOutput{}, errDownloadTaskFileExpired
+	}
+	if downloadTask.DownloadStatus != go_load.DownloadStatus_Success {
+		return GetDownloadTaskFileOutput{}, status.Error(codes.InvalidArgument, "download task does not have status of success")
+	}
+	return d.openDownloadTaskFile(ctx, downloadTask, 0, 0)
+}
+
+// UpdateExpiredDownloadingDownloadTaskStatusToPending only takes back the downloading tasks whose lease has expired,
+// so that tasks still being downloaded by a live worker are left alone.
+func (d downloadTask) UpdateExpiredDownloadingDownloadTaskStatusToPending(ctx context.Context) error {
+	return d.downloadTaskDataAccessor.UpdateExpiredDownloadingDownloadTaskStatusToPending(ctx, time.Now())
 }
-func (d downloadTask) UpdateDownloadingAndFailedDownloadTaskStatusToPending(ctx context.Context) error {
-	return d.downloadTaskDataAccessor.UpdateDownloadingAndFailedDownloadTaskStatusToPending(ctx)
+func (d downloadTask) deleteExpiredDownloadTaskFile(ctx context.Context, id uint64) error {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
+
+	expired := false
+	if err := d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		downloadTask, err := d.downloadTaskDataAccessor.WithDatabase(td).GetDownloadTaskWithXLock(ctx, id)
+		if err != nil {
+			if errors.Is(err, database.ErrDownloadTaskNotFound) {
+				logger.Warn("download task not found, will skip")
+				return nil
+			}
+			return err
+		}
+		if downloadTask.DownloadStatus == go_load.DownloadStatus_Downloading ||
+			downloadTask.DownloadStatus == go_load.DownloadStatus_Expired ||
+			!d.isDownloadTaskExpired(downloadTask) {
+			logger.Info("download task is no longer eligible for expiry, will skip")
+			return nil
+		}
+		downloadTask.DownloadStatus = go_load.DownloadStatus_Expired
+		if err = d.downloadTaskDataAccessor.WithDatabase(td).UpdateDownloadTask(ctx, downloadTask); err != nil {
+			return err
+		}
+		expired = true
+		return nil
+	}); err != nil {
+		return err
+	}
+	// Failed tasks may have left a partially written file behind, so the file is deleted regardless of status.
+	if expired {
+		d.deleteDownloadTaskFile(ctx, id)
+	}
+	return nil
+}
+func (d downloadTask) DeleteExpiredDownloadTaskFile(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, d.logger)
+
+	expiredDownloadTaskIDList, err := d.downloadTaskDataAccessor.GetExpiredDownloadTaskIDList(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(expiredDownloadTaskIDList) == 0 {
+		logger.Info("no expired download task found")
+		return nil
+	}
+
+	logger.
+		With(zap.Int("len(expired_download_task_id_list)", len(expiredDownloadTaskIDList))).
+		Info("expired download task found")
+
+	for _, id := range expiredDownloadTaskIDList {
+		if deleteErr := d.deleteExpiredDownloadTaskFile(ctx, id); deleteErr != nil {
+			logger.
+				With(zap.Uint64("download_task_id", id)).
+				With(zap.Error(deleteErr)).
+				Error("failed to delete expired download task file")
+		}
+	}
+	return nil
+}
+
+// deleteDownloadTaskFile is called once the download task is deleted or expired for good, so that a rolled back
+// transaction does not leave a download task without its file. A file failing to be deleted is only left behind.
+func (d downloadTask) deleteDownloadTaskFile(ctx context.Context, id uint64) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("id", id))
+
+	if err := d.fileClient.Delete(ctx, fmt.Sprintf(downloadTaskFileNameFormat, id)); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to delete download task file, leaving it behind")
+	}
+}
+
+// DeleteDownloadTaskListOfAccount deletes the download tasks owned by the account alone along with their stored files,
+// leaving the ones owned by workspaces to their members. Each task is deleted in its own transaction, and its file once
+// that has committed, so a failure part way leaves the remaining tasks to be deleted by a retry.
+func (d downloadTask) DeleteDownloadTaskListOfAccount(ctx context.Context, accountID uint64) error {
+	downloadTaskIDList, err := d.downloadTaskDataAccessor.GetDownloadTaskIDListOfAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	for _, id := range downloadTaskIDList {
+		if err = d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+			downloadTask, getDownloadTaskWithXLockErr := d.downloadTaskDataAccessor.WithDatabase(td).
+				GetDownloadTaskWithXLock(ctx, id)
+			if getDownloadTaskWithXLockErr != nil {
+				return getDownloadTaskWithXLockErr
+			}
+			if deleteErr := d.webhookLogic.WithDatabase(td).DeleteWebhookListOfDownloadTask(ctx, id); deleteErr != nil {
+				return deleteErr
+			}
+			if deleteErr := d.downloadTaskDataAccessor.WithDatabase(td).DeleteDownloadTask(ctx, id); deleteErr != nil {
+				return deleteErr
+			}
+			return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(
+				ctx, d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeDeleted, downloadTask))
+		}); err != nil {
+			return err
+		}
+		d.deleteDownloadTaskFile(ctx, id)
+	}
+	return nil
+}
+func (d downloadTask) GetAllDownloadTaskList(ctx context.Context, params GetAllDownloadTaskListParams) (GetDownloadTaskListOutput, error) {
+	totalDownloadTaskCount, err := d.downloadTaskDataAccessor.GetDownloadTaskCount(ctx)
+	if err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	downloadTaskList, err := d.downloadTaskDataAccessor.GetDownloadTaskList(ctx, params.Offset, params.Limit)
+	if err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	protoDownloadTaskList, err := d.databaseDownloadTaskListToProtoDownloadTaskList(ctx, downloadTaskList)
+	if err != nil {
+		return GetDownloadTaskListOutput{}, err
+	}
+	return GetDownloadTaskListOutput{
+		TotalDownloadTaskCount: totalDownloadTaskCount,
+		DownloadTaskList:       protoDownloadTaskList,
+	}, nil
+}
+
+// RequeueDownloadTask sends a failed or stuck pending download task to be downloaded again.
+func (d downloadTask) RequeueDownloadTask(ctx context.Context, downloadTaskID uint64) (RequeueDownloadTaskOutput, error) {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("download_task_id", downloadTaskID))
+
+	output := RequeueDownloadTaskOutput{}
+	txErr := d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		downloadTask, err := d.downloadTaskDataAccessor.WithDatabase(td).GetDownloadTaskWithXLock(ctx, downloadTaskID)
+		if err != nil {
+			return err
+		}
+		if downloadTask.DownloadStatus != go_load.DownloadStatus_Failed &&
+			downloadTask.DownloadStatus != go_load.DownloadStatus_Pending {
+			return status.Error(codes.FailedPrecondition, "only failed or pending download tasks can be requeued")
+		}
+		account, err := d.accountDataAccessor.WithDatabase(td).GetAccountByID(ctx, downloadTask.OfAccountID)
+		if err != nil {
+			return err
+		}
+		downloadTask.DownloadStatus = go_load.DownloadStatus_Pending
+		if err = d.downloadTaskDataAccessor.WithDatabase(td).UpdateDownloadTask(ctx, downloadTask); err != nil {
+			return err
+		}
+		output.DownloadTask = d.databaseDownloadTaskToProtoDownloadTask(downloadTask, account)
+		return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskCreated(ctx, producer.DownloadTaskCreated{
+			ID: downloadTaskID,
+		})
+	})
+	if txErr != nil {
+		return RequeueDownloadTaskOutput{}, txErr
+	}
+	logger.Info("requeued download task")
+	return output, nil
+}
+func (d downloadTask) DeleteAnyDownloadTask(ctx context.Context, downloadTaskID uint64) error {
+	logger := utils.LoggerWithContext(ctx, d.logger).With(zap.Uint64("download_task_id", downloadTaskID))
+
+	if err := d.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		downloadTask, err := d.downloadTaskDataAccessor.WithDatabase(td).GetDownloadTaskWithXLock(ctx, downloadTaskID)
+		if err != nil {
+			return err
+		}
+		if err = d.webhookLogic.WithDatabase(td).DeleteWebhookListOfDownloadTask(ctx, downloadTaskID); err != nil {
+			return err
+		}
+		if err = d.downloadTaskDataAccessor.WithDatabase(td).DeleteDownloadTask(ctx, downloadTaskID); err != nil {
+			return err
+		}
+		return d.outboxLogic.WithDatabase(td).EnqueueDownloadTaskLifecycleEvent(
+			ctx, d.newDownloadTaskLifecycleEvent(producer.DownloadTaskLifecycleEventTypeDeleted, downloadTask))
+	}); err != nil {
+		return err
+	}
+	d.deleteDownloadTaskFile(ctx, downloadTaskID)
+	logger.Info("deleted download task")
+	return nil
 }
@@ -4,19 +4,50 @@ import (
 	"GoLoad/internal/utils"
 	"context"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 )
 
 const (
-	HTTPResponseHeaderContentType = "Content-Type"
-	HTTPMetadataKeyContentType    = "content-type"
+	HTTPResponseHeaderContentType        = "Content-Type"
+	HTTPResponseHeaderContentDisposition = "Content-Disposition"
+	HTTPResponseHeaderSetCookie          = "Set-Cookie"
+	HTTPMetadataKeyContentType           = "content-type"
+	HTTPMetadataKeyOriginalFileName      = "original-file-name"
+	HTTPMetadataKeyFinalURL              = "final-url"
+	HTTPMetadataKeyResponseHeaders       = "response-headers"
+
+	maxOriginalFileNameLength = 255
 )
 
+// DownloadProgressFunc is called after each write with the number of bytes downloaded so far and the total size of the
+// file, or -1 if it is not known in advance.
+type DownloadProgressFunc func(downloadedSize uint64, totalSize int64)
 type Downloader interface {
-	Download(ctx context.Context, writer io.Writer) (map[string]any, error)
+	// Download calls progressFunc if it is not nil.
+	Download(ctx context.Context, writer io.Writer, progressFunc DownloadProgressFunc) (map[string]any, error)
+}
+type progressWriter struct {
+	writer         io.Writer
+	downloadedSize uint64
+	totalSize      int64
+	progressFunc   DownloadProgressFunc
 }
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	writtenSize, err := p.writer.Write(data)
+	p.downloadedSize += uint64(writtenSize)
+	p.progressFunc(p.downloadedSize, p.totalSize)
+	return writtenSize, err
+}
+
 type HTTPDownloader struct {
 	url    string
 	logger *zap.Logger
@@ -28,7 +59,56 @@ func NewHTTPDownloader(url string, logger *zap.Logger) Downloader {
 		logger: logger,
 	}
 }
-func (h HTTPDownloader) Download(ctx context.Context, writer io.Writer) (map[string]any, error) {
+
+// sanitizeFileName strips directory components and characters that are unsafe in file names on common platforms,
+// returning an empty string if nothing usable is left.
+func sanitizeFileName(fileName string) string {
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	if fileName == "/" {
+		return ""
+	}
+	fileName = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, fileName)
+	fileName = strings.Trim(fileName, " .")
+	for len(fileName) > maxOriginalFileNameLength {
+		_, lastRuneSize := utf8.DecodeLastRuneInString(fileName)
+		fileName = fileName[:len(fileName)-lastRuneSize]
+	}
+	return fileName
+}
+
+// getOriginalFileName prefers the file name given in the Content-Disposition header, falling back to the last
+// segment of the URL path.
+func getOriginalFileName(response *http.Response) string {
+	if _, params, err := mime.ParseMediaType(response.Header.Get(HTTPResponseHeaderContentDisposition)); err == nil {
+		if fileName := sanitizeFileName(params["filename"]); fileName != "" {
+			return fileName
+		}
+	}
+	if response.Request == nil || response.Request.URL == nil {
+		return ""
+	}
+	urlPath, err := url.PathUnescape(response.Request.URL.EscapedPath())
+	if err != nil {
+		urlPath = response.Request.URL.Path
+	}
+	return sanitizeFileName(urlPath)
+}
+func getResponseHeaders(response *http.Response) map[string]string {
+	responseHeaders := make(map[string]string, len(response.Header))
+	for key, values := range response.Header {
+		if key == HTTPResponseHeaderSetCookie {
+			continue
+		}
+		responseHeaders[key] = strings.Join(values, ", ")
+	}
+	return responseHeaders
+}
+func (h HTTPDownloader) Download(ctx context.Context, writer io.Writer, progressFunc DownloadProgressFunc) (map[string]any, error) {
 	logger := utils.LoggerWithContext(ctx, h.logger)
 
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, http.NoBody)
@@ -42,13 +122,20 @@ func (h HTTPDownloader) Download(ctx context.Context, writer io.Writer) (map[str
 		return nil, err
 	}
 	defer response.Body.Close()
+	if progressFunc != nil {
+		writer = &progressWriter{writer: writer, totalSize: response.ContentLength, progressFunc: progressFunc}
+	}
 	_, err = io.Copy(writer, response.Body)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to read response and write to writer")
 		return nil, err
 	}
+	// The response request is the last one made after following redirects.
 	metadata := map[string]any{
-		HTTPMetadataKeyContentType: response.Header.Get(HTTPResponseHeaderContentType),
+		HTTPMetadataKeyContentType:      response.Header.Get(HTTPResponseHeaderContentType),
+		HTTPMetadataKeyOriginalFileName: getOriginalFileName(response),
+		HTTPMetadataKeyFinalURL:         response.Request.URL.String(),
+		HTTPMetadataKeyResponseHeaders:  getResponseHeaders(response),
 	}
 	return metadata, nil
 }
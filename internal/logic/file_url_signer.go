@@ -0,0 +1,77 @@
+package logic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/utils"
+)
+
+const (
+	DownloadTaskFileURLQueryExpireTime = "expire_time"
+	DownloadTaskFileURLQuerySignature  = "signature"
+	downloadTaskFileURLPathFormat      = "/download-task-files/%d"
+	// minFileURLSigningSecretLength keeps the signing secret from being guessed, which would let anyone sign URLs.
+	minFileURLSigningSecretLength = 32
+)
+
+var (
+	errInvalidFileURLSignature = status.Error(codes.PermissionDenied, "invalid file url signature")
+	errFileURLExpired          = status.Error(codes.PermissionDenied, "file url has expired")
+)
+
+type FileURLSigner interface {
+	Sign(ctx context.Context, downloadTaskID uint64, expireTime time.Time) string
+	Verify(ctx context.Context, downloadTaskID uint64, expireTimeUnix int64, signature string) error
+}
+type fileURLSigner struct {
+	baseURL       string
+	signingSecret []byte
+	logger        *zap.Logger
+}
+
+func NewFileURLSigner(downloadConfig configs.Download, logger *zap.Logger) (FileURLSigner, error) {
+	if len(downloadConfig.FileURL.SigningSecret) < minFileURLSigningSecretLength {
+		return nil, fmt.Errorf("download file_url signing_secret must be at least %d bytes", minFileURLSigningSecretLength)
+	}
+	return &fileURLSigner{
+		baseURL:       strings.TrimSuffix(downloadConfig.FileURL.BaseURL, "/"),
+		signingSecret: []byte(downloadConfig.FileURL.SigningSecret),
+		logger:        logger,
+	}, nil
+}
+func (f fileURLSigner) getSignature(downloadTaskID uint64, expireTimeUnix int64) string {
+	mac := hmac.New(sha256.New, f.signingSecret)
+	fmt.Fprintf(mac, "%d:%d", downloadTaskID, expireTimeUnix)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+func (f fileURLSigner) Sign(_ context.Context, downloadTaskID uint64, expireTime time.Time) string {
+	query := make(url.Values)
+	query.Set(DownloadTaskFileURLQueryExpireTime, fmt.Sprint(expireTime.Unix()))
+	query.Set(DownloadTaskFileURLQuerySignature, f.getSignature(downloadTaskID, expireTime.Unix()))
+	return f.baseURL + fmt.Sprintf(downloadTaskFileURLPathFormat, downloadTaskID) + "?" + query.Encode()
+}
+func (f fileURLSigner) Verify(ctx context.Context, downloadTaskID uint64, expireTimeUnix int64, signature string) error {
+	logger := utils.LoggerWithContext(ctx, f.logger).With(zap.Uint64("download_task_id", downloadTaskID))
+
+	expectedSignature := f.getSignature(downloadTaskID, expireTimeUnix)
+	if !hmac.Equal([]byte(expectedSignature), []byte(signature)) {
+		logger.Warn("invalid file url signature")
+		return errInvalidFileURLSignature
+	}
+	if time.Now().After(time.Unix(expireTimeUnix, 0)) {
+		return errFileURLExpired
+	}
+	return nil
+}
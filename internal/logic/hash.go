@@ -2,18 +2,38 @@ package logic
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
 	"GoLoad/internal/configs"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// argon2idHashPrefix starts hashes in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<base64 salt>$<base64 key>.
+const argon2idHashPrefix = "$argon2id$"
+
+type argon2idHash struct {
+	params configs.Argon2id
+	salt   []byte
+	key    []byte
+}
+
+// Hash stores the algorithm and its parameters in the hash string, so hashes made with earlier settings can still be
+// checked after the settings change.
 type Hash interface {
 	Hash(ctx context.Context, data string) (string, error)
 	IsHashEqual(ctx context.Context, data string, hashed string) (bool, error)
+	// NeedsRehash reports whether hashed was not made with the current algorithm and parameters.
+	NeedsRehash(ctx context.Context, hashed string) bool
 }
 type hash struct {
 	authConfig configs.Auth
@@ -24,14 +44,42 @@ func NewHash(authConfig configs.Auth) Hash {
 		authConfig: authConfig,
 	}
 }
+func (h hash) getAlgorithm() configs.HashAlgorithm {
+	if h.authConfig.Hash.Algorithm == "" {
+		return configs.HashAlgorithmBcrypt
+	}
+	return h.authConfig.Hash.Algorithm
+}
 func (h hash) Hash(ctx context.Context, data string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(data), h.authConfig.Hash.Cost)
-	if err != nil {
-		return "", status.Error(codes.Internal, "failed to hash data")
+	switch h.getAlgorithm() {
+	case configs.HashAlgorithmBcrypt:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(data), h.authConfig.Hash.Cost)
+		if err != nil {
+			return "", status.Error(codes.Internal, "failed to hash data")
+		}
+		return string(hashed), nil
+	case configs.HashAlgorithmArgon2id:
+		salt := make([]byte, h.authConfig.Hash.Argon2id.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", status.Error(codes.Internal, "failed to hash data")
+		}
+		params := h.authConfig.Hash.Argon2id
+		key := argon2.IDKey([]byte(data), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		return encodeArgon2idHash(argon2idHash{params: params, salt: salt, key: key}), nil
+	default:
+		return "", status.Error(codes.Internal, "unsupported hash algorithm")
 	}
-	return string(hashed), nil
 }
 func (h hash) IsHashEqual(ctx context.Context, data string, hashed string) (bool, error) {
+	if strings.HasPrefix(hashed, argon2idHashPrefix) {
+		decodedHash, err := decodeArgon2idHash(hashed)
+		if err != nil {
+			return false, status.Error(codes.Internal, "failed to check if data equal hash")
+		}
+		params := decodedHash.params
+		key := argon2.IDKey([]byte(data), decodedHash.salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, decodedHash.key) == 1, nil
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(data)); err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
 			return false, nil
@@ -40,3 +88,58 @@ func (h hash) IsHashEqual(ctx context.Context, data string, hashed string) (bool
 	}
 	return true, nil
 }
+func (h hash) NeedsRehash(ctx context.Context, hashed string) bool {
+	switch h.getAlgorithm() {
+	case configs.HashAlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(hashed))
+		return err != nil || cost != h.authConfig.Hash.Cost
+	case configs.HashAlgorithmArgon2id:
+		decodedHash, err := decodeArgon2idHash(hashed)
+		return err != nil || decodedHash.params != h.authConfig.Hash.Argon2id
+	default:
+		return false
+	}
+}
+func encodeArgon2idHash(decodedHash argon2idHash) string {
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idHashPrefix,
+		argon2.Version,
+		decodedHash.params.Memory,
+		decodedHash.params.Time,
+		decodedHash.params.Threads,
+		base64.RawStdEncoding.EncodeToString(decodedHash.salt),
+		base64.RawStdEncoding.EncodeToString(decodedHash.key),
+	)
+}
+func decodeArgon2idHash(hashed string) (argon2idHash, error) {
+	// Splitting "$argon2id$v=..$m=..$salt$key" gives an empty first part.
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2idHash{}, errors.New("invalid argon2id hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2idHash{}, fmt.Errorf("invalid argon2id hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return argon2idHash{}, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+	decodedHash := argon2idHash{}
+	if _, err := fmt.Sscanf(
+		parts[3], "m=%d,t=%d,p=%d",
+		&decodedHash.params.Memory, &decodedHash.params.Time, &decodedHash.params.Threads,
+	); err != nil {
+		return argon2idHash{}, fmt.Errorf("invalid argon2id hash parameters: %w", err)
+	}
+	var err error
+	if decodedHash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2idHash{}, fmt.Errorf("invalid argon2id hash salt: %w", err)
+	}
+	if decodedHash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return argon2idHash{}, fmt.Errorf("invalid argon2id hash key: %w", err)
+	}
+	decodedHash.params.SaltLength = uint32(len(decodedHash.salt))
+	decodedHash.params.KeyLength = uint32(len(decodedHash.key))
+	return decodedHash, nil
+}
@@ -0,0 +1,148 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/cache"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/dataaccess/file"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DependencyNameMySQL   = "mysql"
+	DependencyNameCache   = "cache"
+	DependencyNameMQ      = "mq"
+	DependencyNameStorage = "storage"
+
+	// dependencyCheckTimeout keeps a dependency that does not answer from holding the probes of the orchestrator.
+	dependencyCheckTimeout = 3 * time.Second
+
+	dependencyHealthErrorUnavailable = "unavailable"
+	dependencyHealthErrorTimeout     = "timed out"
+)
+
+type DependencyHealth struct {
+	Name    string
+	Healthy bool
+	// Error is only set if the dependency is not healthy. It is reported to unauthenticated callers, so it only tells
+	// whether the check timed out, the cause being logged instead.
+	Error string
+}
+type GetReadinessOutput struct {
+	Ready                bool
+	ShuttingDown         bool
+	DependencyHealthList []DependencyHealth
+}
+
+// Health tells the orchestrator running this instance whether it can serve requests.
+type Health interface {
+	// GetReadiness checks all dependencies. The instance is ready if all of them are healthy and it is not shutting down.
+	GetReadiness(ctx context.Context) GetReadinessOutput
+	GetDependencyHealth(ctx context.Context, name string) (DependencyHealth, error)
+	// SetShuttingDown marks the instance as not ready for the rest of its life, so that it stops being sent requests
+	// while it drains the running ones.
+	SetShuttingDown()
+	IsShuttingDown() bool
+}
+type health struct {
+	goquDatabase *goqu.Database
+	cacheClient  cache.Client
+	mqClient     producer.Client
+	fileClient   file.Client
+	shuttingDown *atomic.Bool
+	logger       *zap.Logger
+}
+
+func NewHealth(
+	goquDatabase *goqu.Database,
+	cacheClient cache.Client,
+	mqClient producer.Client,
+	fileClient file.Client,
+	logger *zap.Logger,
+) Health {
+	return &health{
+		goquDatabase: goquDatabase,
+		cacheClient:  cacheClient,
+		mqClient:     mqClient,
+		fileClient:   fileClient,
+		shuttingDown: new(atomic.Bool),
+		logger:       logger,
+	}
+}
+func (h health) getDependencyNameToPingFuncMap() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{
+		DependencyNameMySQL: func(ctx context.Context) error {
+			return database.PingDatabase(ctx, h.goquDatabase)
+		},
+		DependencyNameCache:   h.cacheClient.Ping,
+		DependencyNameMQ:      h.mqClient.Ping,
+		DependencyNameStorage: h.fileClient.Ping,
+	}
+}
+func (h health) checkDependency(ctx context.Context, name string, ping func(context.Context) error) DependencyHealth {
+	logger := utils.LoggerWithContext(ctx, h.logger).With(zap.String("dependency_name", name))
+
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+	if err := ping(ctx); err != nil {
+		logger.With(zap.Error(err)).Warn("dependency is not healthy")
+		dependencyHealth := DependencyHealth{
+			Name:  name,
+			Error: dependencyHealthErrorUnavailable,
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			dependencyHealth.Error = dependencyHealthErrorTimeout
+		}
+		return dependencyHealth
+	}
+	return DependencyHealth{
+		Name:    name,
+		Healthy: true,
+	}
+}
+func (h health) GetReadiness(ctx context.Context) GetReadinessOutput {
+	dependencyNameToPingFuncMap := h.getDependencyNameToPingFuncMap()
+	dependencyNameList := []string{DependencyNameMySQL, DependencyNameCache, DependencyNameMQ, DependencyNameStorage}
+	dependencyHealthList := make([]DependencyHealth, len(dependencyNameList))
+	var waitGroup sync.WaitGroup
+	for i, name := range dependencyNameList {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			dependencyHealthList[i] = h.checkDependency(ctx, name, dependencyNameToPingFuncMap[name])
+		}()
+	}
+	waitGroup.Wait()
+	output := GetReadinessOutput{
+		Ready:                !h.IsShuttingDown(),
+		ShuttingDown:         h.IsShuttingDown(),
+		DependencyHealthList: dependencyHealthList,
+	}
+	for _, dependencyHealth := range dependencyHealthList {
+		output.Ready = output.Ready && dependencyHealth.Healthy
+	}
+	return output
+}
+func (h health) GetDependencyHealth(ctx context.Context, name string) (DependencyHealth, error) {
+	ping, ok := h.getDependencyNameToPingFuncMap()[name]
+	if !ok {
+		return DependencyHealth{}, status.Error(codes.NotFound, "dependency not found")
+	}
+	return h.checkDependency(ctx, name, ping), nil
+}
+func (h health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+func (h health) IsShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
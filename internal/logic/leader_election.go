@@ -0,0 +1,99 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/utils"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LeaderElection elects one instance among the ones competing for a name, through leases stored in the database.
+type LeaderElection interface {
+	// TryAcquireLeadership takes the lease of name if it is free or expired, or renews it if this instance already
+	// holds it. It reports whether this instance holds the lease.
+	TryAcquireLeadership(ctx context.Context, name string) (bool, error)
+	// ReleaseLeadership lets another instance take over without waiting for the lease to expire.
+	ReleaseLeadership(ctx context.Context, name string) error
+}
+type leaderElection struct {
+	goquDatabase            *goqu.Database
+	leaderLeaseDataAccessor database.LeaderLeaseDataAccessor
+	leaderElectionConfig    configs.LeaderElection
+	candidateID             string
+	logger                  *zap.Logger
+}
+
+func NewLeaderElection(
+	goquDatabase *goqu.Database,
+	leaderLeaseDataAccessor database.LeaderLeaseDataAccessor,
+	cronConfig configs.Cron,
+	logger *zap.Logger,
+) LeaderElection {
+	return &leaderElection{
+		goquDatabase:            goquDatabase,
+		leaderLeaseDataAccessor: leaderLeaseDataAccessor,
+		leaderElectionConfig:    cronConfig.LeaderElection,
+		candidateID:             cronConfig.LeaderElection.GetCandidateID(),
+		logger:                  logger,
+	}
+}
+func (l leaderElection) TryAcquireLeadership(ctx context.Context, name string) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("name", name))
+
+	leaseTTL, err := l.leaderElectionConfig.GetLeaseTTLDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse leader election lease ttl")
+		return false, status.Error(codes.Internal, "failed to parse leader election lease ttl")
+	}
+	acquired := false
+	txErr := l.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		now := time.Now()
+		if createErr := l.leaderLeaseDataAccessor.WithDatabase(td).CreateLeaderLease(ctx, database.LeaderLease{
+			Name:            name,
+			HolderID:        l.candidateID,
+			LeaseExpireTime: now.Add(leaseTTL),
+		}); createErr != nil {
+			return createErr
+		}
+		leaderLease, getErr := l.leaderLeaseDataAccessor.WithDatabase(td).GetLeaderLeaseWithXLock(ctx, name)
+		if getErr != nil {
+			return getErr
+		}
+		if leaderLease.HolderID != l.candidateID && leaderLease.LeaseExpireTime.After(now) {
+			return nil
+		}
+		if leaderLease.HolderID != l.candidateID {
+			logger.With(zap.String("previous_holder_id", leaderLease.HolderID)).Info("taking over expired leader lease")
+		}
+		leaderLease.HolderID = l.candidateID
+		leaderLease.LeaseExpireTime = now.Add(leaseTTL)
+		if updateErr := l.leaderLeaseDataAccessor.WithDatabase(td).UpdateLeaderLease(ctx, leaderLease); updateErr != nil {
+			return updateErr
+		}
+		acquired = true
+		return nil
+	})
+	if txErr != nil {
+		return false, txErr
+	}
+	return acquired, nil
+}
+func (l leaderElection) ReleaseLeadership(ctx context.Context, name string) error {
+	return l.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		leaderLease, err := l.leaderLeaseDataAccessor.WithDatabase(td).GetLeaderLeaseWithXLock(ctx, name)
+		if err != nil {
+			return err
+		}
+		if leaderLease.HolderID != l.candidateID {
+			return nil
+		}
+		leaderLease.LeaseExpireTime = time.Now()
+		return l.leaderLeaseDataAccessor.WithDatabase(td).UpdateLeaderLease(ctx, leaderLease)
+	})
+}
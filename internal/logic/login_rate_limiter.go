@@ -0,0 +1,176 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/cache"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/utils"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	auditLogActionLoginFailed = "login_failed"
+	auditLogActionLockedOut   = "login_locked_out"
+)
+
+var errLoginLockedOut = status.Error(codes.ResourceExhausted, "too many failed login attempts, please try again later")
+
+type LoginAttemptParams struct {
+	AccountName string
+	ClientIP    string
+}
+type RecordFailedLoginParams struct {
+	AccountName string
+	ClientIP    string
+	// OfAccountID is nil when no account has the attempted account name.
+	OfAccountID *uint64
+	Reason      string
+}
+
+// LoginRateLimiter locks out account names and client IPs after repeated failed logins. Cache failures let the login
+// through, so that an unavailable cache does not lock every user out.
+type LoginRateLimiter interface {
+	CheckLoginAllowed(ctx context.Context, params LoginAttemptParams) error
+	RecordFailedLogin(ctx context.Context, params RecordFailedLoginParams) error
+	RecordSuccessfulLogin(ctx context.Context, params LoginAttemptParams)
+}
+type loginRateLimiter struct {
+	loginAttemptCache           cache.LoginAttempt
+	auditLogDataAccessor        database.AuditLogDataAccessor
+	window                      time.Duration
+	maxFailedAttemptsPerAccount int64
+	maxFailedAttemptsPerIP      int64
+	baseLockoutDuration         time.Duration
+	maxLockoutDuration          time.Duration
+	logger                      *zap.Logger
+}
+
+func NewLoginRateLimiter(loginAttemptCache cache.LoginAttempt, auditLogDataAccessor database.AuditLogDataAccessor,
+	authConfig configs.Auth, logger *zap.Logger) (LoginRateLimiter, error) {
+	window, err := authConfig.LoginRateLimit.GetWindowDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse login_rate_limit.window")
+		return nil, err
+	}
+	baseLockoutDuration, err := authConfig.LoginRateLimit.GetBaseLockoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse login_rate_limit.base_lockout_duration")
+		return nil, err
+	}
+	maxLockoutDuration, err := authConfig.LoginRateLimit.GetMaxLockoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse login_rate_limit.max_lockout_duration")
+		return nil, err
+	}
+	return &loginRateLimiter{
+		loginAttemptCache:           loginAttemptCache,
+		auditLogDataAccessor:        auditLogDataAccessor,
+		window:                      window,
+		maxFailedAttemptsPerAccount: authConfig.LoginRateLimit.MaxFailedAttemptsPerAccount,
+		maxFailedAttemptsPerIP:      authConfig.LoginRateLimit.MaxFailedAttemptsPerIP,
+		baseLockoutDuration:         baseLockoutDuration,
+		maxLockoutDuration:          maxLockoutDuration,
+		logger:                      logger,
+	}, nil
+}
+func (l loginRateLimiter) getAccountKey(accountName string) string {
+	return fmt.Sprintf("account:%s", accountName)
+}
+func (l loginRateLimiter) getIPKey(clientIP string) string {
+	return fmt.Sprintf("ip:%s", clientIP)
+}
+
+// getLockoutDuration doubles the lockout for every failed attempt past the limit.
+func (l loginRateLimiter) getLockoutDuration(failedCount, maxFailedAttempts int64) time.Duration {
+	lockoutDuration := l.baseLockoutDuration
+	for i := maxFailedAttempts; i < failedCount && lockoutDuration < l.maxLockoutDuration; i++ {
+		lockoutDuration *= 2
+	}
+	if lockoutDuration > l.maxLockoutDuration {
+		return l.maxLockoutDuration
+	}
+	return lockoutDuration
+}
+func (l loginRateLimiter) CheckLoginAllowed(ctx context.Context, params LoginAttemptParams) error {
+	logger := utils.LoggerWithContext(ctx, l.logger).
+		With(zap.String("account_name", params.AccountName)).
+		With(zap.String("client_ip", params.ClientIP))
+
+	keyList := []string{l.getAccountKey(params.AccountName)}
+	if params.ClientIP != "" {
+		keyList = append(keyList, l.getIPKey(params.ClientIP))
+	}
+	for _, key := range keyList {
+		lockedOut, err := l.loginAttemptCache.IsLockedOut(ctx, key)
+		if err != nil {
+			logger.With(zap.Error(err)).Warn("failed to check login lockout, will allow login attempt")
+			continue
+		}
+		if lockedOut {
+			return errLoginLockedOut
+		}
+	}
+	return nil
+}
+
+// incrementFailedCount counts the failed attempt against key and locks key out once maxFailedAttempts is reached.
+// It returns whether key has been locked out.
+func (l loginRateLimiter) incrementFailedCount(ctx context.Context, key string, maxFailedAttempts int64) bool {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("key", key))
+
+	failedCount, err := l.loginAttemptCache.IncrementFailedCount(ctx, key, l.window)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to count failed login attempt")
+		return false
+	}
+	if maxFailedAttempts <= 0 || failedCount < maxFailedAttempts {
+		return false
+	}
+	lockoutDuration := l.getLockoutDuration(failedCount, maxFailedAttempts)
+	if err = l.loginAttemptCache.SetLockout(ctx, key, lockoutDuration); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to lock out login attempts")
+		return false
+	}
+	logger.
+		With(zap.Int64("failed_count", failedCount)).
+		With(zap.Duration("lockout_duration", lockoutDuration)).
+		Warn("locked out login attempts")
+	return true
+}
+func (l loginRateLimiter) RecordFailedLogin(ctx context.Context, params RecordFailedLoginParams) error {
+	lockedOut := l.incrementFailedCount(ctx, l.getAccountKey(params.AccountName), l.maxFailedAttemptsPerAccount)
+	if params.ClientIP != "" {
+		lockedOut = l.incrementFailedCount(ctx, l.getIPKey(params.ClientIP), l.maxFailedAttemptsPerIP) || lockedOut
+	}
+	action := auditLogActionLoginFailed
+	if lockedOut {
+		action = auditLogActionLockedOut
+	}
+	_, err := l.auditLogDataAccessor.CreateAuditLog(ctx, database.AuditLog{
+		OfAccountID: params.OfAccountID,
+		Action:      action,
+		ClientIP:    params.ClientIP,
+		Metadata: database.JSON{Data: map[string]any{
+			"account_name": params.AccountName,
+			"reason":       params.Reason,
+		}},
+		CreatedTime: time.Now(),
+	})
+	return err
+}
+
+// RecordSuccessfulLogin only clears the count of the account; the count of the IP keeps expiring on its own, so one
+// valid account does not hide guessing against other accounts from the same IP.
+func (l loginRateLimiter) RecordSuccessfulLogin(ctx context.Context, params LoginAttemptParams) {
+	logger := utils.LoggerWithContext(ctx, l.logger).With(zap.String("account_name", params.AccountName))
+
+	if err := l.loginAttemptCache.ResetFailedCount(ctx, l.getAccountKey(params.AccountName)); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to reset failed login count")
+	}
+}
@@ -0,0 +1,204 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/cache"
+	"GoLoad/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const oidcRandomValueByteCount = 32
+
+var (
+	errOIDCDisabled     = status.Error(codes.NotFound, "oidc login is not enabled")
+	errInvalidOIDCState = status.Error(codes.InvalidArgument, "invalid or expired oidc login state")
+	errInvalidIDToken   = status.Error(codes.Unauthenticated, "invalid id token")
+)
+
+type StartOIDCLoginOutput struct {
+	AuthCodeURL string
+	State       string
+	ExpireTime  time.Time
+}
+type FinishOIDCLoginParams struct {
+	// State is the state returned by the identity provider, ExpectedState the one the browser started the login with.
+	State         string
+	ExpectedState string
+	Code          string
+}
+
+// OIDC logs users in with an OpenID Connect identity provider. The provider is discovered on first use, so that the
+// server starts even if the provider is unreachable.
+type OIDC interface {
+	StartOIDCLogin(ctx context.Context) (StartOIDCLoginOutput, error)
+	FinishOIDCLogin(ctx context.Context, params FinishOIDCLoginParams) (CreateSessionOutput, error)
+}
+type oidcProvider struct {
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+}
+type oidcProviderHolder struct {
+	mutex    sync.Mutex
+	provider *oidcProvider
+}
+type oidcLogic struct {
+	oidcLoginCache     cache.OIDCLogin
+	accountLogic       Account
+	oidcConfig         configs.OIDC
+	loginExpiresIn     time.Duration
+	oidcProviderHolder *oidcProviderHolder
+	logger             *zap.Logger
+}
+
+func NewOIDC(oidcLoginCache cache.OIDCLogin, accountLogic Account, authConfig configs.Auth, logger *zap.Logger) (OIDC, error) {
+	var loginExpiresIn time.Duration
+	if authConfig.OIDC.Enabled {
+		var err error
+		loginExpiresIn, err = authConfig.OIDC.GetLoginExpiresInDuration()
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to parse oidc login_expires_in")
+			return nil, err
+		}
+	}
+	return &oidcLogic{
+		oidcLoginCache:     oidcLoginCache,
+		accountLogic:       accountLogic,
+		oidcConfig:         authConfig.OIDC,
+		loginExpiresIn:     loginExpiresIn,
+		oidcProviderHolder: new(oidcProviderHolder),
+		logger:             logger,
+	}, nil
+}
+func (o oidcLogic) getProvider(ctx context.Context) (*oidcProvider, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger).With(zap.String("issuer_url", o.oidcConfig.IssuerURL))
+
+	o.oidcProviderHolder.mutex.Lock()
+	defer o.oidcProviderHolder.mutex.Unlock()
+	if o.oidcProviderHolder.provider != nil {
+		return o.oidcProviderHolder.provider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, o.oidcConfig.IssuerURL)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to discover oidc provider")
+		return nil, status.Error(codes.Unavailable, "failed to discover oidc provider")
+	}
+	o.oidcProviderHolder.provider = &oidcProvider{
+		oauth2Config: oauth2.Config{
+			ClientID:     o.oidcConfig.ClientID,
+			ClientSecret: o.oidcConfig.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  o.oidcConfig.RedirectURL,
+			Scopes:       append([]string{oidc.ScopeOpenID}, o.oidcConfig.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: o.oidcConfig.ClientID}),
+	}
+	return o.oidcProviderHolder.provider, nil
+}
+func (o oidcLogic) generateRandomValue(ctx context.Context) (string, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	randomBytes := make([]byte, oidcRandomValueByteCount)
+	if _, err := rand.Read(randomBytes); err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate random value")
+		return "", status.Error(codes.Internal, "failed to generate random value")
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+func (o oidcLogic) StartOIDCLogin(ctx context.Context) (StartOIDCLoginOutput, error) {
+	if !o.oidcConfig.Enabled {
+		return StartOIDCLoginOutput{}, errOIDCDisabled
+	}
+	provider, err := o.getProvider(ctx)
+	if err != nil {
+		return StartOIDCLoginOutput{}, err
+	}
+	state, err := o.generateRandomValue(ctx)
+	if err != nil {
+		return StartOIDCLoginOutput{}, err
+	}
+	nonce, err := o.generateRandomValue(ctx)
+	if err != nil {
+		return StartOIDCLoginOutput{}, err
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+	if err = o.oidcLoginCache.Set(ctx, state, cache.OIDCLoginEntry{
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+	}, o.loginExpiresIn); err != nil {
+		return StartOIDCLoginOutput{}, status.Error(codes.Internal, "failed to save oidc login state")
+	}
+	return StartOIDCLoginOutput{
+		AuthCodeURL: provider.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)),
+		State:       state,
+		ExpireTime:  time.Now().Add(o.loginExpiresIn),
+	}, nil
+}
+func (o oidcLogic) FinishOIDCLogin(ctx context.Context, params FinishOIDCLoginParams) (CreateSessionOutput, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	if !o.oidcConfig.Enabled {
+		return CreateSessionOutput{}, errOIDCDisabled
+	}
+	// Checking the state against the one the browser started with stops a login being completed in another browser.
+	if params.State == "" || params.State != params.ExpectedState {
+		return CreateSessionOutput{}, errInvalidOIDCState
+	}
+	loginEntry, err := o.oidcLoginCache.Take(ctx, params.State)
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			logger.With(zap.Error(err)).Error("failed to get oidc login state")
+		}
+		return CreateSessionOutput{}, errInvalidOIDCState
+	}
+	provider, err := o.getProvider(ctx)
+	if err != nil {
+		return CreateSessionOutput{}, err
+	}
+	oauth2Token, err := provider.oauth2Config.Exchange(ctx, params.Code, oauth2.VerifierOption(loginEntry.CodeVerifier))
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to exchange authorization code")
+		return CreateSessionOutput{}, status.Error(codes.Unauthenticated, "failed to exchange authorization code")
+	}
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		logger.Warn("token response has no id token")
+		return CreateSessionOutput{}, errInvalidIDToken
+	}
+	idToken, err := provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to verify id token")
+		return CreateSessionOutput{}, errInvalidIDToken
+	}
+	if idToken.Nonce != loginEntry.Nonce {
+		logger.Warn("id token nonce does not match")
+		return CreateSessionOutput{}, errInvalidIDToken
+	}
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to parse id token claims")
+		return CreateSessionOutput{}, errInvalidIDToken
+	}
+	preferredAccountName := claims.PreferredUsername
+	if preferredAccountName == "" {
+		preferredAccountName = claims.Email
+	}
+	return o.accountLogic.CreateSessionOfIdentity(ctx, CreateSessionOfIdentityParams{
+		Issuer:               idToken.Issuer,
+		Subject:              idToken.Subject,
+		PreferredAccountName: preferredAccountName,
+	})
+}
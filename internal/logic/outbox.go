@@ -0,0 +1,216 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	outboxPendingEventCountGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "goload",
+		Subsystem: "outbox",
+		Name:      "pending_events",
+		Help:      "Number of outbox events not relayed to the message queue yet.",
+	})
+	outboxLagSecondsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "goload",
+		Subsystem: "outbox",
+		Name:      "lag_seconds",
+		Help:      "Age of the oldest outbox event not relayed to the message queue yet, 0 when there is none.",
+	})
+	outboxRelayedEventCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "goload",
+		Subsystem: "outbox",
+		Name:      "relayed_events_total",
+		Help:      "Number of outbox events relayed to the message queue.",
+	})
+	outboxFailedRelayCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "goload",
+		Subsystem: "outbox",
+		Name:      "failed_relays_total",
+		Help:      "Number of failed attempts to relay an outbox event to the message queue.",
+	})
+)
+
+// Outbox records message queue events in the database transaction of the change they announce, so that an event is
+// published if and only if its transaction commits. Events are published later by RelayPendingEvents, at least once.
+type Outbox interface {
+	// EnqueueDownloadTaskCreated must be called on an Outbox returned by WithDatabase with the transaction of the
+	// change.
+	EnqueueDownloadTaskCreated(ctx context.Context, event producer.DownloadTaskCreated) error
+	EnqueueDownloadTaskLifecycleEvent(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error
+	// RelayPendingEvents publishes the due pending events, retrying failed ones with exponential backoff, until no
+	// event is due. It also deletes sent events past their retention. Delivery is at least once and unordered: an event
+	// waiting for its retry does not hold back later ones, and concurrent relays publish their batches interleaved.
+	RelayPendingEvents(ctx context.Context) error
+	WithDatabase(database database.Database) Outbox
+}
+type outbox struct {
+	goquDatabase            *goqu.Database
+	outboxEventDataAccessor database.OutboxEventDataAccessor
+	mqClient                producer.Client
+	mqConfig                configs.MQ
+	logger                  *zap.Logger
+}
+
+func NewOutbox(
+	goquDatabase *goqu.Database,
+	outboxEventDataAccessor database.OutboxEventDataAccessor,
+	mqClient producer.Client,
+	mqConfig configs.MQ,
+	logger *zap.Logger,
+) (Outbox, error) {
+	// A batch size of 0 is no limit to the database, so that a relay run would never stop fetching more events.
+	if mqConfig.Outbox.BatchSize == 0 {
+		return nil, errors.New("mq outbox batch_size must be positive")
+	}
+	return &outbox{
+		goquDatabase:            goquDatabase,
+		outboxEventDataAccessor: outboxEventDataAccessor,
+		mqClient:                mqClient,
+		mqConfig:                mqConfig,
+		logger:                  logger,
+	}, nil
+}
+func (o outbox) enqueue(ctx context.Context, queueName string, event any) error {
+	logger := utils.LoggerWithContext(ctx, o.logger).With(zap.String("queue_name", queueName))
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to marshal outbox event")
+		return status.Error(codes.Internal, "failed to marshal outbox event")
+	}
+	now := time.Now()
+	_, err = o.outboxEventDataAccessor.CreateOutboxEvent(ctx, database.OutboxEvent{
+		QueueName:       queueName,
+		Payload:         payload,
+		CreatedTime:     now,
+		NextAttemptTime: now,
+	})
+	return err
+}
+func (o outbox) EnqueueDownloadTaskCreated(ctx context.Context, event producer.DownloadTaskCreated) error {
+	return o.enqueue(ctx, producer.MessageQueueDownloadTaskCreated, event)
+}
+func (o outbox) EnqueueDownloadTaskLifecycleEvent(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error {
+	return o.enqueue(ctx, producer.MessageQueueDownloadTaskLifecycleEvent, event)
+}
+func (o outbox) getRetryDelay(attemptCount uint64) (time.Duration, error) {
+	retryBaseDelay, err := o.mqConfig.Outbox.GetRetryBaseDelayDuration()
+	if err != nil {
+		return 0, err
+	}
+	retryMaxDelay, err := o.mqConfig.Outbox.GetRetryMaxDelayDuration()
+	if err != nil {
+		return 0, err
+	}
+	retryDelay := retryBaseDelay
+	for i := uint64(1); i < attemptCount && retryDelay < retryMaxDelay; i++ {
+		retryDelay *= 2
+	}
+	return min(retryDelay, retryMaxDelay), nil
+}
+
+// relayPendingEventBatch reports whether there may be more events due. It stops at the first event failing to be
+// published, since the following ones would most likely fail the same way.
+func (o outbox) relayPendingEventBatch(ctx context.Context) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	hasMore := false
+	txErr := o.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		outboxEventList, err := o.outboxEventDataAccessor.WithDatabase(td).
+			GetPendingOutboxEventListWithXLock(ctx, time.Now(), o.mqConfig.Outbox.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, outboxEvent := range outboxEventList {
+			produceErr := o.mqClient.Produce(ctx, outboxEvent.QueueName, outboxEvent.Payload)
+			if produceErr == nil {
+				sentTime := time.Now()
+				outboxEvent.AttemptCount++
+				outboxEvent.SentTime = &sentTime
+				if err = o.outboxEventDataAccessor.WithDatabase(td).UpdateOutboxEvent(ctx, outboxEvent); err != nil {
+					return err
+				}
+				outboxRelayedEventCounter.Inc()
+				continue
+			}
+			outboxFailedRelayCounter.Inc()
+			logger.With(zap.Uint64("outbox_event_id", outboxEvent.ID)).With(zap.Error(produceErr)).
+				Warn("failed to relay outbox event, will retry")
+			outboxEvent.AttemptCount++
+			retryDelay, err := o.getRetryDelay(outboxEvent.AttemptCount)
+			if err != nil {
+				logger.With(zap.Error(err)).Error("failed to parse outbox retry delay")
+				return status.Error(codes.Internal, "failed to parse outbox retry delay")
+			}
+			lastError := produceErr.Error()
+			outboxEvent.NextAttemptTime = time.Now().Add(retryDelay)
+			outboxEvent.LastError = &lastError
+			return o.outboxEventDataAccessor.WithDatabase(td).UpdateOutboxEvent(ctx, outboxEvent)
+		}
+		hasMore = uint64(len(outboxEventList)) == o.mqConfig.Outbox.BatchSize
+		return nil
+	})
+	if txErr != nil {
+		return false, txErr
+	}
+	return hasMore, nil
+}
+func (o outbox) updateMetrics(ctx context.Context) {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	pendingEventCount, err := o.outboxEventDataAccessor.GetPendingOutboxEventCount(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to update outbox pending event count metric")
+		return
+	}
+	outboxPendingEventCountGauge.Set(float64(pendingEventCount))
+	oldestCreatedTime, err := o.outboxEventDataAccessor.GetOldestPendingOutboxEventCreatedTime(ctx)
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to update outbox lag metric")
+		return
+	}
+	if oldestCreatedTime == nil {
+		outboxLagSecondsGauge.Set(0)
+		return
+	}
+	outboxLagSecondsGauge.Set(time.Since(*oldestCreatedTime).Seconds())
+}
+func (o outbox) RelayPendingEvents(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, o.logger)
+
+	defer o.updateMetrics(ctx)
+	for {
+		hasMore, err := o.relayPendingEventBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasMore {
+			break
+		}
+	}
+	sentEventRetention, err := o.mqConfig.Outbox.GetSentEventRetentionDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse outbox sent event retention")
+		return status.Error(codes.Internal, "failed to parse outbox sent event retention")
+	}
+	return o.outboxEventDataAccessor.DeleteSentOutboxEventsBefore(ctx, time.Now().Add(-sentEventRetention))
+}
+func (o outbox) WithDatabase(database database.Database) Outbox {
+	o.outboxEventDataAccessor = o.outboxEventDataAccessor.WithDatabase(database)
+	return o
+}
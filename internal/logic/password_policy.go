@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type PasswordPolicy interface {
+	// Validate returns an InvalidArgument error describing the first rule the password breaks.
+	Validate(ctx context.Context, password string) error
+}
+type passwordPolicy struct {
+	passwordPolicyConfig configs.PasswordPolicy
+	breachedPasswordSet  map[string]struct{}
+}
+
+func NewPasswordPolicy(authConfig configs.Auth, logger *zap.Logger) (PasswordPolicy, error) {
+	breachedPasswordSet, err := loadBreachedPasswordSet(authConfig.PasswordPolicy.BreachedPasswordListFile)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to load breached password list")
+		return nil, err
+	}
+	return &passwordPolicy{
+		passwordPolicyConfig: authConfig.PasswordPolicy,
+		breachedPasswordSet:  breachedPasswordSet,
+	}, nil
+}
+
+// loadBreachedPasswordSet reads one password per line, ignoring blank lines. Passwords are compared case-sensitively.
+func loadBreachedPasswordSet(filePath string) (map[string]struct{}, error) {
+	breachedPasswordSet := make(map[string]struct{})
+	if filePath == "" {
+		return breachedPasswordSet, nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list file: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		breachedPassword := strings.TrimRight(scanner.Text(), "\r")
+		if breachedPassword == "" {
+			continue
+		}
+		breachedPasswordSet[breachedPassword] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list file: %w", err)
+	}
+	return breachedPasswordSet, nil
+}
+func (p passwordPolicy) Validate(_ context.Context, password string) error {
+	if len([]rune(password)) < p.passwordPolicyConfig.MinLength {
+		return status.Errorf(codes.InvalidArgument, "password must be at least %d characters long", p.passwordPolicyConfig.MinLength)
+	}
+	var hasUppercase, hasLowercase, hasDigit, hasSymbol bool
+	for _, character := range password {
+		switch {
+		case unicode.IsUpper(character):
+			hasUppercase = true
+		case unicode.IsLower(character):
+			hasLowercase = true
+		case unicode.IsDigit(character):
+			hasDigit = true
+		case unicode.IsPunct(character) || unicode.IsSymbol(character):
+			hasSymbol = true
+		}
+	}
+	if p.passwordPolicyConfig.RequireUppercase && !hasUppercase {
+		return status.Error(codes.InvalidArgument, "password must contain an uppercase letter")
+	}
+	if p.passwordPolicyConfig.RequireLowercase && !hasLowercase {
+		return status.Error(codes.InvalidArgument, "password must contain a lowercase letter")
+	}
+	if p.passwordPolicyConfig.RequireDigit && !hasDigit {
+		return status.Error(codes.InvalidArgument, "password must contain a digit")
+	}
+	if p.passwordPolicyConfig.RequireSymbol && !hasSymbol {
+		return status.Error(codes.InvalidArgument, "password must contain a symbol")
+	}
+	if _, ok := p.breachedPasswordSet[password]; ok {
+		return status.Error(codes.InvalidArgument, "password has appeared in a data breach, please choose another one")
+	}
+	return nil
+}
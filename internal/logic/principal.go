@@ -0,0 +1,59 @@
+package logic
+
+import (
+	"GoLoad/internal/generated/grpc/go_load"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errMissingPrincipal = status.Error(codes.Unauthenticated, "request is not authenticated")
+
+// Principal is the authenticated caller of a request, with the session or API key it authenticated with.
+type Principal struct {
+	TokenClaims
+	AccountRole go_load.AccountRole
+}
+type principalContextKey struct{}
+
+// ContextWithPrincipal is used by handlers once they have authenticated a request, for the logic layer to read the
+// principal back with PrincipalFromContext.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+func getPrincipal(ctx context.Context) (Principal, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return Principal{}, errMissingPrincipal
+	}
+	return principal, nil
+}
+
+// getSessionPrincipal rejects API keys, for operations managing the account.
+func getSessionPrincipal(ctx context.Context) (Principal, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return Principal{}, err
+	}
+	if principal.APIKeyID != 0 {
+		return Principal{}, errSessionRequired
+	}
+	return principal, nil
+}
+
+// getAccountIDWithScope returns the account of the principal if it allows operations of scope.
+func getAccountIDWithScope(ctx context.Context, scope go_load.APIKeyScope) (uint64, error) {
+	principal, err := getPrincipal(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !principal.HasScope(scope) {
+		return 0, errAPIKeyScopeNotGranted
+	}
+	return principal.AccountID, nil
+}
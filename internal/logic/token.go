@@ -2,14 +2,18 @@ package logic
 
 import (
 	"context"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"database/sql"
 	"encoding/pem"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -18,6 +22,7 @@ import (
 	"GoLoad/internal/configs"
 	"GoLoad/internal/dataaccess/cache"
 	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/generated/grpc/go_load"
 	"GoLoad/internal/utils"
 )
 
@@ -31,14 +36,53 @@ var (
 	errCannotGetTokensKidClaim = status.Error(codes.Unauthenticated, "cannot get token's kid claim")
 	errCannotGetTokensSubClaim = status.Error(codes.Unauthenticated, "cannot get token's sub claim")
 	errCannotGetTokensExpClaim = status.Error(codes.Unauthenticated, "cannot get token's exp claim")
+	errCannotGetTokensSidClaim = status.Error(codes.Unauthenticated, "cannot get token's sid claim")
+	errSessionRevoked          = status.Error(codes.Unauthenticated, "session has been revoked")
 	errTokenPublicKeyNotFound  = status.Error(codes.Unauthenticated, "token public key not found")
 	errInvalidToken            = status.Error(codes.Unauthenticated, "invalid token")
+	errInvalidAPIKey           = status.Error(codes.Unauthenticated, "invalid api key")
+	errSessionRequired         = status.Error(codes.PermissionDenied, "api keys cannot be used for this operation")
+	errAPIKeyScopeNotGranted   = status.Error(codes.PermissionDenied, "api key is not granted the scope of this operation")
 	errFailedToSignToken       = status.Error(codes.Internal, "failed to sign token")
 )
 
+// TokenClaims identifies either a session or, when APIKeyID is set, an API key. ExpireTime is zero for API keys that
+// never expire.
+type TokenClaims struct {
+	AccountID    uint64
+	SessionID    uint64
+	APIKeyID     uint64
+	APIKeyScopes []go_load.APIKeyScope
+	ExpireTime   time.Time
+}
+
+// HasScope reports whether the claims allow an operation of scope. Sessions and API keys without scopes allow all.
+func (c TokenClaims) HasScope(scope go_load.APIKeyScope) bool {
+	if c.APIKeyID == 0 || len(c.APIKeyScopes) == 0 {
+		return true
+	}
+	for _, apiKeyScope := range c.APIKeyScopes {
+		if apiKeyScope == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type Token interface {
-	GetToken(ctx context.Context, accountID uint64) (string, time.Time, error)
-	GetAccountIDAndExpireTime(ctx context.Context, token string) (uint64, time.Time, error)
+	GetToken(ctx context.Context, accountID uint64, sessionID uint64) (string, time.Time, error)
+	// GetTokenClaims accepts both session tokens and API keys.
+	GetTokenClaims(ctx context.Context, token string) (TokenClaims, error)
+	// RenewTokenIfNearExpiry returns a new token for the same session if the token expires within the configured
+	// regenerate_token_before_expiry, and reports whether it did so.
+	RenewTokenIfNearExpiry(ctx context.Context, token string) (string, bool, error)
+	RevokeSession(ctx context.Context, sessionID uint64) error
+	// RevokeSessionsOfAccount revokes every session of the account except exceptSessionID, which may be 0.
+	RevokeSessionsOfAccount(ctx context.Context, accountID uint64, exceptSessionID uint64) error
+	// RotateSigningKey replaces the signing key once it is older than the rotation interval, and deletes keys no
+	// longer needed to verify unexpired tokens.
+	RotateSigningKey(ctx context.Context) error
+	GetJSONWebKeySet(ctx context.Context) (JSONWebKeySet, error)
 	WithDatabase(database database.Database) Token
 }
 
@@ -63,51 +107,66 @@ func pemEncodePublicKey(pubKey *rsa.PublicKey) ([]byte, error) {
 }
 
 type token struct {
+	goquDatabase               *goqu.Database
 	accountDataAccessor        database.AccountDataAccessor
 	tokenPublicKeyCache        cache.TokenPublicKey
 	tokenPublicKeyDataAccessor database.TokenPublicKeyDataAccessor
+	sessionDataAccessor        database.SessionDataAccessor
+	revokedSessionCache        cache.RevokedSession
+	apiKeyDataAccessor         database.APIKeyDataAccessor
 	expiresIn                  time.Duration
-	privateKey                 *rsa.PrivateKey
-	tokenPublicKeyID           uint64
+	regenerateBeforeExpiry     time.Duration
+	rotationInterval           time.Duration
+	privateKeyEncryptionAEAD   cipher.AEAD
+	signingKeyHolder           *signingKeyHolder
 	authConfig                 configs.Auth
 	logger                     *zap.Logger
 }
 
-func NewToken(accountDataAccessor database.AccountDataAccessor, tokenPublicKeyCache cache.TokenPublicKey,
-	tokenPublicKeyDataAccessor database.TokenPublicKeyDataAccessor, authConfig configs.Auth, logger *zap.Logger) (Token, error) {
+func NewToken(goquDatabase *goqu.Database, accountDataAccessor database.AccountDataAccessor, tokenPublicKeyCache cache.TokenPublicKey,
+	tokenPublicKeyDataAccessor database.TokenPublicKeyDataAccessor, sessionDataAccessor database.SessionDataAccessor,
+	revokedSessionCache cache.RevokedSession, apiKeyDataAccessor database.APIKeyDataAccessor, authConfig configs.Auth,
+	logger *zap.Logger) (Token, error) {
 	expiresIn, err := authConfig.Token.GetExpiresInDuration()
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to parse expires_in")
 		return nil, err
 	}
-	rsaKeyPair, err := generateRSAKeyPair(rs512KeyPairBitCount)
+	regenerateBeforeExpiry, err := authConfig.Token.GetRegenerateTokenBeforeExpiryDuration()
 	if err != nil {
-		logger.With(zap.Error(err)).Error("failed to generate rsa key pair")
+		logger.With(zap.Error(err)).Error("failed to parse regenerate_token_before_expiry")
 		return nil, err
 	}
-	publicKeyBytes, err := pemEncodePublicKey(&rsaKeyPair.PublicKey)
+	rotationInterval, err := authConfig.Token.SigningKey.GetRotationIntervalDuration()
 	if err != nil {
-		logger.With(zap.Error(err)).Error("failed to encode public key in pem format")
+		logger.With(zap.Error(err)).Error("failed to parse signing key rotation_interval")
 		return nil, err
 	}
-	tokenPublicKeyID, err := tokenPublicKeyDataAccessor.CreatePublicKey(
-		context.Background(),
-		database.TokenPublicKey{PublicKey: string(publicKeyBytes)},
-	)
+	privateKeyEncryptionAEAD, err := newPrivateKeyEncryptionAEAD(authConfig.Token.SigningKey)
 	if err != nil {
-		logger.With(zap.Error(err)).Error("failed to create public key entry in database")
+		logger.With(zap.Error(err)).Error("failed to initialize signing key encryption")
 		return nil, err
 	}
-	return &token{
+	t := &token{
+		goquDatabase:               goquDatabase,
 		accountDataAccessor:        accountDataAccessor,
 		tokenPublicKeyCache:        tokenPublicKeyCache,
 		tokenPublicKeyDataAccessor: tokenPublicKeyDataAccessor,
+		sessionDataAccessor:        sessionDataAccessor,
+		revokedSessionCache:        revokedSessionCache,
+		apiKeyDataAccessor:         apiKeyDataAccessor,
 		expiresIn:                  expiresIn,
-		privateKey:                 rsaKeyPair,
-		tokenPublicKeyID:           tokenPublicKeyID,
+		regenerateBeforeExpiry:     regenerateBeforeExpiry,
+		rotationInterval:           rotationInterval,
+		privateKeyEncryptionAEAD:   privateKeyEncryptionAEAD,
+		signingKeyHolder:           new(signingKeyHolder),
 		authConfig:                 authConfig,
 		logger:                     logger,
-	}, nil
+	}
+	if _, err = t.getSigningKey(context.Background()); err != nil {
+		return nil, err
+	}
+	return t, nil
 }
 func (t token) getJWTPublicKey(ctx context.Context, id uint64) (*rsa.PublicKey, error) {
 	logger := utils.LoggerWithContext(ctx, t.logger).With(zap.Uint64("id", id))
@@ -136,9 +195,60 @@ func (t token) getJWTPublicKey(ctx context.Context, id uint64) (*rsa.PublicKey,
 	return jwt.ParseRSAPublicKeyFromPEM([]byte(tokenPublicKey.PublicKey))
 }
 
-func (t token) GetAccountIDAndExpireTime(ctx context.Context, tokenString string) (uint64, time.Time, error) {
+func (t token) isSessionRevoked(ctx context.Context, sessionID uint64) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, t.logger).With(zap.Uint64("session_id", sessionID))
+
+	revoked, err := t.revokedSessionCache.Get(ctx, sessionID)
+	if err == nil {
+		return revoked, nil
+	}
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		logger.With(zap.Error(err)).Warn("failed to get revoked session from cache, will fall back to database")
+	}
+	session, err := t.sessionDataAccessor.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	// Every token of the session expires within expires_in, so the cache entry is not needed for longer than that.
+	if err = t.revokedSessionCache.Set(ctx, sessionID, session.Revoked, t.expiresIn); err != nil {
+		logger.With(zap.Error(err)).Warn("failed to set revoked session into cache")
+	}
+	return session.Revoked, nil
+}
+func (t token) getAPIKeyClaims(ctx context.Context, key string) (TokenClaims, error) {
 	logger := utils.LoggerWithContext(ctx, t.logger)
 
+	apiKey, err := t.apiKeyDataAccessor.GetAPIKeyByKeyHash(ctx, hashRandomToken(key))
+	if err != nil {
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			return TokenClaims{}, errInvalidAPIKey
+		}
+		return TokenClaims{}, err
+	}
+	if apiKey.Revoked || (apiKey.ExpireTime != nil && !time.Now().Before(*apiKey.ExpireTime)) {
+		logger.With(zap.Uint64("api_key_id", apiKey.ID)).Warn("api key is revoked or expired")
+		return TokenClaims{}, errInvalidAPIKey
+	}
+	claims := TokenClaims{
+		AccountID:    apiKey.OfAccountID,
+		APIKeyID:     apiKey.ID,
+		APIKeyScopes: parseAPIKeyScopes(apiKey.Scopes),
+	}
+	if apiKey.ExpireTime != nil {
+		claims.ExpireTime = *apiKey.ExpireTime
+	}
+	return claims, nil
+}
+func (t token) GetTokenClaims(ctx context.Context, tokenString string) (TokenClaims, error) {
+	logger := utils.LoggerWithContext(ctx, t.logger)
+
+	if strings.HasPrefix(tokenString, apiKeyPrefix) {
+		return t.getAPIKeyClaims(ctx, tokenString)
+	}
+
 	parsedToken, err := jwt.Parse(tokenString, func(parsedToken *jwt.Token) (interface{}, error) {
 		if _, ok := parsedToken.Method.(*jwt.SigningMethodRSA); !ok {
 			logger.Error("unexpected signing method")
@@ -158,46 +268,112 @@ func (t token) GetAccountIDAndExpireTime(ctx context.Context, tokenString string
 	})
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to parse token")
-		return 0, time.Time{}, errInvalidToken
+		return TokenClaims{}, errInvalidToken
 	}
 	if !parsedToken.Valid {
 		logger.Error("invalid token")
-		return 0, time.Time{}, errInvalidToken
+		return TokenClaims{}, errInvalidToken
 	}
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
 		logger.Error("cannot get token's claims")
-		return 0, time.Time{}, errCannotGetTokensClaims
+		return TokenClaims{}, errCannotGetTokensClaims
 	}
 	accountID, ok := claims["sub"].(float64)
 	if !ok {
 		logger.Error("cannot get token's sub claim")
-		return 0, time.Time{}, errCannotGetTokensSubClaim
+		return TokenClaims{}, errCannotGetTokensSubClaim
 	}
 	expireTimeUnix, ok := claims["exp"].(float64)
 	if !ok {
 		logger.Error("cannot get token's exp claim")
-		return 0, time.Time{}, errCannotGetTokensExpClaim
+		return TokenClaims{}, errCannotGetTokensExpClaim
+	}
+	sessionID, ok := claims["sid"].(float64)
+	if !ok {
+		logger.Error("cannot get token's sid claim")
+		return TokenClaims{}, errCannotGetTokensSidClaim
+	}
+	revoked, err := t.isSessionRevoked(ctx, uint64(sessionID))
+	if err != nil {
+		return TokenClaims{}, err
 	}
-	return uint64(accountID), time.Unix(int64(expireTimeUnix), 0), nil
+	if revoked {
+		return TokenClaims{}, errSessionRevoked
+	}
+	return TokenClaims{
+		AccountID:  uint64(accountID),
+		SessionID:  uint64(sessionID),
+		ExpireTime: time.Unix(int64(expireTimeUnix), 0),
+	}, nil
 }
-func (t token) GetToken(ctx context.Context, accountID uint64) (string, time.Time, error) {
+func (t token) GetToken(ctx context.Context, accountID uint64, sessionID uint64) (string, time.Time, error) {
 	logger := utils.LoggerWithContext(ctx, t.logger)
 
+	signingKey, err := t.getSigningKey(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
 	expireTime := time.Now().Add(t.expiresIn)
 	token := jwt.NewWithClaims(jwt.SigningMethodRS512, jwt.MapClaims{
 		"sub": accountID,
+		"sid": sessionID,
 		"exp": expireTime.Unix(),
-		"kid": t.tokenPublicKeyID,
+		"kid": signingKey.id,
 	})
-	tokenString, err := token.SignedString(t.privateKey)
+	// The kid header lets services verifying tokens with the JWKS endpoint find the key.
+	token.Header["kid"] = strconv.FormatUint(signingKey.id, 10)
+	tokenString, err := token.SignedString(signingKey.privateKey)
 	if err != nil {
 		logger.With(zap.Error(err)).Error("failed to sign token")
 		return "", time.Time{}, errFailedToSignToken
 	}
 	return tokenString, expireTime, nil
 }
+func (t token) RenewTokenIfNearExpiry(ctx context.Context, tokenString string) (string, bool, error) {
+	claims, err := t.GetTokenClaims(ctx, tokenString)
+	if err != nil {
+		return "", false, err
+	}
+	if claims.APIKeyID != 0 || time.Until(claims.ExpireTime) > t.regenerateBeforeExpiry {
+		return "", false, nil
+	}
+	renewedToken, _, err := t.GetToken(ctx, claims.AccountID, claims.SessionID)
+	if err != nil {
+		return "", false, err
+	}
+	return renewedToken, true, nil
+}
+func (t token) RevokeSession(ctx context.Context, sessionID uint64) error {
+	session, err := t.sessionDataAccessor.GetSessionWithXLock(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	session.Revoked = true
+	if err = t.sessionDataAccessor.UpdateSession(ctx, session); err != nil {
+		return err
+	}
+	// A stale cache entry would keep the session usable, so failing to update the cache fails the revocation.
+	return t.revokedSessionCache.Set(ctx, sessionID, true, t.expiresIn)
+}
+func (t token) RevokeSessionsOfAccount(ctx context.Context, accountID uint64, exceptSessionID uint64) error {
+	sessionIDList, err := t.sessionDataAccessor.GetUnrevokedSessionIDListOfAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDList {
+		if sessionID == exceptSessionID {
+			continue
+		}
+		if err = t.RevokeSession(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func (t token) WithDatabase(database database.Database) Token {
 	t.accountDataAccessor = t.accountDataAccessor.WithDatabase(database)
+	t.sessionDataAccessor = t.sessionDataAccessor.WithDatabase(database)
+	t.apiKeyDataAccessor = t.apiKeyDataAccessor.WithDatabase(database)
 	return t
 }
@@ -0,0 +1,239 @@
+package logic
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/utils"
+)
+
+const (
+	// signingKeyReloadInterval bounds how long an instance keeps signing with a key another instance has rotated out.
+	signingKeyReloadInterval = time.Minute
+	jsonWebKeyTypeRSA        = "RSA"
+	jsonWebKeyUseSignature   = "sig"
+)
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type signingKey struct {
+	id         uint64
+	privateKey *rsa.PrivateKey
+	loadedTime time.Time
+}
+type signingKeyHolder struct {
+	mutex      sync.Mutex
+	signingKey *signingKey
+}
+
+func newPrivateKeyEncryptionAEAD(signingKeyConfig configs.SigningKey) (cipher.AEAD, error) {
+	encryptionKey, err := signingKeyConfig.GetEncryptionKeyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptPrivateKey returns the base64 encoded nonce followed by the sealed PKCS #1 encoding of the private key.
+func (t token) encryptPrivateKey(privateKey *rsa.PrivateKey) (string, error) {
+	nonce := make([]byte, t.privateKeyEncryptionAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := t.privateKeyEncryptionAEAD.Seal(nonce, nonce, x509.MarshalPKCS1PrivateKey(privateKey), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+func (t token) decryptPrivateKey(privateKeyCiphertext string) (*rsa.PrivateKey, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(privateKeyCiphertext)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := t.privateKeyEncryptionAEAD.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("private key ciphertext is too short")
+	}
+	plaintext, err := t.privateKeyEncryptionAEAD.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKCS1PrivateKey(plaintext)
+}
+
+// createSigningKey stores a new key pair and retires every other key, leaving them valid for verification until
+// tokens signed by them can no longer be unexpired.
+func (t token) createSigningKey(ctx context.Context, tokenPublicKeyDataAccessor database.TokenPublicKeyDataAccessor) (signingKey, error) {
+	logger := utils.LoggerWithContext(ctx, t.logger)
+
+	rsaKeyPair, err := generateRSAKeyPair(rs512KeyPairBitCount)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to generate rsa key pair")
+		return signingKey{}, status.Error(codes.Internal, "failed to generate rsa key pair")
+	}
+	publicKeyBytes, err := pemEncodePublicKey(&rsaKeyPair.PublicKey)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to encode public key in pem format")
+		return signingKey{}, status.Error(codes.Internal, "failed to encode public key in pem format")
+	}
+	privateKeyCiphertext, err := t.encryptPrivateKey(rsaKeyPair)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to encrypt private key")
+		return signingKey{}, status.Error(codes.Internal, "failed to encrypt private key")
+	}
+	now := time.Now()
+	tokenPublicKeyID, err := tokenPublicKeyDataAccessor.CreatePublicKey(ctx, database.TokenPublicKey{
+		PublicKey:            string(publicKeyBytes),
+		PrivateKeyCiphertext: sql.NullString{String: privateKeyCiphertext, Valid: true},
+		CreatedTime:          now,
+	})
+	if err != nil {
+		return signingKey{}, err
+	}
+	if err = tokenPublicKeyDataAccessor.RetirePublicKeysExcept(
+		ctx, tokenPublicKeyID, now, now.Add(t.expiresIn+signingKeyReloadInterval),
+	); err != nil {
+		return signingKey{}, err
+	}
+	logger.With(zap.Uint64("token_public_key_id", tokenPublicKeyID)).Info("created new token signing key")
+	return signingKey{
+		id:         tokenPublicKeyID,
+		privateKey: rsaKeyPair,
+		loadedTime: now,
+	}, nil
+}
+func (t token) loadActiveSigningKey(ctx context.Context) (signingKey, error) {
+	logger := utils.LoggerWithContext(ctx, t.logger)
+
+	var activeSigningKey signingKey
+	txErr := t.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		tokenPublicKeyDataAccessor := t.tokenPublicKeyDataAccessor.WithDatabase(td)
+		tokenPublicKey, err := tokenPublicKeyDataAccessor.GetActivePublicKey(ctx)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			activeSigningKey, err = t.createSigningKey(ctx, tokenPublicKeyDataAccessor)
+			return err
+		}
+		privateKey, err := t.decryptPrivateKey(tokenPublicKey.PrivateKeyCiphertext.String)
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to decrypt private key")
+			return status.Error(codes.Internal, "failed to decrypt private key")
+		}
+		activeSigningKey = signingKey{
+			id:         tokenPublicKey.ID,
+			privateKey: privateKey,
+			loadedTime: time.Now(),
+		}
+		return nil
+	})
+	if txErr != nil {
+		return signingKey{}, txErr
+	}
+	return activeSigningKey, nil
+}
+func (t token) getSigningKey(ctx context.Context) (signingKey, error) {
+	t.signingKeyHolder.mutex.Lock()
+	defer t.signingKeyHolder.mutex.Unlock()
+	if t.signingKeyHolder.signingKey != nil && time.Since(t.signingKeyHolder.signingKey.loadedTime) < signingKeyReloadInterval {
+		return *t.signingKeyHolder.signingKey, nil
+	}
+	activeSigningKey, err := t.loadActiveSigningKey(ctx)
+	if err != nil {
+		return signingKey{}, err
+	}
+	t.signingKeyHolder.signingKey = &activeSigningKey
+	return activeSigningKey, nil
+}
+func (t token) RotateSigningKey(ctx context.Context) error {
+	var rotatedSigningKey *signingKey
+	txErr := t.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		tokenPublicKeyDataAccessor := t.tokenPublicKeyDataAccessor.WithDatabase(td)
+		// The lock keeps instances running this job at the same time from rotating more than once.
+		tokenPublicKey, err := tokenPublicKeyDataAccessor.GetActivePublicKeyWithXLock(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && time.Since(tokenPublicKey.CreatedTime) < t.rotationInterval {
+			return nil
+		}
+		newSigningKey, err := t.createSigningKey(ctx, tokenPublicKeyDataAccessor)
+		if err != nil {
+			return err
+		}
+		rotatedSigningKey = &newSigningKey
+		return nil
+	})
+	if txErr != nil {
+		return txErr
+	}
+	if rotatedSigningKey != nil {
+		t.signingKeyHolder.mutex.Lock()
+		t.signingKeyHolder.signingKey = rotatedSigningKey
+		t.signingKeyHolder.mutex.Unlock()
+	}
+	return t.tokenPublicKeyDataAccessor.DeleteExpiredPublicKeys(ctx, time.Now())
+}
+func (t token) GetJSONWebKeySet(ctx context.Context) (JSONWebKeySet, error) {
+	logger := utils.LoggerWithContext(ctx, t.logger)
+
+	tokenPublicKeyList, err := t.tokenPublicKeyDataAccessor.GetUnexpiredPublicKeyList(ctx, time.Now())
+	if err != nil {
+		return JSONWebKeySet{}, err
+	}
+	jsonWebKeySet := JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(tokenPublicKeyList)),
+	}
+	for _, tokenPublicKey := range tokenPublicKeyList {
+		publicKey, parseErr := jwt.ParseRSAPublicKeyFromPEM([]byte(tokenPublicKey.PublicKey))
+		if parseErr != nil {
+			logger.
+				With(zap.Uint64("token_public_key_id", tokenPublicKey.ID)).
+				With(zap.Error(parseErr)).
+				Error("failed to parse public key, will skip")
+			continue
+		}
+		jsonWebKeySet.Keys = append(jsonWebKeySet.Keys, JSONWebKey{
+			KeyType:   jsonWebKeyTypeRSA,
+			Use:       jsonWebKeyUseSignature,
+			Algorithm: jwt.SigningMethodRS512.Alg(),
+			KeyID:     strconv.FormatUint(tokenPublicKey.ID, 10),
+			Modulus:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		})
+	}
+	return jsonWebKeySet, nil
+}
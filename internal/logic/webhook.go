@@ -0,0 +1,593 @@
+package logic
+
+import (
+	"GoLoad/internal/configs"
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/dataaccess/mq/producer"
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gammazero/workerpool"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	WebhookHeaderSignature  = "X-GoLoad-Signature"
+	WebhookHeaderTimestamp  = "X-GoLoad-Timestamp"
+	WebhookHeaderEventID    = "X-GoLoad-Event-ID"
+	WebhookHeaderEventType  = "X-GoLoad-Event-Type"
+	WebhookHeaderDeliveryID = "X-GoLoad-Delivery-ID"
+	// webhookSignaturePrefix names the algorithm of the signature, so that it can be changed without breaking receivers.
+	webhookSignaturePrefix = "sha256="
+)
+
+var (
+	errWebhookURLNotAllowed = status.Error(codes.InvalidArgument, "webhook url must resolve to public addresses only")
+	errWebhookAddressDenied = errors.New("webhook address is not public")
+	// sharedAddressSpacePrefix is the carrier-grade NAT range, which is not covered by netip.Addr.IsPrivate.
+	sharedAddressSpacePrefix = netip.MustParsePrefix("100.64.0.0/10")
+)
+
+// webhookEventTypeList are the lifecycle events webhooks are notified of.
+var webhookEventTypeList = []producer.DownloadTaskLifecycleEventType{
+	producer.DownloadTaskLifecycleEventTypeSucceeded,
+	producer.DownloadTaskLifecycleEventTypeFailed,
+}
+
+type CreateWebhookParams struct {
+	URL    string
+	Secret string
+	// DownloadTaskID is 0 for webhooks notified of every download task of the account.
+	DownloadTaskID uint64
+}
+type CreateWebhookOutput struct {
+	Webhook *go_load.Webhook
+	Secret  string
+}
+type GetWebhookListOutput struct {
+	WebhookList []*go_load.Webhook
+}
+type DeleteWebhookParams struct {
+	WebhookID uint64
+}
+type GetWebhookDeliveryListParams struct {
+	WebhookID uint64
+	Offset    uint64
+	Limit     uint64
+}
+type GetWebhookDeliveryListOutput struct {
+	WebhookDeliveryList       []*go_load.WebhookDelivery
+	TotalWebhookDeliveryCount uint64
+}
+
+// Webhook notifies URLs of the accounts when their download tasks succeed or fail. Each payload is a download task
+// lifecycle event signed with the secret of the webhook, as the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Webhooks can only be created and deleted with a session, API keys can only list them and their deliveries.
+type Webhook interface {
+	CreateWebhook(ctx context.Context, params CreateWebhookParams) (CreateWebhookOutput, error)
+	GetWebhookList(ctx context.Context) (GetWebhookListOutput, error)
+	DeleteWebhook(ctx context.Context, params DeleteWebhookParams) error
+	GetWebhookDeliveryList(ctx context.Context, params GetWebhookDeliveryListParams) (GetWebhookDeliveryListOutput, error)
+	// CreateWebhookDeliveryListOfEvent records a delivery of the event for each webhook to notify. It can be called
+	// several times with the same event.
+	CreateWebhookDeliveryListOfEvent(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error
+	// DeliverPendingWebhookDeliveries sends the deliveries that are due, retrying failed ones with exponential backoff.
+	// It also deletes finished deliveries past their retention.
+	DeliverPendingWebhookDeliveries(ctx context.Context) error
+	// DeleteWebhookListOfDownloadTask and DeleteWebhookListOfAccount also delete the deliveries of the webhooks, and
+	// should be called on a Webhook returned by WithDatabase with the transaction deleting the download task or account.
+	DeleteWebhookListOfDownloadTask(ctx context.Context, downloadTaskID uint64) error
+	DeleteWebhookListOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database database.Database) Webhook
+}
+type webhook struct {
+	goquDatabase                *goqu.Database
+	webhookDataAccessor         database.WebhookDataAccessor
+	webhookDeliveryDataAccessor database.WebhookDeliveryDataAccessor
+	downloadTaskDataAccessor    database.DownloadTaskDataAccessor
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor
+	webhookConfig               configs.Webhook
+	httpClient                  *http.Client
+	logger                      *zap.Logger
+}
+
+func NewWebhook(
+	goquDatabase *goqu.Database,
+	webhookDataAccessor database.WebhookDataAccessor,
+	webhookDeliveryDataAccessor database.WebhookDeliveryDataAccessor,
+	downloadTaskDataAccessor database.DownloadTaskDataAccessor,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor,
+	webhookConfig configs.Webhook,
+	logger *zap.Logger,
+) (Webhook, error) {
+	requestTimeout, err := webhookConfig.GetRequestTimeoutDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse webhook request timeout")
+		return nil, err
+	}
+	// A batch size of 0 is no limit to the database, so that a delivery run would never stop fetching more deliveries.
+	if webhookConfig.BatchSize == 0 {
+		return nil, errors.New("webhook batch_size must be positive")
+	}
+	return &webhook{
+		goquDatabase:                goquDatabase,
+		webhookDataAccessor:         webhookDataAccessor,
+		webhookDeliveryDataAccessor: webhookDeliveryDataAccessor,
+		downloadTaskDataAccessor:    downloadTaskDataAccessor,
+		workspaceMemberDataAccessor: workspaceMemberDataAccessor,
+		webhookConfig:               webhookConfig,
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: newWebhookTransport(),
+			// Redirects are not followed, so that a webhook cannot be used to reach another URL than its own.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+	}, nil
+}
+
+// isPublicWebhookAddress rejects the addresses a webhook could use to reach the network GoLoad runs in, such as the
+// cloud metadata endpoint.
+func isPublicWebhookAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpacePrefix.Contains(addr)
+}
+
+// newWebhookTransport checks the address of each connection right before it is made, so that a host name resolving
+// to a public address when the webhook is created cannot be rebound to an internal one afterward. Proxies from the
+// environment are not used, since the check would then apply to the proxy instead of the webhook.
+func newWebhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicWebhookAddress(addrPort.Addr()) {
+				return errWebhookAddressDenied
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+func (w webhook) databaseWebhookToProtoWebhook(webhook database.Webhook) *go_load.Webhook {
+	return &go_load.Webhook{
+		Id:             webhook.ID,
+		Url:            webhook.URL,
+		DownloadTaskId: lo.FromPtr(webhook.OfDownloadTaskID),
+		CreatedTime:    webhook.CreatedTime.Unix(),
+	}
+}
+func (w webhook) databaseWebhookDeliveryToProtoWebhookDelivery(webhookDelivery database.WebhookDelivery) *go_load.WebhookDelivery {
+	protoWebhookDelivery := &go_load.WebhookDelivery{
+		Id:                     webhookDelivery.ID,
+		WebhookId:              webhookDelivery.OfWebhookID,
+		DownloadTaskId:         webhookDelivery.OfDownloadTaskID,
+		EventId:                webhookDelivery.EventID,
+		EventType:              webhookDelivery.EventType,
+		Status:                 webhookDelivery.Status,
+		AttemptCount:           webhookDelivery.AttemptCount,
+		LastResponseStatusCode: lo.FromPtr(webhookDelivery.LastResponseStatusCode),
+		LastError:              lo.FromPtr(webhookDelivery.LastError),
+		CreatedTime:            webhookDelivery.CreatedTime.Unix(),
+	}
+	if webhookDelivery.Status == go_load.WebhookDeliveryStatus_WebhookDeliveryPending {
+		protoWebhookDelivery.NextAttemptTime = webhookDelivery.NextAttemptTime.Unix()
+	}
+	if webhookDelivery.DeliveredTime != nil {
+		protoWebhookDelivery.DeliveredTime = webhookDelivery.DeliveredTime.Unix()
+	}
+	return protoWebhookDelivery
+}
+func (w webhook) validateWebhookURL(ctx context.Context, webhookURL string) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).With(zap.String("url", webhookURL))
+
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return status.Error(codes.InvalidArgument, "webhook url must be an absolute http or https url")
+	}
+	addrList, err := net.DefaultResolver.LookupNetIP(ctx, "ip", parsedURL.Hostname())
+	if err != nil {
+		logger.With(zap.Error(err)).Warn("failed to resolve webhook host")
+		return status.Error(codes.InvalidArgument, "failed to resolve webhook host")
+	}
+	for _, addr := range addrList {
+		if !isPublicWebhookAddress(addr) {
+			logger.With(zap.Stringer("address", addr)).Warn("webhook host resolves to a non-public address")
+			return errWebhookURLNotAllowed
+		}
+	}
+	return nil
+}
+func (w webhook) CreateWebhook(ctx context.Context, params CreateWebhookParams) (CreateWebhookOutput, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return CreateWebhookOutput{}, err
+	}
+	accountID := principal.AccountID
+	if err = w.validateWebhookURL(ctx, params.URL); err != nil {
+		return CreateWebhookOutput{}, err
+	}
+	databaseWebhook := database.Webhook{
+		OfAccountID: accountID,
+		URL:         params.URL,
+		Secret:      params.Secret,
+		CreatedTime: time.Now(),
+	}
+	if params.DownloadTaskID != 0 {
+		downloadTask, getDownloadTaskErr := w.downloadTaskDataAccessor.GetDownloadTask(ctx, params.DownloadTaskID)
+		if getDownloadTaskErr != nil {
+			return CreateWebhookOutput{}, getDownloadTaskErr
+		}
+		canAccess, canAccessErr := canAccessDownloadTask(
+			ctx, w.workspaceMemberDataAccessor, accountID, downloadTask, allWorkspaceRoleList)
+		if canAccessErr != nil {
+			return CreateWebhookOutput{}, canAccessErr
+		}
+		if !canAccess {
+			return CreateWebhookOutput{}, status.Error(codes.PermissionDenied, "trying to watch a download task the account does not own")
+		}
+		databaseWebhook.OfDownloadTaskID = &params.DownloadTaskID
+	}
+	if databaseWebhook.Secret == "" {
+		secretBytes := make([]byte, randomTokenByteCount)
+		if _, err = rand.Read(secretBytes); err != nil {
+			logger.With(zap.Error(err)).Error("failed to generate webhook secret")
+			return CreateWebhookOutput{}, status.Error(codes.Internal, "failed to generate webhook secret")
+		}
+		databaseWebhook.Secret = base64.RawURLEncoding.EncodeToString(secretBytes)
+	}
+	databaseWebhook.ID, err = w.webhookDataAccessor.CreateWebhook(ctx, databaseWebhook)
+	if err != nil {
+		return CreateWebhookOutput{}, err
+	}
+	logger.
+		With(zap.Uint64("account_id", accountID)).
+		With(zap.Uint64("webhook_id", databaseWebhook.ID)).
+		Info("created webhook")
+	return CreateWebhookOutput{
+		Webhook: w.databaseWebhookToProtoWebhook(databaseWebhook),
+		Secret:  databaseWebhook.Secret,
+	}, nil
+}
+func (w webhook) GetWebhookList(ctx context.Context) (GetWebhookListOutput, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_ReadOnly)
+	if err != nil {
+		return GetWebhookListOutput{}, err
+	}
+	webhookList, err := w.webhookDataAccessor.GetWebhookListOfAccount(ctx, accountID)
+	if err != nil {
+		return GetWebhookListOutput{}, err
+	}
+	return GetWebhookListOutput{
+		WebhookList: lo.Map(webhookList, func(item database.Webhook, _ int) *go_load.Webhook {
+			return w.databaseWebhookToProtoWebhook(item)
+		}),
+	}, nil
+}
+func (w webhook) deleteWebhookList(ctx context.Context, webhookList []database.Webhook) error {
+	for _, webhook := range webhookList {
+		if err := w.webhookDeliveryDataAccessor.DeleteWebhookDeliveriesOfWebhook(ctx, webhook.ID); err != nil {
+			return err
+		}
+		if err := w.webhookDataAccessor.DeleteWebhook(ctx, webhook.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (w webhook) DeleteWebhook(ctx context.Context, params DeleteWebhookParams) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	accountID := principal.AccountID
+	return w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		webhook, getWebhookErr := w.webhookDataAccessor.WithDatabase(td).GetWebhookWithXLock(ctx, params.WebhookID)
+		if getWebhookErr != nil {
+			return getWebhookErr
+		}
+		if webhook.OfAccountID != accountID {
+			return status.Error(codes.PermissionDenied, "trying to delete a webhook the account does not own")
+		}
+		if deleteErr := w.webhookDeliveryDataAccessor.WithDatabase(td).
+			DeleteWebhookDeliveriesOfWebhook(ctx, params.WebhookID); deleteErr != nil {
+			return deleteErr
+		}
+		return w.webhookDataAccessor.WithDatabase(td).DeleteWebhook(ctx, params.WebhookID)
+	})
+}
+func (w webhook) GetWebhookDeliveryList(
+	ctx context.Context,
+	params GetWebhookDeliveryListParams,
+) (GetWebhookDeliveryListOutput, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_ReadOnly)
+	if err != nil {
+		return GetWebhookDeliveryListOutput{}, err
+	}
+	webhook, err := w.webhookDataAccessor.GetWebhook(ctx, params.WebhookID)
+	if err != nil {
+		return GetWebhookDeliveryListOutput{}, err
+	}
+	if webhook.OfAccountID != accountID {
+		return GetWebhookDeliveryListOutput{}, status.Error(codes.PermissionDenied, "trying to get deliveries of a webhook the account does not own")
+	}
+	totalWebhookDeliveryCount, err := w.webhookDeliveryDataAccessor.GetWebhookDeliveryCountOfWebhook(ctx, params.WebhookID)
+	if err != nil {
+		return GetWebhookDeliveryListOutput{}, err
+	}
+	webhookDeliveryList, err := w.webhookDeliveryDataAccessor.
+		GetWebhookDeliveryListOfWebhook(ctx, params.WebhookID, params.Offset, params.Limit)
+	if err != nil {
+		return GetWebhookDeliveryListOutput{}, err
+	}
+	return GetWebhookDeliveryListOutput{
+		WebhookDeliveryList: lo.Map(webhookDeliveryList, func(item database.WebhookDelivery, _ int) *go_load.WebhookDelivery {
+			return w.databaseWebhookDeliveryToProtoWebhookDelivery(item)
+		}),
+		TotalWebhookDeliveryCount: totalWebhookDeliveryCount,
+	}, nil
+}
+func (w webhook) CreateWebhookDeliveryListOfEvent(ctx context.Context, event producer.DownloadTaskLifecycleEvent) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.String("event_id", event.EventID)).
+		With(zap.Uint64("download_task_id", event.DownloadTaskID))
+
+	if !lo.Contains(webhookEventTypeList, event.EventType) {
+		return nil
+	}
+	webhookList, err := w.webhookDataAccessor.GetWebhookListToNotifyOfDownloadTask(ctx, event.OfAccountID, event.DownloadTaskID)
+	if err != nil {
+		return err
+	}
+	if len(webhookList) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to marshal webhook payload")
+		return status.Error(codes.Internal, "failed to marshal webhook payload")
+	}
+	now := time.Now()
+	for _, webhook := range webhookList {
+		if err = w.webhookDeliveryDataAccessor.CreateWebhookDelivery(ctx, database.WebhookDelivery{
+			OfWebhookID:      webhook.ID,
+			OfDownloadTaskID: event.DownloadTaskID,
+			EventID:          event.EventID,
+			EventType:        string(event.EventType),
+			Payload:          payload,
+			Status:           go_load.WebhookDeliveryStatus_WebhookDeliveryPending,
+			NextAttemptTime:  now,
+			CreatedTime:      now,
+		}); err != nil {
+			return err
+		}
+	}
+	logger.With(zap.Int("webhook_count", len(webhookList))).Info("created webhook deliveries")
+	return nil
+}
+func (w webhook) getRetryDelay(attemptCount uint64) (time.Duration, error) {
+	retryBaseDelay, err := w.webhookConfig.GetRetryBaseDelayDuration()
+	if err != nil {
+		return 0, err
+	}
+	retryMaxDelay, err := w.webhookConfig.GetRetryMaxDelayDuration()
+	if err != nil {
+		return 0, err
+	}
+	retryDelay := retryBaseDelay
+	for i := uint64(1); i < attemptCount && retryDelay < retryMaxDelay; i++ {
+		retryDelay *= 2
+	}
+	return min(retryDelay, retryMaxDelay), nil
+}
+func (w webhook) signPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhookDelivery returns the status code of the response, or 0 if no response was received.
+func (w webhook) sendWebhookDelivery(
+	ctx context.Context,
+	webhook database.Webhook,
+	webhookDelivery database.WebhookDelivery,
+) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(webhookDelivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookHeaderSignature, w.signPayload(webhook.Secret, timestamp, webhookDelivery.Payload))
+	request.Header.Set(WebhookHeaderTimestamp, timestamp)
+	request.Header.Set(WebhookHeaderEventID, webhookDelivery.EventID)
+	request.Header.Set(WebhookHeaderEventType, webhookDelivery.EventType)
+	request.Header.Set(WebhookHeaderDeliveryID, strconv.FormatUint(webhookDelivery.ID, 10))
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusOK && response.StatusCode < http.StatusMultipleChoices {
+		return response.StatusCode, nil
+	}
+	// The response body is not kept, since it is shown to the account and webhooks are not trusted.
+	return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+}
+func (w webhook) deliverWebhookDelivery(
+	ctx context.Context,
+	webhook database.Webhook,
+	webhookDelivery database.WebhookDelivery,
+) error {
+	logger := utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("webhook_id", webhook.ID)).
+		With(zap.Uint64("webhook_delivery_id", webhookDelivery.ID))
+
+	statusCode, sendErr := w.sendWebhookDelivery(ctx, webhook, webhookDelivery)
+	webhookDelivery.AttemptCount++
+	webhookDelivery.LastResponseStatusCode = nil
+	if statusCode != 0 {
+		webhookDelivery.LastResponseStatusCode = lo.ToPtr(int32(statusCode))
+	}
+	switch {
+	case sendErr == nil:
+		deliveredTime := time.Now()
+		webhookDelivery.Status = go_load.WebhookDeliveryStatus_WebhookDeliverySucceeded
+		webhookDelivery.DeliveredTime = &deliveredTime
+		webhookDelivery.LastError = nil
+	case webhookDelivery.AttemptCount >= w.webhookConfig.MaxAttempts:
+		logger.With(zap.Error(sendErr)).Warn("failed to deliver webhook, giving up")
+		webhookDelivery.Status = go_load.WebhookDeliveryStatus_WebhookDeliveryFailed
+		webhookDelivery.LastError = lo.ToPtr(sendErr.Error())
+	default:
+		logger.With(zap.Error(sendErr)).Warn("failed to deliver webhook, will retry")
+		retryDelay, err := w.getRetryDelay(webhookDelivery.AttemptCount)
+		if err != nil {
+			logger.With(zap.Error(err)).Error("failed to parse webhook retry delay")
+			return status.Error(codes.Internal, "failed to parse webhook retry delay")
+		}
+		webhookDelivery.NextAttemptTime = time.Now().Add(retryDelay)
+		webhookDelivery.LastError = lo.ToPtr(sendErr.Error())
+	}
+	return w.webhookDeliveryDataAccessor.UpdateWebhookDelivery(ctx, webhookDelivery)
+}
+
+// claimPendingWebhookDeliveryList pushes back the next attempt of the due deliveries it returns past the request
+// timeout, so that other workers do not send them while they are being sent outside of the transaction.
+func (w webhook) claimPendingWebhookDeliveryList(ctx context.Context) ([]database.WebhookDelivery, error) {
+	requestTimeout, err := w.webhookConfig.GetRequestTimeoutDuration()
+	if err != nil {
+		return nil, err
+	}
+	webhookDeliveryList := make([]database.WebhookDelivery, 0)
+	txErr := w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		now := time.Now()
+		webhookDeliveryList, err = w.webhookDeliveryDataAccessor.WithDatabase(td).
+			GetPendingWebhookDeliveryListWithXLock(ctx, now, w.webhookConfig.BatchSize)
+		if err != nil {
+			return err
+		}
+		return w.webhookDeliveryDataAccessor.WithDatabase(td).UpdateWebhookDeliveryListNextAttemptTime(
+			ctx,
+			lo.Map(webhookDeliveryList, func(item database.WebhookDelivery, _ int) uint64 { return item.ID }),
+			now.Add(2*requestTimeout),
+		)
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	return webhookDeliveryList, nil
+}
+
+// deliverPendingWebhookDeliveryBatch reports whether there may be more deliveries due.
+func (w webhook) deliverPendingWebhookDeliveryBatch(ctx context.Context) (bool, error) {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	webhookDeliveryList, err := w.claimPendingWebhookDeliveryList(ctx)
+	if err != nil {
+		return false, err
+	}
+	webhookList, err := w.webhookDataAccessor.GetWebhookListByIDList(ctx, lo.Uniq(lo.Map(
+		webhookDeliveryList, func(item database.WebhookDelivery, _ int) uint64 { return item.OfWebhookID },
+	)))
+	if err != nil {
+		return false, err
+	}
+	webhookMap := lo.KeyBy(webhookList, func(item database.Webhook) uint64 { return item.ID })
+	workerPool := workerpool.New(w.webhookConfig.ConcurrencyLimit)
+	for _, webhookDelivery := range webhookDeliveryList {
+		webhook, ok := webhookMap[webhookDelivery.OfWebhookID]
+		if !ok {
+			// The webhook was deleted along with its deliveries after they were claimed.
+			continue
+		}
+		workerPool.Submit(func() {
+			if deliverErr := w.deliverWebhookDelivery(ctx, webhook, webhookDelivery); deliverErr != nil {
+				logger.
+					With(zap.Uint64("webhook_delivery_id", webhookDelivery.ID)).
+					With(zap.Error(deliverErr)).
+					Error("failed to deliver webhook delivery")
+			}
+		})
+	}
+	workerPool.StopWait()
+	return uint64(len(webhookDeliveryList)) == w.webhookConfig.BatchSize, nil
+}
+func (w webhook) DeliverPendingWebhookDeliveries(ctx context.Context) error {
+	logger := utils.LoggerWithContext(ctx, w.logger)
+
+	for {
+		hasMore, err := w.deliverPendingWebhookDeliveryBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasMore {
+			break
+		}
+	}
+	deliveryRetention, err := w.webhookConfig.GetDeliveryRetentionDuration()
+	if err != nil {
+		logger.With(zap.Error(err)).Error("failed to parse webhook delivery retention")
+		return status.Error(codes.Internal, "failed to parse webhook delivery retention")
+	}
+	return w.webhookDeliveryDataAccessor.DeleteFinishedWebhookDeliveriesBefore(ctx, time.Now().Add(-deliveryRetention))
+}
+func (w webhook) DeleteWebhookListOfDownloadTask(ctx context.Context, downloadTaskID uint64) error {
+	webhookList, err := w.webhookDataAccessor.GetWebhookListOfDownloadTask(ctx, downloadTaskID)
+	if err != nil {
+		return err
+	}
+	return w.deleteWebhookList(ctx, webhookList)
+}
+func (w webhook) DeleteWebhookListOfAccount(ctx context.Context, accountID uint64) error {
+	webhookList, err := w.webhookDataAccessor.GetWebhookListOfAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return w.deleteWebhookList(ctx, webhookList)
+}
+func (w webhook) WithDatabase(database database.Database) Webhook {
+	w.webhookDataAccessor = w.webhookDataAccessor.WithDatabase(database)
+	w.webhookDeliveryDataAccessor = w.webhookDeliveryDataAccessor.WithDatabase(database)
+	return w
+}
@@ -9,4 +9,17 @@ var WireSet = wire.NewSet(
 	NewHash,
 	NewToken,
 	NewDownloadTask,
+	NewFileURLSigner,
+	NewPasswordPolicy,
+	NewLoginRateLimiter,
+	NewAPIKey,
+	NewOIDC,
+	NewAuthorization,
+	NewAdmin,
+	NewWorkspace,
+	NewOutbox,
+	NewDeadLetter,
+	NewWebhook,
+	NewLeaderElection,
+	NewHealth,
 )
@@ -0,0 +1,426 @@
+package logic
+
+import (
+	"GoLoad/internal/dataaccess/database"
+	"GoLoad/internal/generated/grpc/go_load"
+	"GoLoad/internal/utils"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// allWorkspaceRoleList allows every member of a workspace.
+	allWorkspaceRoleList    = []go_load.WorkspaceRole{go_load.WorkspaceRole_Owner, go_load.WorkspaceRole_Editor, go_load.WorkspaceRole_Viewer}
+	editorWorkspaceRoleList = []go_load.WorkspaceRole{go_load.WorkspaceRole_Owner, go_load.WorkspaceRole_Editor}
+	ownerWorkspaceRoleList  = []go_load.WorkspaceRole{go_load.WorkspaceRole_Owner}
+
+	errWorkspaceRoleNotAllowed = status.Error(codes.PermissionDenied, "workspace role is not allowed to perform this operation")
+	errInvalidWorkspaceRole    = status.Error(codes.InvalidArgument, "invalid workspace role")
+	errWorkspaceWithoutOwner   = status.Error(codes.FailedPrecondition, "workspace must keep at least one owner")
+)
+
+type CreateWorkspaceParams struct {
+	WorkspaceName string
+}
+type CreateWorkspaceOutput struct {
+	Workspace *go_load.Workspace
+}
+type GetWorkspaceListOutput struct {
+	WorkspaceList []*go_load.Workspace
+}
+type GetWorkspaceMemberListParams struct {
+	WorkspaceID uint64
+}
+type GetWorkspaceMemberListOutput struct {
+	WorkspaceMemberList []*go_load.WorkspaceMember
+}
+type AddWorkspaceMemberParams struct {
+	WorkspaceID uint64
+	AccountName string
+	Role        go_load.WorkspaceRole
+}
+type AddWorkspaceMemberOutput struct {
+	WorkspaceMember *go_load.WorkspaceMember
+}
+type UpdateWorkspaceMemberRoleParams struct {
+	WorkspaceID uint64
+	AccountID   uint64
+	Role        go_load.WorkspaceRole
+}
+type RemoveWorkspaceMemberParams struct {
+	WorkspaceID uint64
+	AccountID   uint64
+}
+
+// Workspace manages workspaces, which own download tasks shared by their members. Membership can only be managed with
+// session tokens, while API keys can still list the workspaces and members of their account.
+type Workspace interface {
+	CreateWorkspace(ctx context.Context, params CreateWorkspaceParams) (CreateWorkspaceOutput, error)
+	GetWorkspaceList(ctx context.Context) (GetWorkspaceListOutput, error)
+	GetWorkspaceMemberList(ctx context.Context, params GetWorkspaceMemberListParams) (GetWorkspaceMemberListOutput, error)
+	AddWorkspaceMember(ctx context.Context, params AddWorkspaceMemberParams) (AddWorkspaceMemberOutput, error)
+	UpdateWorkspaceMemberRole(ctx context.Context, params UpdateWorkspaceMemberRoleParams) error
+	RemoveWorkspaceMember(ctx context.Context, params RemoveWorkspaceMemberParams) error
+	// LeaveWorkspaceListOfAccount removes the account from all of its workspaces, handing the download tasks it created
+	// in each of them over to another owner of the workspace. It fails with errWorkspaceWithoutOwner if the account is
+	// the last owner of one of them, and should be called on a Workspace returned by WithDatabase with a transaction.
+	LeaveWorkspaceListOfAccount(ctx context.Context, accountID uint64) error
+	WithDatabase(database database.Database) Workspace
+}
+type workspace struct {
+	goquDatabase                *goqu.Database
+	accountDataAccessor         database.AccountDataAccessor
+	workspaceDataAccessor       database.WorkspaceDataAccessor
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor
+	downloadTaskDataAccessor    database.DownloadTaskDataAccessor
+	logger                      *zap.Logger
+}
+
+func NewWorkspace(
+	goquDatabase *goqu.Database,
+	accountDataAccessor database.AccountDataAccessor,
+	workspaceDataAccessor database.WorkspaceDataAccessor,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor,
+	downloadTaskDataAccessor database.DownloadTaskDataAccessor,
+	logger *zap.Logger,
+) Workspace {
+	return &workspace{
+		goquDatabase:                goquDatabase,
+		accountDataAccessor:         accountDataAccessor,
+		workspaceDataAccessor:       workspaceDataAccessor,
+		workspaceMemberDataAccessor: workspaceMemberDataAccessor,
+		downloadTaskDataAccessor:    downloadTaskDataAccessor,
+		logger:                      logger,
+	}
+}
+
+// getWorkspaceRoleOfAccount returns UndefinedWorkspaceRole if the account is not a member of the workspace.
+func getWorkspaceRoleOfAccount(
+	ctx context.Context,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor,
+	workspaceID uint64,
+	accountID uint64,
+) (go_load.WorkspaceRole, error) {
+	workspaceMember, err := workspaceMemberDataAccessor.GetWorkspaceMember(ctx, workspaceID, accountID)
+	if err != nil {
+		if errors.Is(err, database.ErrWorkspaceMemberNotFound) {
+			return go_load.WorkspaceRole_UndefinedWorkspaceRole, nil
+		}
+		return go_load.WorkspaceRole_UndefinedWorkspaceRole, err
+	}
+	return workspaceMember.Role, nil
+}
+
+// checkWorkspaceRoleOfAccount returns PermissionDenied unless the account is a member of the workspace with one of the
+// allowed roles.
+func checkWorkspaceRoleOfAccount(
+	ctx context.Context,
+	workspaceMemberDataAccessor database.WorkspaceMemberDataAccessor,
+	workspaceID uint64,
+	accountID uint64,
+	allowedRoleList []go_load.WorkspaceRole,
+) error {
+	role, err := getWorkspaceRoleOfAccount(ctx, workspaceMemberDataAccessor, workspaceID, accountID)
+	if err != nil {
+		return err
+	}
+	if !lo.Contains(allowedRoleList, role) {
+		return errWorkspaceRoleNotAllowed
+	}
+	return nil
+}
+func (w workspace) isValidWorkspaceRole(role go_load.WorkspaceRole) bool {
+	return lo.Contains(allWorkspaceRoleList, role)
+}
+func (w workspace) databaseWorkspaceToProtoWorkspace(workspace database.Workspace, role go_load.WorkspaceRole) *go_load.Workspace {
+	return &go_load.Workspace{
+		Id:            workspace.ID,
+		WorkspaceName: workspace.WorkspaceName,
+		CreatedTime:   workspace.CreatedTime.Unix(),
+		Role:          role,
+	}
+}
+func (w workspace) databaseWorkspaceMemberToProtoWorkspaceMember(
+	workspaceMember database.WorkspaceMember,
+	account database.Account,
+) *go_load.WorkspaceMember {
+	return &go_load.WorkspaceMember{
+		Account: &go_load.Account{
+			Id:          account.ID,
+			AccountName: account.AccountName,
+			Role:        account.Role,
+			Disabled:    account.Disabled,
+		},
+		Role: workspaceMember.Role,
+	}
+}
+
+// checkWorkspaceKeepsOwner returns errWorkspaceWithoutOwner if the member is the last owner of the workspace. The
+// caller must hold the lock on the workspace.
+func (w workspace) checkWorkspaceKeepsOwner(ctx context.Context, td *goqu.TxDatabase, workspaceMember database.WorkspaceMember) error {
+	if workspaceMember.Role != go_load.WorkspaceRole_Owner {
+		return nil
+	}
+	workspaceMemberList, err := w.workspaceMemberDataAccessor.WithDatabase(td).
+		GetWorkspaceMemberListOfWorkspace(ctx, workspaceMember.OfWorkspaceID)
+	if err != nil {
+		return err
+	}
+	ownerCount := lo.CountBy(workspaceMemberList, func(item database.WorkspaceMember) bool {
+		return item.Role == go_load.WorkspaceRole_Owner
+	})
+	if ownerCount <= 1 {
+		return errWorkspaceWithoutOwner
+	}
+	return nil
+}
+func (w workspace) CreateWorkspace(ctx context.Context, params CreateWorkspaceParams) (CreateWorkspaceOutput, error) {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return CreateWorkspaceOutput{}, err
+	}
+	if params.WorkspaceName == "" {
+		return CreateWorkspaceOutput{}, status.Error(codes.InvalidArgument, "workspace name must not be empty")
+	}
+	newWorkspace := database.Workspace{
+		WorkspaceName: params.WorkspaceName,
+		CreatedTime:   time.Now(),
+	}
+	if txErr := w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		newWorkspace.ID, err = w.workspaceDataAccessor.WithDatabase(td).CreateWorkspace(ctx, newWorkspace)
+		if err != nil {
+			return err
+		}
+		return w.workspaceMemberDataAccessor.WithDatabase(td).CreateWorkspaceMember(ctx, database.WorkspaceMember{
+			OfWorkspaceID: newWorkspace.ID,
+			OfAccountID:   principal.AccountID,
+			Role:          go_load.WorkspaceRole_Owner,
+		})
+	}); txErr != nil {
+		return CreateWorkspaceOutput{}, txErr
+	}
+	utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("workspace_id", newWorkspace.ID)).
+		With(zap.Uint64("account_id", principal.AccountID)).
+		Info("created workspace")
+	return CreateWorkspaceOutput{
+		Workspace: w.databaseWorkspaceToProtoWorkspace(newWorkspace, go_load.WorkspaceRole_Owner),
+	}, nil
+}
+func (w workspace) GetWorkspaceList(ctx context.Context) (GetWorkspaceListOutput, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_ReadOnly)
+	if err != nil {
+		return GetWorkspaceListOutput{}, err
+	}
+	workspaceMemberList, err := w.workspaceMemberDataAccessor.GetWorkspaceMemberListOfAccount(ctx, accountID)
+	if err != nil {
+		return GetWorkspaceListOutput{}, err
+	}
+	workspaceList, err := w.workspaceDataAccessor.GetWorkspaceListByIDList(ctx, lo.Map(
+		workspaceMemberList, func(item database.WorkspaceMember, _ int) uint64 { return item.OfWorkspaceID },
+	))
+	if err != nil {
+		return GetWorkspaceListOutput{}, err
+	}
+	workspaceMemberMap := lo.KeyBy(workspaceMemberList, func(item database.WorkspaceMember) uint64 { return item.OfWorkspaceID })
+	return GetWorkspaceListOutput{
+		WorkspaceList: lo.Map(workspaceList, func(item database.Workspace, _ int) *go_load.Workspace {
+			return w.databaseWorkspaceToProtoWorkspace(item, workspaceMemberMap[item.ID].Role)
+		}),
+	}, nil
+}
+func (w workspace) GetWorkspaceMemberList(ctx context.Context, params GetWorkspaceMemberListParams) (GetWorkspaceMemberListOutput, error) {
+	accountID, err := getAccountIDWithScope(ctx, go_load.APIKeyScope_ReadOnly)
+	if err != nil {
+		return GetWorkspaceMemberListOutput{}, err
+	}
+	if err = checkWorkspaceRoleOfAccount(
+		ctx, w.workspaceMemberDataAccessor, params.WorkspaceID, accountID, allWorkspaceRoleList,
+	); err != nil {
+		return GetWorkspaceMemberListOutput{}, err
+	}
+	workspaceMemberList, err := w.workspaceMemberDataAccessor.GetWorkspaceMemberListOfWorkspace(ctx, params.WorkspaceID)
+	if err != nil {
+		return GetWorkspaceMemberListOutput{}, err
+	}
+	accountList, err := w.accountDataAccessor.GetAccountListByIDList(ctx, lo.Map(
+		workspaceMemberList, func(item database.WorkspaceMember, _ int) uint64 { return item.OfAccountID },
+	))
+	if err != nil {
+		return GetWorkspaceMemberListOutput{}, err
+	}
+	accountMap := lo.KeyBy(accountList, func(item database.Account) uint64 { return item.ID })
+	return GetWorkspaceMemberListOutput{
+		WorkspaceMemberList: lo.Map(workspaceMemberList, func(item database.WorkspaceMember, _ int) *go_load.WorkspaceMember {
+			return w.databaseWorkspaceMemberToProtoWorkspaceMember(item, accountMap[item.OfAccountID])
+		}),
+	}, nil
+}
+func (w workspace) AddWorkspaceMember(ctx context.Context, params AddWorkspaceMemberParams) (AddWorkspaceMemberOutput, error) {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return AddWorkspaceMemberOutput{}, err
+	}
+	if !w.isValidWorkspaceRole(params.Role) {
+		return AddWorkspaceMemberOutput{}, errInvalidWorkspaceRole
+	}
+	account, err := w.accountDataAccessor.GetAccountByAccountName(ctx, params.AccountName)
+	if err != nil {
+		return AddWorkspaceMemberOutput{}, err
+	}
+	newWorkspaceMember := database.WorkspaceMember{
+		OfWorkspaceID: params.WorkspaceID,
+		OfAccountID:   account.ID,
+		Role:          params.Role,
+	}
+	if txErr := w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		if _, getWorkspaceErr := w.workspaceDataAccessor.WithDatabase(td).
+			GetWorkspaceWithXLock(ctx, params.WorkspaceID); getWorkspaceErr != nil {
+			return getWorkspaceErr
+		}
+		if checkErr := checkWorkspaceRoleOfAccount(
+			ctx, w.workspaceMemberDataAccessor.WithDatabase(td), params.WorkspaceID, principal.AccountID, ownerWorkspaceRoleList,
+		); checkErr != nil {
+			return checkErr
+		}
+		existingRole, getRoleErr := getWorkspaceRoleOfAccount(
+			ctx, w.workspaceMemberDataAccessor.WithDatabase(td), params.WorkspaceID, account.ID)
+		if getRoleErr != nil {
+			return getRoleErr
+		}
+		if existingRole != go_load.WorkspaceRole_UndefinedWorkspaceRole {
+			return status.Error(codes.AlreadyExists, "account is already a member of the workspace")
+		}
+		return w.workspaceMemberDataAccessor.WithDatabase(td).CreateWorkspaceMember(ctx, newWorkspaceMember)
+	}); txErr != nil {
+		return AddWorkspaceMemberOutput{}, txErr
+	}
+	utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("workspace_id", params.WorkspaceID)).
+		With(zap.Uint64("account_id", account.ID)).
+		Info("added workspace member")
+	return AddWorkspaceMemberOutput{
+		WorkspaceMember: w.databaseWorkspaceMemberToProtoWorkspaceMember(newWorkspaceMember, account),
+	}, nil
+}
+func (w workspace) UpdateWorkspaceMemberRole(ctx context.Context, params UpdateWorkspaceMemberRoleParams) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if !w.isValidWorkspaceRole(params.Role) {
+		return errInvalidWorkspaceRole
+	}
+	if txErr := w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		if _, getWorkspaceErr := w.workspaceDataAccessor.WithDatabase(td).
+			GetWorkspaceWithXLock(ctx, params.WorkspaceID); getWorkspaceErr != nil {
+			return getWorkspaceErr
+		}
+		if checkErr := checkWorkspaceRoleOfAccount(
+			ctx, w.workspaceMemberDataAccessor.WithDatabase(td), params.WorkspaceID, principal.AccountID, ownerWorkspaceRoleList,
+		); checkErr != nil {
+			return checkErr
+		}
+		workspaceMember, getWorkspaceMemberErr := w.workspaceMemberDataAccessor.WithDatabase(td).
+			GetWorkspaceMemberWithXLock(ctx, params.WorkspaceID, params.AccountID)
+		if getWorkspaceMemberErr != nil {
+			return getWorkspaceMemberErr
+		}
+		if params.Role != go_load.WorkspaceRole_Owner {
+			if checkErr := w.checkWorkspaceKeepsOwner(ctx, td, workspaceMember); checkErr != nil {
+				return checkErr
+			}
+		}
+		workspaceMember.Role = params.Role
+		return w.workspaceMemberDataAccessor.WithDatabase(td).UpdateWorkspaceMember(ctx, workspaceMember)
+	}); txErr != nil {
+		return txErr
+	}
+	utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("workspace_id", params.WorkspaceID)).
+		With(zap.Uint64("account_id", params.AccountID)).
+		With(zap.String("role", params.Role.String())).
+		Info("updated workspace member role")
+	return nil
+}
+func (w workspace) RemoveWorkspaceMember(ctx context.Context, params RemoveWorkspaceMemberParams) error {
+	principal, err := getSessionPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if txErr := w.goquDatabase.WithTx(func(td *goqu.TxDatabase) error {
+		if _, getWorkspaceErr := w.workspaceDataAccessor.WithDatabase(td).
+			GetWorkspaceWithXLock(ctx, params.WorkspaceID); getWorkspaceErr != nil {
+			return getWorkspaceErr
+		}
+		// Members can always leave, but only owners can remove other members.
+		if params.AccountID != principal.AccountID {
+			if checkErr := checkWorkspaceRoleOfAccount(
+				ctx, w.workspaceMemberDataAccessor.WithDatabase(td), params.WorkspaceID, principal.AccountID, ownerWorkspaceRoleList,
+			); checkErr != nil {
+				return checkErr
+			}
+		}
+		workspaceMember, getWorkspaceMemberErr := w.workspaceMemberDataAccessor.WithDatabase(td).
+			GetWorkspaceMemberWithXLock(ctx, params.WorkspaceID, params.AccountID)
+		if getWorkspaceMemberErr != nil {
+			return getWorkspaceMemberErr
+		}
+		if checkErr := w.checkWorkspaceKeepsOwner(ctx, td, workspaceMember); checkErr != nil {
+			return checkErr
+		}
+		return w.workspaceMemberDataAccessor.WithDatabase(td).DeleteWorkspaceMember(ctx, params.WorkspaceID, params.AccountID)
+	}); txErr != nil {
+		return txErr
+	}
+	utils.LoggerWithContext(ctx, w.logger).
+		With(zap.Uint64("workspace_id", params.WorkspaceID)).
+		With(zap.Uint64("account_id", params.AccountID)).
+		Info("removed workspace member")
+	return nil
+}
+func (w workspace) LeaveWorkspaceListOfAccount(ctx context.Context, accountID uint64) error {
+	workspaceMemberList, err := w.workspaceMemberDataAccessor.GetWorkspaceMemberListOfAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	for _, workspaceMember := range workspaceMemberList {
+		if _, err = w.workspaceDataAccessor.GetWorkspaceWithXLock(ctx, workspaceMember.OfWorkspaceID); err != nil {
+			return err
+		}
+		memberListOfWorkspace, getMemberListErr := w.workspaceMemberDataAccessor.
+			GetWorkspaceMemberListOfWorkspace(ctx, workspaceMember.OfWorkspaceID)
+		if getMemberListErr != nil {
+			return getMemberListErr
+		}
+		otherOwner, found := lo.Find(memberListOfWorkspace, func(item database.WorkspaceMember) bool {
+			return item.Role == go_load.WorkspaceRole_Owner && item.OfAccountID != accountID
+		})
+		if !found {
+			return errWorkspaceWithoutOwner
+		}
+		if err = w.downloadTaskDataAccessor.UpdateDownloadTaskAccountOfWorkspace(
+			ctx, workspaceMember.OfWorkspaceID, accountID, otherOwner.OfAccountID,
+		); err != nil {
+			return err
+		}
+		if err = w.workspaceMemberDataAccessor.
+			DeleteWorkspaceMember(ctx, workspaceMember.OfWorkspaceID, accountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (w workspace) WithDatabase(database database.Database) Workspace {
+	w.workspaceDataAccessor = w.workspaceDataAccessor.WithDatabase(database)
+	w.workspaceMemberDataAccessor = w.workspaceMemberDataAccessor.WithDatabase(database)
+	w.downloadTaskDataAccessor = w.downloadTaskDataAccessor.WithDatabase(database)
+	return w
+}
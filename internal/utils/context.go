@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// WithDrainTimeout returns a context outliving ctx by timeout, for the work started before ctx is cancelled to be
+// drained before it is interrupted.
+func WithDrainTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
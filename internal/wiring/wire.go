@@ -29,3 +29,33 @@ func InitializeStandaloneServer(configFilePath configs.ConfigFilePath) (*app.Sta
 	wire.Build(WireSet)
 	return nil, nil, nil
 }
+
+func InitializeAPIServer(configFilePath configs.ConfigFilePath) (*app.APIServer, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
+
+func InitializeWorker(configFilePath configs.ConfigFilePath) (*app.Worker, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
+
+func InitializeCronScheduler(configFilePath configs.ConfigFilePath) (*app.CronScheduler, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
+
+func InitializeAccountLogic(configFilePath configs.ConfigFilePath) (logic.Account, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
+
+func InitializeAdminLogic(configFilePath configs.ConfigFilePath) (logic.Admin, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
+
+func InitializeDeadLetterLogic(configFilePath configs.ConfigFilePath) (logic.DeadLetter, func(), error) {
+	wire.Build(WireSet)
+	return nil, nil, nil
+}
@@ -14,6 +14,7 @@ import (
 	"GoLoad/internal/dataaccess/database"
 	"GoLoad/internal/dataaccess/file"
 	"GoLoad/internal/dataaccess/mq/consumer"
+	"GoLoad/internal/dataaccess/mq/inmemory"
 	"GoLoad/internal/dataaccess/mq/producer"
 	"GoLoad/internal/handler"
 	"GoLoad/internal/handler/consumer"
@@ -49,57 +50,769 @@ func InitializeStandaloneServer(configFilePath configs.ConfigFilePath) (*app.Sta
 	takenAccountName := cache.NewTakenAccountName(client, logger)
 	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
 	accountPasswordDataAccessor := database.NewAccountPasswordDataAccessor(goquDatabase, logger)
+	sessionDataAccessor := database.NewSessionDataAccessor(goquDatabase, logger)
+	passwordResetTokenDataAccessor := database.NewPasswordResetTokenDataAccessor(goquDatabase, logger)
+	apiKeyDataAccessor := database.NewAPIKeyDataAccessor(goquDatabase, logger)
+	accountIdentityDataAccessor := database.NewAccountIdentityDataAccessor(goquDatabase, logger)
 	auth := config.Auth
 	hash := logic.NewHash(auth)
 	tokenPublicKey := cache.NewTokenPublicKey(client, logger)
 	tokenPublicKeyDataAccessor := database.NewTokenPublicKeyDataAccessor(goquDatabase, logger)
-	token, err := logic.NewToken(accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, auth, logger)
+	revokedSession := cache.NewRevokedSession(client, logger)
+	token, err := logic.NewToken(goquDatabase, accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, sessionDataAccessor, revokedSession, apiKeyDataAccessor, auth, logger)
 	if err != nil {
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	account := logic.NewAccount(goquDatabase, takenAccountName, accountDataAccessor, accountPasswordDataAccessor, hash, token, logger)
 	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
 	mq := config.MQ
-	producerClient, err := producer.NewClient(mq, logger)
+	broker := inmemory.NewBroker()
+	producerClient, cleanup3, err := producer.NewClient(mq, broker, logger)
 	if err != nil {
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	downloadTaskCreatedProducer := producer.NewDownloadTaskCreatedProducer(producerClient, logger)
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, producerClient, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
 	download := config.Download
 	fileClient, err := file.NewClient(download, logger)
 	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
 	cron := config.Cron
-	downloadTask := logic.NewDownloadTask(token, accountDataAccessor, downloadTaskDataAccessor, downloadTaskCreatedProducer, goquDatabase, fileClient, cron, logger)
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	workspaceDataAccessor := database.NewWorkspaceDataAccessor(goquDatabase, logger)
+	workspace := logic.NewWorkspace(goquDatabase, accountDataAccessor, workspaceDataAccessor, workspaceMemberDataAccessor, downloadTaskDataAccessor, logger)
+	passwordPolicy, err := logic.NewPasswordPolicy(auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	loginAttempt := cache.NewLoginAttempt(client, logger)
+	auditLogDataAccessor := database.NewAuditLogDataAccessor(goquDatabase, logger)
+	loginRateLimiter, err := logic.NewLoginRateLimiter(loginAttempt, auditLogDataAccessor, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	account := logic.NewAccount(goquDatabase, takenAccountName, accountDataAccessor, accountPasswordDataAccessor, sessionDataAccessor, passwordResetTokenDataAccessor, apiKeyDataAccessor, accountIdentityDataAccessor, hash, token, downloadTask, logicWebhook, workspace, passwordPolicy, loginRateLimiter, auth, logger)
+	apiKey := logic.NewAPIKey(goquDatabase, apiKeyDataAccessor, logger)
 	configsGRPC := config.GRPC
-	goLoadServiceServer, err := grpc.NewHandler(account, downloadTask, configsGRPC)
+	goLoadServiceServer, err := grpc.NewHandler(account, apiKey, downloadTask, workspace, logicWebhook, configsGRPC)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	admin := logic.NewAdmin(goquDatabase, accountDataAccessor, account, token, logger)
+	adminServiceServer := grpc.NewAdminHandler(admin, downloadTask)
+	health := logic.NewHealth(goquDatabase, client, producerClient, fileClient, logger)
+	healthServer := grpc.NewHealthHandler(health)
+	authorization := logic.NewAuthorization(token, accountDataAccessor, logger)
+	shutdown := config.Shutdown
+	server := grpc.NewServer(goLoadServiceServer, adminServiceServer, healthServer, token, authorization, configsGRPC, shutdown, logger)
+	downloadTaskFile := http.NewDownloadTaskFile(downloadTask, authorization, logger)
+	jwks := http.NewJWKS(token, logger)
+	oidcLogin := cache.NewOIDCLogin(client, logger)
+	oidc, err := logic.NewOIDC(oidcLogin, account, auth, logger)
 	if err != nil {
+		cleanup3()
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	server := grpc.NewServer(goLoadServiceServer, configsGRPC, logger)
+	httpOIDC := http.NewOIDC(oidc, auth, logger)
+	httpHealth := http.NewHealth(health, logger)
 	configsHTTP := config.HTTP
-	httpServer := http.NewServer(configsGRPC, configsHTTP, auth, logger)
+	httpServer := http.NewServer(downloadTaskFile, jwks, httpOIDC, httpHealth, configsGRPC, configsHTTP, auth, shutdown, logger)
+	metrics := config.Metrics
+	metricsServer := http.NewMetricsServer(metrics, shutdown, logger)
+	apiServer := app.NewAPIServer(server, httpServer, metricsServer, health, shutdown, logger)
 	downloadTaskCreated := consumers.NewDownloadTaskCreated(downloadTask, logger)
-	consumerConsumer, err := consumer.NewConsumer(mq, logger)
+	downloadTaskLifecycleEvent := consumers.NewDownloadTaskLifecycleEvent(logicWebhook, logger)
+	consumerConsumer, err := consumer.NewConsumer(mq, producerClient, broker, logger)
 	if err != nil {
+		cleanup3()
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	root := consumers.NewRoot(downloadTaskCreated, consumerConsumer, logger)
+	root := consumers.NewRoot(downloadTaskCreated, downloadTaskLifecycleEvent, consumerConsumer, shutdown, logger)
 	executeAllPendingDownloadTask := jobs.NewExecuteAllPendingDownloadTask(downloadTask)
+	configsHealth := config.Health
+	httpHealthServer := http.NewHealthServer(httpHealth, configsHealth, shutdown, logger)
+	appWorker := app.NewWorker(root, executeAllPendingDownloadTask, httpHealthServer, metricsServer, health, cron, shutdown, logger)
 	updateDownloadingAndFailedDownloadTaskStatusToPending := jobs.NewUpdateDownloadingAndFailedDownloadTaskStatusToPending(downloadTask)
-	standaloneServer := app.NewStandaloneServer(server, httpServer, root, executeAllPendingDownloadTask, updateDownloadingAndFailedDownloadTaskStatusToPending, cron, logger)
+	deleteExpiredDownloadTaskFile := jobs.NewDeleteExpiredDownloadTaskFile(downloadTask)
+	rotateTokenSigningKey := jobs.NewRotateTokenSigningKey(token)
+	relayOutboxEvents := jobs.NewRelayOutboxEvents(outbox)
+	deliverWebhooks := jobs.NewDeliverWebhooks(logicWebhook)
+	leaderLeaseDataAccessor := database.NewLeaderLeaseDataAccessor(goquDatabase, logger)
+	leaderElection := logic.NewLeaderElection(goquDatabase, leaderLeaseDataAccessor, cron, logger)
+	cronScheduler := app.NewCronScheduler(updateDownloadingAndFailedDownloadTaskStatusToPending, deleteExpiredDownloadTaskFile, rotateTokenSigningKey, relayOutboxEvents, deliverWebhooks, leaderElection, httpHealthServer, metricsServer, health, cron, shutdown, logger)
+	standaloneServer := app.NewStandaloneServer(apiServer, appWorker, cronScheduler, metricsServer, health, logger)
 	return standaloneServer, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeAPIServer(configFilePath configs.ConfigFilePath) (*app.APIServer, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	configsDatabase := config.Database
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, cleanup2, err := database.InitializeAndMigrateUpDB(configsDatabase, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	goquDatabase := database.InitializeGoquDB(db)
+	configsCache := config.Cache
+	client := cache.NewRedisClient(configsCache, logger)
+	takenAccountName := cache.NewTakenAccountName(client, logger)
+	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
+	accountPasswordDataAccessor := database.NewAccountPasswordDataAccessor(goquDatabase, logger)
+	sessionDataAccessor := database.NewSessionDataAccessor(goquDatabase, logger)
+	passwordResetTokenDataAccessor := database.NewPasswordResetTokenDataAccessor(goquDatabase, logger)
+	apiKeyDataAccessor := database.NewAPIKeyDataAccessor(goquDatabase, logger)
+	accountIdentityDataAccessor := database.NewAccountIdentityDataAccessor(goquDatabase, logger)
+	auth := config.Auth
+	hash := logic.NewHash(auth)
+	tokenPublicKey := cache.NewTokenPublicKey(client, logger)
+	tokenPublicKeyDataAccessor := database.NewTokenPublicKeyDataAccessor(goquDatabase, logger)
+	revokedSession := cache.NewRevokedSession(client, logger)
+	token, err := logic.NewToken(goquDatabase, accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, sessionDataAccessor, revokedSession, apiKeyDataAccessor, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	producerClient, cleanup3, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, producerClient, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	download := config.Download
+	fileClient, err := file.NewClient(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cron := config.Cron
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	workspaceDataAccessor := database.NewWorkspaceDataAccessor(goquDatabase, logger)
+	workspace := logic.NewWorkspace(goquDatabase, accountDataAccessor, workspaceDataAccessor, workspaceMemberDataAccessor, downloadTaskDataAccessor, logger)
+	passwordPolicy, err := logic.NewPasswordPolicy(auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	loginAttempt := cache.NewLoginAttempt(client, logger)
+	auditLogDataAccessor := database.NewAuditLogDataAccessor(goquDatabase, logger)
+	loginRateLimiter, err := logic.NewLoginRateLimiter(loginAttempt, auditLogDataAccessor, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	account := logic.NewAccount(goquDatabase, takenAccountName, accountDataAccessor, accountPasswordDataAccessor, sessionDataAccessor, passwordResetTokenDataAccessor, apiKeyDataAccessor, accountIdentityDataAccessor, hash, token, downloadTask, logicWebhook, workspace, passwordPolicy, loginRateLimiter, auth, logger)
+	apiKey := logic.NewAPIKey(goquDatabase, apiKeyDataAccessor, logger)
+	configsGRPC := config.GRPC
+	goLoadServiceServer, err := grpc.NewHandler(account, apiKey, downloadTask, workspace, logicWebhook, configsGRPC)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	admin := logic.NewAdmin(goquDatabase, accountDataAccessor, account, token, logger)
+	adminServiceServer := grpc.NewAdminHandler(admin, downloadTask)
+	health := logic.NewHealth(goquDatabase, client, producerClient, fileClient, logger)
+	healthServer := grpc.NewHealthHandler(health)
+	authorization := logic.NewAuthorization(token, accountDataAccessor, logger)
+	shutdown := config.Shutdown
+	server := grpc.NewServer(goLoadServiceServer, adminServiceServer, healthServer, token, authorization, configsGRPC, shutdown, logger)
+	downloadTaskFile := http.NewDownloadTaskFile(downloadTask, authorization, logger)
+	jwks := http.NewJWKS(token, logger)
+	oidcLogin := cache.NewOIDCLogin(client, logger)
+	oidc, err := logic.NewOIDC(oidcLogin, account, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	httpOIDC := http.NewOIDC(oidc, auth, logger)
+	httpHealth := http.NewHealth(health, logger)
+	configsHTTP := config.HTTP
+	httpServer := http.NewServer(downloadTaskFile, jwks, httpOIDC, httpHealth, configsGRPC, configsHTTP, auth, shutdown, logger)
+	metrics := config.Metrics
+	metricsServer := http.NewMetricsServer(metrics, shutdown, logger)
+	apiServer := app.NewAPIServer(server, httpServer, metricsServer, health, shutdown, logger)
+	return apiServer, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeWorker(configFilePath configs.ConfigFilePath) (*app.Worker, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	configsDatabase := config.Database
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, cleanup2, err := database.InitializeAndMigrateUpDB(configsDatabase, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	goquDatabase := database.InitializeGoquDB(db)
+	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
+	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	client, cleanup3, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, client, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	download := config.Download
+	fileClient, err := file.NewClient(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cron := config.Cron
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	downloadTaskCreated := consumers.NewDownloadTaskCreated(downloadTask, logger)
+	downloadTaskLifecycleEvent := consumers.NewDownloadTaskLifecycleEvent(logicWebhook, logger)
+	consumerConsumer, err := consumer.NewConsumer(mq, client, broker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	shutdown := config.Shutdown
+	root := consumers.NewRoot(downloadTaskCreated, downloadTaskLifecycleEvent, consumerConsumer, shutdown, logger)
+	executeAllPendingDownloadTask := jobs.NewExecuteAllPendingDownloadTask(downloadTask)
+	configsCache := config.Cache
+	cacheClient := cache.NewRedisClient(configsCache, logger)
+	health := logic.NewHealth(goquDatabase, cacheClient, client, fileClient, logger)
+	httpHealth := http.NewHealth(health, logger)
+	configsHealth := config.Health
+	healthServer := http.NewHealthServer(httpHealth, configsHealth, shutdown, logger)
+	metrics := config.Metrics
+	metricsServer := http.NewMetricsServer(metrics, shutdown, logger)
+	appWorker := app.NewWorker(root, executeAllPendingDownloadTask, healthServer, metricsServer, health, cron, shutdown, logger)
+	return appWorker, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeCronScheduler(configFilePath configs.ConfigFilePath) (*app.CronScheduler, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	configsDatabase := config.Database
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, cleanup2, err := database.InitializeAndMigrateUpDB(configsDatabase, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	goquDatabase := database.InitializeGoquDB(db)
+	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
+	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	client, cleanup3, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, client, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	download := config.Download
+	fileClient, err := file.NewClient(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cron := config.Cron
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	updateDownloadingAndFailedDownloadTaskStatusToPending := jobs.NewUpdateDownloadingAndFailedDownloadTaskStatusToPending(downloadTask)
+	deleteExpiredDownloadTaskFile := jobs.NewDeleteExpiredDownloadTaskFile(downloadTask)
+	configsCache := config.Cache
+	cacheClient := cache.NewRedisClient(configsCache, logger)
+	tokenPublicKey := cache.NewTokenPublicKey(cacheClient, logger)
+	tokenPublicKeyDataAccessor := database.NewTokenPublicKeyDataAccessor(goquDatabase, logger)
+	sessionDataAccessor := database.NewSessionDataAccessor(goquDatabase, logger)
+	revokedSession := cache.NewRevokedSession(cacheClient, logger)
+	apiKeyDataAccessor := database.NewAPIKeyDataAccessor(goquDatabase, logger)
+	auth := config.Auth
+	token, err := logic.NewToken(goquDatabase, accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, sessionDataAccessor, revokedSession, apiKeyDataAccessor, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	rotateTokenSigningKey := jobs.NewRotateTokenSigningKey(token)
+	relayOutboxEvents := jobs.NewRelayOutboxEvents(outbox)
+	deliverWebhooks := jobs.NewDeliverWebhooks(logicWebhook)
+	leaderLeaseDataAccessor := database.NewLeaderLeaseDataAccessor(goquDatabase, logger)
+	leaderElection := logic.NewLeaderElection(goquDatabase, leaderLeaseDataAccessor, cron, logger)
+	health := logic.NewHealth(goquDatabase, cacheClient, client, fileClient, logger)
+	httpHealth := http.NewHealth(health, logger)
+	configsHealth := config.Health
+	shutdown := config.Shutdown
+	healthServer := http.NewHealthServer(httpHealth, configsHealth, shutdown, logger)
+	metrics := config.Metrics
+	metricsServer := http.NewMetricsServer(metrics, shutdown, logger)
+	cronScheduler := app.NewCronScheduler(updateDownloadingAndFailedDownloadTaskStatusToPending, deleteExpiredDownloadTaskFile, rotateTokenSigningKey, relayOutboxEvents, deliverWebhooks, leaderElection, healthServer, metricsServer, health, cron, shutdown, logger)
+	return cronScheduler, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeAccountLogic(configFilePath configs.ConfigFilePath) (logic.Account, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	configsDatabase := config.Database
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, cleanup2, err := database.InitializeAndMigrateUpDB(configsDatabase, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	goquDatabase := database.InitializeGoquDB(db)
+	configsCache := config.Cache
+	client := cache.NewRedisClient(configsCache, logger)
+	takenAccountName := cache.NewTakenAccountName(client, logger)
+	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
+	accountPasswordDataAccessor := database.NewAccountPasswordDataAccessor(goquDatabase, logger)
+	sessionDataAccessor := database.NewSessionDataAccessor(goquDatabase, logger)
+	passwordResetTokenDataAccessor := database.NewPasswordResetTokenDataAccessor(goquDatabase, logger)
+	apiKeyDataAccessor := database.NewAPIKeyDataAccessor(goquDatabase, logger)
+	accountIdentityDataAccessor := database.NewAccountIdentityDataAccessor(goquDatabase, logger)
+	auth := config.Auth
+	hash := logic.NewHash(auth)
+	tokenPublicKey := cache.NewTokenPublicKey(client, logger)
+	tokenPublicKeyDataAccessor := database.NewTokenPublicKeyDataAccessor(goquDatabase, logger)
+	revokedSession := cache.NewRevokedSession(client, logger)
+	token, err := logic.NewToken(goquDatabase, accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, sessionDataAccessor, revokedSession, apiKeyDataAccessor, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	producerClient, cleanup3, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, producerClient, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	download := config.Download
+	fileClient, err := file.NewClient(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cron := config.Cron
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	workspaceDataAccessor := database.NewWorkspaceDataAccessor(goquDatabase, logger)
+	workspace := logic.NewWorkspace(goquDatabase, accountDataAccessor, workspaceDataAccessor, workspaceMemberDataAccessor, downloadTaskDataAccessor, logger)
+	passwordPolicy, err := logic.NewPasswordPolicy(auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	loginAttempt := cache.NewLoginAttempt(client, logger)
+	auditLogDataAccessor := database.NewAuditLogDataAccessor(goquDatabase, logger)
+	loginRateLimiter, err := logic.NewLoginRateLimiter(loginAttempt, auditLogDataAccessor, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	account := logic.NewAccount(goquDatabase, takenAccountName, accountDataAccessor, accountPasswordDataAccessor, sessionDataAccessor, passwordResetTokenDataAccessor, apiKeyDataAccessor, accountIdentityDataAccessor, hash, token, downloadTask, logicWebhook, workspace, passwordPolicy, loginRateLimiter, auth, logger)
+	return account, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeAdminLogic(configFilePath configs.ConfigFilePath) (logic.Admin, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	configsDatabase := config.Database
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, cleanup2, err := database.InitializeAndMigrateUpDB(configsDatabase, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	goquDatabase := database.InitializeGoquDB(db)
+	accountDataAccessor := database.NewAccountDataAccessor(goquDatabase, logger)
+	configsCache := config.Cache
+	client := cache.NewRedisClient(configsCache, logger)
+	takenAccountName := cache.NewTakenAccountName(client, logger)
+	accountPasswordDataAccessor := database.NewAccountPasswordDataAccessor(goquDatabase, logger)
+	sessionDataAccessor := database.NewSessionDataAccessor(goquDatabase, logger)
+	passwordResetTokenDataAccessor := database.NewPasswordResetTokenDataAccessor(goquDatabase, logger)
+	apiKeyDataAccessor := database.NewAPIKeyDataAccessor(goquDatabase, logger)
+	accountIdentityDataAccessor := database.NewAccountIdentityDataAccessor(goquDatabase, logger)
+	auth := config.Auth
+	hash := logic.NewHash(auth)
+	tokenPublicKey := cache.NewTokenPublicKey(client, logger)
+	tokenPublicKeyDataAccessor := database.NewTokenPublicKeyDataAccessor(goquDatabase, logger)
+	revokedSession := cache.NewRevokedSession(client, logger)
+	token, err := logic.NewToken(goquDatabase, accountDataAccessor, tokenPublicKey, tokenPublicKeyDataAccessor, sessionDataAccessor, revokedSession, apiKeyDataAccessor, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	downloadTaskDataAccessor := database.NewDownloadTaskDataAccessor(goquDatabase, logger)
+	workspaceMemberDataAccessor := database.NewWorkspaceMemberDataAccessor(goquDatabase, logger)
+	outboxEventDataAccessor := database.NewOutboxEventDataAccessor(goquDatabase, logger)
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	producerClient, cleanup3, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	outbox, err := logic.NewOutbox(goquDatabase, outboxEventDataAccessor, producerClient, mq, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	webhookDataAccessor := database.NewWebhookDataAccessor(goquDatabase, logger)
+	webhookDeliveryDataAccessor := database.NewWebhookDeliveryDataAccessor(goquDatabase, logger)
+	webhook := config.Webhook
+	logicWebhook, err := logic.NewWebhook(goquDatabase, webhookDataAccessor, webhookDeliveryDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, webhook, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	download := config.Download
+	fileClient, err := file.NewClient(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	fileURLSigner, err := logic.NewFileURLSigner(download, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cron := config.Cron
+	retention := config.Retention
+	worker := config.Worker
+	downloadTask, err := logic.NewDownloadTask(accountDataAccessor, downloadTaskDataAccessor, workspaceMemberDataAccessor, outbox, logicWebhook, goquDatabase, fileClient, fileURLSigner, cron, download, retention, worker, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	workspaceDataAccessor := database.NewWorkspaceDataAccessor(goquDatabase, logger)
+	workspace := logic.NewWorkspace(goquDatabase, accountDataAccessor, workspaceDataAccessor, workspaceMemberDataAccessor, downloadTaskDataAccessor, logger)
+	passwordPolicy, err := logic.NewPasswordPolicy(auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	loginAttempt := cache.NewLoginAttempt(client, logger)
+	auditLogDataAccessor := database.NewAuditLogDataAccessor(goquDatabase, logger)
+	loginRateLimiter, err := logic.NewLoginRateLimiter(loginAttempt, auditLogDataAccessor, auth, logger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	account := logic.NewAccount(goquDatabase, takenAccountName, accountDataAccessor, accountPasswordDataAccessor, sessionDataAccessor, passwordResetTokenDataAccessor, apiKeyDataAccessor, accountIdentityDataAccessor, hash, token, downloadTask, logicWebhook, workspace, passwordPolicy, loginRateLimiter, auth, logger)
+	admin := logic.NewAdmin(goquDatabase, accountDataAccessor, account, token, logger)
+	return admin, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}, nil
+}
+
+func InitializeDeadLetterLogic(configFilePath configs.ConfigFilePath) (logic.DeadLetter, func(), error) {
+	config, err := configs.NewConfig(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	mq := config.MQ
+	broker := inmemory.NewBroker()
+	log := config.Log
+	logger, cleanup, err := utils.InitializeLogger(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	deadLetterReader, err := consumer.NewDeadLetterReader(mq, broker, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	client, cleanup2, err := producer.NewClient(mq, broker, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	deadLetter := logic.NewDeadLetter(deadLetterReader, client, logger)
+	return deadLetter, func() {
 		cleanup2()
 		cleanup()
 	}, nil